@@ -0,0 +1,283 @@
+package audit
+
+import (
+    "bufio"
+    "bytes"
+    "crypto/sha512"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "strconv"
+    "sync"
+    "time"
+)
+
+//////////////////////////////////////////////
+////////////////// Audit Log /////////////////
+//////////////////////////////////////////////
+
+// the events the server records
+const (
+  EventShutdown = "shutdown"
+  EventShutdownCancelled = "shutdown_cancelled"
+  EventStatsReset = "stats_reset"
+  EventKeyCreated = "key_created"
+  EventKeyRevoked = "key_revoked"
+  EventRestart = "restart"
+  EventAuthFailed = "auth_failed"
+)
+
+// how big the log gets before it is rotated, and how many rotated files are kept, unless Open is told otherwise
+var (
+  DefaultMaxBytes int64 = 10 << 20
+  DefaultKeep = 5
+)
+
+// Event is one line of the audit log. Seq and Prev chain the lines together: Prev is the hex SHA-512 of the line
+// before, so changing, dropping or reordering a line breaks the chain from there on
+type Event struct {
+  Seq int64
+  Time time.Time
+  Event string // one of the Event constants
+  Actor string // who did it: admin, key:<id> or anonymous
+  Remote string `json:",omitempty"` // the caller's address
+  RequestID string `json:",omitempty"`
+  Detail string `json:",omitempty"` // e.g. the key id, or the error code of an auth failure
+  Prev string // empty on the first line the log ever wrote
+}
+
+// Log appends hash-chained events to a file as json lines. once the file would grow past maxBytes it is renamed
+// to path.1 (path.1 to path.2 and so on, keeping keep of them) and the chain carries on in a new file
+type Log struct {
+  path string
+  maxBytes int64
+  keep int
+
+  mu sync.Mutex
+  f *os.File
+  size int64
+  seq int64
+  prev string // hash of the last line written
+}
+
+// Report is what Verify found
+type Report struct {
+  Files int
+  Records int
+  First int64 // Seq of the first record checked
+  Last int64
+  Anchored bool // whether the first record checked is the first the log ever wrote; false once old files were rotated away
+}
+
+// Open appends to the log at path, carrying on the chain where the file, or the last file rotated out, left off.
+// maxBytes and keep fall back to DefaultMaxBytes and DefaultKeep when they aren't positive
+func Open(path string, maxBytes int64, keep int) (*Log, error) {
+  if maxBytes <= 0 {
+    maxBytes = DefaultMaxBytes
+  }
+  if keep <= 0 {
+    keep = DefaultKeep
+  }
+  l := &Log{path: path, maxBytes: maxBytes, keep: keep}
+  if err := l.load(); err != nil {
+    return nil, err
+  }
+  return l, nil
+}
+
+// Record fills in e's Seq and Prev, and its Time when it's zero, and appends it
+func (l *Log) Record(e Event) error {
+  l.mu.Lock()
+  defer l.mu.Unlock()
+  if l.f == nil {
+    return errors.New("the audit log is closed")
+  }
+  if e.Time.IsZero() {
+    e.Time = time.Now()
+  }
+  e.Seq, e.Prev = l.seq + 1, l.prev
+  line, err := json.Marshal(e)
+  if err != nil {
+    return err
+  }
+  if l.size > 0 && l.size + int64(len(line)) + 1 > l.maxBytes {
+    if err := l.rotate(); err != nil {
+      return err
+    }
+  }
+  n, err := l.f.Write(append(line, '\n'))
+  l.size += int64(n)
+  if err != nil {
+    return err
+  }
+  l.seq, l.prev = e.Seq, sum(line)
+  return nil
+}
+
+// Close closes the file. Record fails from then on
+func (l *Log) Close() error {
+  l.mu.Lock()
+  defer l.mu.Unlock()
+  if l.f == nil {
+    return nil
+  }
+  err := l.f.Close()
+  l.f = nil
+  return err
+}
+
+// Reopen opens a closed Log again, picking the chain up from the file in case another process wrote to it meanwhile
+func (l *Log) Reopen() error {
+  l.mu.Lock()
+  defer l.mu.Unlock()
+  if l.f != nil {
+    return nil
+  }
+  return l.load()
+}
+
+// Files lists the log at path and its rotated files that exist, oldest first, the way Verify wants them
+func Files(path string) []string {
+  var files []string
+  for i := 1; ; i++ {
+    if _, err := os.Stat(rotated(path, i)); err != nil {
+      break
+    }
+    files = append([]string{rotated(path, i)}, files...)
+  }
+  if _, err := os.Stat(path); err == nil {
+    files = append(files, path)
+  }
+  return files
+}
+
+// Verify checks the chain through files, oldest first: every line an event, numbered one after the other, and
+// carrying the hash of the line before. the first line is taken on trust unless it's the very first the log wrote
+func Verify(files ...string) (Report, error) {
+  report := Report{}
+  var prev []byte
+  for _, file := range files {
+    f, err := os.Open(file)
+    if err != nil {
+      return report, err
+    }
+    report.Files++
+    scanner := bufio.NewScanner(f)
+    scanner.Buffer(nil, 1 << 20)
+    for n := 1; scanner.Scan(); n++ {
+      line := scanner.Bytes()
+      e := Event{}
+      if err := json.Unmarshal(line, &e); err != nil {
+        f.Close()
+        return report, fmt.Errorf("%s line %d is not an audit event: %v", file, n, err)
+      }
+      if prev == nil {
+        report.First, report.Anchored = e.Seq, e.Seq == 1 && e.Prev == ""
+      } else if e.Seq != report.Last + 1 {
+        f.Close()
+        return report, fmt.Errorf("%s line %d: expected seq %d after %d, got %d", file, n, report.Last + 1, report.Last, e.Seq)
+      } else if e.Prev != sum(prev) {
+        f.Close()
+        return report, fmt.Errorf("%s line %d (seq %d): the line before it has been changed", file, n, e.Seq)
+      }
+      prev = append(prev[:0], line...)
+      report.Last = e.Seq
+      report.Records++
+    }
+    err = scanner.Err()
+    f.Close()
+    if err != nil {
+      return report, fmt.Errorf("%s: %v", file, err)
+    }
+  }
+  if report.Files == 0 {
+    return report, errors.New("no audit log files to verify")
+  }
+  return report, nil
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// finds where the chain left off and opens path for appending. caller holds the lock, or has the Log to itself
+func (l *Log) load() error {
+  l.seq, l.prev = 0, ""
+  for _, file := range []string{l.path, rotated(l.path, 1)} {
+    line, err := lastLine(file)
+    if err != nil {
+      return err
+    }
+    if line == nil {
+      continue
+    }
+    e := Event{}
+    if err := json.Unmarshal(line, &e); err != nil {
+      return fmt.Errorf("audit log %s ends in a line that isn't an event: %v", file, err)
+    }
+    l.seq, l.prev = e.Seq, sum(line)
+    break
+  }
+  return l.open()
+}
+
+// opens path for appending. caller holds the lock
+func (l *Log) open() error {
+  f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+  if err != nil {
+    return err
+  }
+  info, err := f.Stat()
+  if err != nil {
+    f.Close()
+    return err
+  }
+  l.f, l.size = f, info.Size()
+  return nil
+}
+
+// shifts path to path.1, path.1 to path.2 and so on, dropping the oldest beyond keep, and starts a new file. caller holds the lock
+func (l *Log) rotate() error {
+  if err := l.f.Close(); err != nil {
+    return err
+  }
+  l.f = nil
+  os.Remove(rotated(l.path, l.keep))
+  for i := l.keep - 1; i >= 1; i-- {
+    if err := os.Rename(rotated(l.path, i), rotated(l.path, i + 1)); err != nil && !os.IsNotExist(err) {
+      return err
+    }
+  }
+  if err := os.Rename(l.path, rotated(l.path, 1)); err != nil {
+    return err
+  }
+  return l.open()
+}
+
+func rotated(path string, i int) string {
+  return path + "." + strconv.Itoa(i)
+}
+
+// the last non-empty line of file, or nil when the file is empty or missing
+func lastLine(file string) ([]byte, error) {
+  data, err := os.ReadFile(file)
+  if os.IsNotExist(err) {
+    return nil, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+  data = bytes.TrimRight(data, "\n")
+  if len(data) == 0 {
+    return nil, nil
+  }
+  return data[bytes.LastIndexByte(data, '\n') + 1:], nil
+}
+
+// hex SHA-512 of a line, without its newline
+func sum(line []byte) string {
+  h := sha512.Sum512(line)
+  return hex.EncodeToString(h[:])
+}
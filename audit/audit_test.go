@@ -0,0 +1,105 @@
+package audit
+
+import (
+  "testing"
+  "os"
+  "path/filepath"
+  "strings"
+)
+
+//////////////////////////////////////////////
+////////////// Audit Log Unit Tests //////////
+//////////////////////////////////////////////
+
+func TestLogChainsAcrossRestarts(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "audit.log")
+  l := openLog(t, path, 0, 0)
+  record(t, l, EventKeyCreated, EventStatsReset)
+  l.Close()
+  if err := l.Record(Event{Event: EventShutdown}); err == nil {
+    t.Errorf("Expected a closed log to refuse events")
+  }
+
+  // a new process carries on where the file left off
+  l = openLog(t, path, 0, 0)
+  record(t, l, EventShutdown)
+  l.Close()
+
+  report, err := Verify(Files(path)...)
+  if err != nil {
+    t.Fatalf("Expected an intact chain. err %v", err)
+  }
+  if report.Records != 3 || report.First != 1 || report.Last != 3 || !report.Anchored || report.Files != 1 {
+    t.Errorf("Expected three records from the start of the log. got %+v", report)
+  }
+}
+
+func TestLogRotates(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "audit.log")
+  l := openLog(t, path, 200, 2) // about one event a file
+  for i := 0; i < 5; i++ {
+    record(t, l, EventAuthFailed)
+  }
+  l.Close()
+
+  files := Files(path)
+  if len(files) != 3 || files[0] != path + ".2" || files[2] != path {
+    t.Fatalf("Expected the log and two rotated files, oldest first. got %v", files)
+  }
+  report, err := Verify(files...)
+  if err != nil {
+    t.Fatalf("Expected the chain to carry on across files. err %v", err)
+  }
+  if report.Last != 5 || report.Anchored {
+    t.Errorf("Expected the newest records without the first, which was rotated away. got %+v", report)
+  }
+}
+
+func TestVerifyCatchesTampering(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "audit.log")
+  l := openLog(t, path, 0, 0)
+  record(t, l, EventKeyCreated, EventKeyRevoked, EventShutdown)
+  l.Close()
+  original, _ := os.ReadFile(path)
+  lines := strings.SplitAfter(string(original), "\n")
+
+  for name, tampered := range map[string]string{
+    "changed": strings.Replace(string(original), EventKeyRevoked, EventStatsReset, 1),
+    "dropped": lines[0] + lines[2],
+    "reordered": lines[1] + lines[0] + lines[2],
+    "not json": string(original) + "oops\n",
+  } {
+    os.WriteFile(path, []byte(tampered), 0600)
+    if _, err := Verify(path); err == nil {
+      t.Errorf("Expected a %s line to break the chain", name)
+    }
+  }
+  // as if the first line had been rotated away
+  os.WriteFile(path, []byte(lines[1] + lines[2]), 0600)
+  if report, err := Verify(path); err != nil || report.Anchored || report.First != 2 {
+    t.Errorf("Expected a first line that isn't the log's first to be taken on trust but not anchored. got %+v %v", report, err)
+  }
+  if _, err := Verify(); err == nil {
+    t.Errorf("Expected an error without any files")
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+func openLog(t *testing.T, path string, maxBytes int64, keep int) *Log {
+  l, err := Open(path, maxBytes, keep)
+  if err != nil {
+    t.Fatalf("Did not expect an error but got one. err %v", err)
+  }
+  return l
+}
+
+func record(t *testing.T, l *Log, events ...string) {
+  for _, event := range events {
+    if err := l.Record(Event{Event: event, Actor: "admin", Remote: "127.0.0.1:1234"}); err != nil {
+      t.Fatalf("Did not expect an error but got one. err %v", err)
+    }
+  }
+}
@@ -0,0 +1,481 @@
+package client
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "syscall"
+    "time"
+)
+
+//////////////////////////////////////////////
+////////////////// Client ////////////////////
+//////////////////////////////////////////////
+
+// Client calls a GoHTTP server. The zero value is not usable; create one with New
+type Client struct {
+  BaseURL string // e.g. http://localhost:8080
+  HTTPClient *http.Client
+  MaxRetries int // how many times a 429 or 503 is retried. 0 disables retries
+  Backoff time.Duration // wait before the first retry; doubled for each retry after that
+  MaxBackoff time.Duration // longest wait between retries
+  APIKey string // sent as Authorization: Bearer on every request when set. needed once the server has API keys
+}
+
+// New returns a Client for the server at baseURL with retries enabled
+func New(baseURL string) *Client {
+  return &Client{
+    BaseURL: strings.TrimRight(baseURL, "/"),
+    HTTPClient: http.DefaultClient,
+    MaxRetries: 3,
+    Backoff: 100 * time.Millisecond,
+    MaxBackoff: 5 * time.Second,
+  }
+}
+
+// Error is an error response from the server
+type Error struct {
+  StatusCode int
+  Code string // stable error code, e.g. missing_password. see GET /errors
+  Message string
+  RequestID string
+}
+
+func (e *Error) Error() string {
+  if e.Code == "" {
+    return fmt.Sprintf("gohttp: %d %s", e.StatusCode, e.Message)
+  }
+  return fmt.Sprintf("gohttp: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// IsCode reports whether err is an *Error with the given error code
+func IsCode(err error, code string) bool {
+  var e *Error
+  return errors.As(err, &e) && e.Code == code
+}
+
+// Job is an asynchronous hash request
+type Job struct {
+  ID string
+  Status string // pending, done, failed or cancelled
+  Algorithm string
+  Hash string
+  Error string
+  Created time.Time
+  Started time.Time // zero until hashing begins
+  Finished time.Time
+  Duration float64 // microseconds from Created to Finished, once the job has finished
+  Callback *Callback // set when the job was started with HashWithCallback
+}
+
+// JobList is a page of jobs, newest first
+type JobList struct {
+  Jobs []Job
+  Next string // the cursor for the next page, empty on the last one
+}
+
+// Callback is the delivery state of a job's callback url
+type Callback struct {
+  URL string
+  Status string // pending, delivered or failed
+  Attempts []CallbackAttempt
+}
+
+// CallbackAttempt is one try at delivering a callback
+type CallbackAttempt struct {
+  At time.Time
+  StatusCode int
+  Error string
+}
+
+// Stats is the /stats response
+type Stats struct {
+  Total int
+  Average float64
+  Endpoints []EndpointStats
+  Protocols map[string]int // requests per protocol, e.g. HTTP/1.1
+  Pool *PoolStats // nil on servers without a hashing worker pool
+  Keys []KeyStats // usage per API key, on servers with API keys
+  Cache *CacheStats // nil on servers without a hash cache section
+}
+
+// ShutdownStatus is the /shutdown response
+type ShutdownStatus struct {
+  State string // running, scheduled or draining
+  At time.Time // when the scheduled shutdown starts draining, or started; zero while running
+  Deadline time.Time // when the drain gives up on the hashing in progress; zero when it waits as long as it takes
+  HashesInProgress int
+  PendingJobs int // async jobs that haven't finished
+  RequestsInFlight int
+}
+
+// CacheStats is the /stats section for the server's hash cache
+type CacheStats struct {
+  Entries int
+  Capacity int // 0 when the cache is off
+  Hits int
+  Misses int
+  Coalesced int // requests that waited on an identical hash in progress
+}
+
+// KeyStats is the /stats section for one API key. times are in microseconds
+type KeyStats struct {
+  ID string
+  Name string
+  Requests int
+  Today int // counted against the daily quota
+  DailyQuota int // 0 for no limit
+  Rejected int // refused for being over the quota
+  Average float64
+  Max float64
+}
+
+// PoolStats is the /stats section for the server's hashing workers
+type PoolStats struct {
+  Workers int
+  Busy int
+  QueueCapacity int
+  Lanes []LaneStats
+}
+
+// LaneStats is one priority lane of the hashing queue. wait times are in microseconds
+type LaneStats struct {
+  Lane string
+  Queued int
+  Completed int
+  Rejected int
+  Cancelled int
+  WaitAverage float64
+  WaitMax float64
+}
+
+// EndpointStats is the /stats breakdown for one route, method and status
+type EndpointStats struct {
+  Route string
+  Method string
+  Status int
+  Count int
+  Average float64
+  Max float64
+  Errors map[string]int
+}
+
+// Hash hashes password and waits for the result
+func (c *Client) Hash(ctx context.Context, password string) (string, error) {
+  body, err := c.do(ctx, "POST", "/hash", url.Values{"password": {password}})
+  if err != nil {
+    return "", err
+  }
+  return string(body), nil
+}
+
+// HashAsync starts hashing password in the background and returns the job id to pass to Result
+func (c *Client) HashAsync(ctx context.Context, password string) (string, error) {
+  body, err := c.do(ctx, "POST", "/hash", url.Values{"password": {password}, "async": {"true"}})
+  if err != nil {
+    return "", err
+  }
+  job := Job{}
+  if err := json.Unmarshal(body, &job); err != nil {
+    return "", fmt.Errorf("gohttp: decoding job: %v", err)
+  }
+  return job.ID, nil
+}
+
+// HashWithCallback starts hashing password in the background. the finished job is POSTed to callbackURL as json;
+// check it came from the server with VerifyCallback
+func (c *Client) HashWithCallback(ctx context.Context, password string, callbackURL string) (string, error) {
+  body, err := c.do(ctx, "POST", "/hash", url.Values{"password": {password}, "callback_url": {callbackURL}})
+  if err != nil {
+    return "", err
+  }
+  job := Job{}
+  if err := json.Unmarshal(body, &job); err != nil {
+    return "", fmt.Errorf("gohttp: decoding job: %v", err)
+  }
+  return job.ID, nil
+}
+
+// VerifyCallback checks the X-GoHTTP-Signature of a callback request against the shared secret and decodes the job.
+// callbacks signed more than maxAge ago are refused so they can't be replayed
+func VerifyCallback(r *http.Request, secret []byte, maxAge time.Duration) (*Job, error) {
+  body, err := ioutil.ReadAll(r.Body)
+  if err != nil {
+    return nil, err
+  }
+  timestamp := r.Header.Get("X-GoHTTP-Timestamp")
+  signed, err := strconv.ParseInt(timestamp, 10, 64)
+  if err != nil {
+    return nil, fmt.Errorf("gohttp: bad callback timestamp %q", timestamp)
+  }
+  if age := time.Since(time.Unix(signed, 0)); age > maxAge || age < -maxAge {
+    return nil, fmt.Errorf("gohttp: callback was signed %v ago", age.Round(time.Second))
+  }
+  mac := hmac.New(sha256.New, secret)
+  mac.Write([]byte(timestamp + "."))
+  mac.Write(body)
+  expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+  if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-GoHTTP-Signature"))) {
+    return nil, errors.New("gohttp: callback signature doesn't match")
+  }
+  job := &Job{}
+  if err := json.Unmarshal(body, job); err != nil {
+    return nil, fmt.Errorf("gohttp: decoding job: %v", err)
+  }
+  return job, nil
+}
+
+// Result fetches an async job. The hash is set once Status is done
+func (c *Client) Result(ctx context.Context, id string) (*Job, error) {
+  body, err := c.do(ctx, "GET", "/hash/" + url.PathEscape(id), nil)
+  if err != nil {
+    return nil, err
+  }
+  job := &Job{}
+  if err := json.Unmarshal(body, job); err != nil {
+    return nil, fmt.Errorf("gohttp: decoding job: %v", err)
+  }
+  return job, nil
+}
+
+// CancelJob cancels a pending async job and returns it
+func (c *Client) CancelJob(ctx context.Context, id string) (*Job, error) {
+  body, err := c.do(ctx, "DELETE", "/hash/" + url.PathEscape(id), nil)
+  if err != nil {
+    return nil, err
+  }
+  job := &Job{}
+  if err := json.Unmarshal(body, job); err != nil {
+    return nil, fmt.Errorf("gohttp: decoding job: %v", err)
+  }
+  return job, nil
+}
+
+// ListJobs fetches a page of async jobs, newest first. status, cursor and limit are left out when empty or 0
+func (c *Client) ListJobs(ctx context.Context, status string, cursor string, limit int) (*JobList, error) {
+  query := url.Values{}
+  if status != "" {
+    query.Set("status", status)
+  }
+  if cursor != "" {
+    query.Set("cursor", cursor)
+  }
+  if limit > 0 {
+    query.Set("limit", strconv.Itoa(limit))
+  }
+  path := "/hash"
+  if len(query) > 0 {
+    path += "?" + query.Encode()
+  }
+  body, err := c.do(ctx, "GET", path, nil)
+  if err != nil {
+    return nil, err
+  }
+  list := &JobList{}
+  if err := json.Unmarshal(body, list); err != nil {
+    return nil, fmt.Errorf("gohttp: decoding jobs: %v", err)
+  }
+  return list, nil
+}
+
+// Verify reports whether hash is the hash of password
+func (c *Client) Verify(ctx context.Context, password string, hash string) (bool, error) {
+  body, err := c.do(ctx, "POST", "/verify", url.Values{"password": {password}, "hash": {hash}})
+  if err != nil {
+    return false, err
+  }
+  m := struct{ Match bool }{}
+  if err := json.Unmarshal(body, &m); err != nil {
+    return false, fmt.Errorf("gohttp: decoding verification: %v", err)
+  }
+  return m.Match, nil
+}
+
+// Stats fetches the server's request statistics
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+  body, err := c.do(ctx, "GET", "/stats", nil)
+  if err != nil {
+    return nil, err
+  }
+  stats := &Stats{}
+  if err := json.Unmarshal(body, stats); err != nil {
+    return nil, fmt.Errorf("gohttp: decoding stats: %v", err)
+  }
+  return stats, nil
+}
+
+// Shutdown asks the server to shut down straight away. The server may exit before answering,
+// so a dropped connection counts as success. It is never retried
+func (c *Client) Shutdown(ctx context.Context) error {
+  _, err := c.ScheduleShutdown(ctx, 0, 0)
+  return err
+}
+
+// ScheduleShutdown asks the server to start draining after the delay and to give up on the hashing in progress
+// deadline later; 0 for straight away and for as long as it takes. It replaces a shutdown already scheduled.
+// The status is nil when the server exited before answering. It is never retried
+func (c *Client) ScheduleShutdown(ctx context.Context, after time.Duration, deadline time.Duration) (*ShutdownStatus, error) {
+  form := url.Values{}
+  if after > 0 {
+    form.Set("after", after.String())
+  }
+  if deadline > 0 {
+    form.Set("deadline", deadline.String())
+  }
+  req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL + "/shutdown", strings.NewReader(form.Encode()))
+  if err != nil {
+    return nil, err
+  }
+  req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+  resp, err := c.HTTPClient.Do(req)
+  if err != nil {
+    if ctx.Err() == nil && connectionDropped(err) {
+      return nil, nil
+    }
+    return nil, err
+  }
+  defer resp.Body.Close()
+  body, err := ioutil.ReadAll(resp.Body)
+  if resp.StatusCode >= 300 {
+    return nil, decodeError(resp, body)
+  }
+  if err != nil {
+    if connectionDropped(err) {
+      return nil, nil
+    }
+    return nil, err
+  }
+  return decodeShutdownStatus(body)
+}
+
+// ShutdownStatus reports whether a shutdown is scheduled or draining, and the work it waits on
+func (c *Client) ShutdownStatus(ctx context.Context) (*ShutdownStatus, error) {
+  body, err := c.do(ctx, "GET", "/shutdown", nil)
+  if err != nil {
+    return nil, err
+  }
+  return decodeShutdownStatus(body)
+}
+
+// CancelShutdown calls off a scheduled shutdown that hasn't started draining
+func (c *Client) CancelShutdown(ctx context.Context) (*ShutdownStatus, error) {
+  body, err := c.do(ctx, "DELETE", "/shutdown", nil)
+  if err != nil {
+    return nil, err
+  }
+  return decodeShutdownStatus(body)
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// sends the request, retrying 429 and 503 responses with backoff, and returns the body of a 2xx response
+func (c *Client) do(ctx context.Context, method string, path string, form url.Values) ([]byte, error) {
+  backoff := c.Backoff
+  for attempt := 0; ; attempt++ {
+    var body io.Reader
+    if form != nil {
+      body = strings.NewReader(form.Encode())
+    }
+    req, err := http.NewRequestWithContext(ctx, method, c.BaseURL + path, body)
+    if err != nil {
+      return nil, err
+    }
+    if form != nil {
+      req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    }
+    if c.APIKey != "" {
+      req.Header.Set("Authorization", "Bearer " + c.APIKey)
+    }
+    resp, err := c.HTTPClient.Do(req)
+    if err != nil {
+      return nil, err
+    }
+    respBody, err := ioutil.ReadAll(resp.Body)
+    resp.Body.Close()
+    if err != nil {
+      return nil, err
+    }
+    if resp.StatusCode < 300 {
+      return respBody, nil
+    }
+
+    retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+    if err := decodeError(resp, respBody); !retryable || attempt >= c.MaxRetries || IsCode(err, "quota_exceeded") {
+      return nil, err // a used up daily quota won't come back within any backoff
+    }
+    wait := backoff
+    if after := retryAfter(resp); after > 0 {
+      wait = after
+    }
+    if c.MaxBackoff > 0 && wait > c.MaxBackoff {
+      wait = c.MaxBackoff
+    }
+    select {
+      case <-ctx.Done():
+        return nil, ctx.Err()
+      case <-time.After(wait):
+    }
+    backoff *= 2
+  }
+}
+
+func decodeShutdownStatus(body []byte) (*ShutdownStatus, error) {
+  status := &ShutdownStatus{}
+  if err := json.Unmarshal(body, status); err != nil {
+    return nil, fmt.Errorf("gohttp: decoding shutdown status: %v", err)
+  }
+  return status, nil
+}
+
+// turns an ErrorMessage or problem details response into an *Error
+func decodeError(resp *http.Response, body []byte) error {
+  e := &Error{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-ID")}
+  m := struct {
+    Error string
+    Code string
+    RequestID string
+    Detail string `json:"detail"`
+    ProblemCode string `json:"code"`
+  }{}
+  if json.Unmarshal(body, &m) == nil {
+    e.Message, e.Code = m.Error, m.Code
+    if e.Message == "" {
+      e.Message = m.Detail
+    }
+    if e.Code == "" {
+      e.Code = m.ProblemCode
+    }
+    if m.RequestID != "" {
+      e.RequestID = m.RequestID
+    }
+  }
+  if e.Message == "" {
+    e.Message = http.StatusText(resp.StatusCode)
+  }
+  return e
+}
+
+// Retry-After in seconds; 0 if missing or not a number
+func retryAfter(resp *http.Response) time.Duration {
+  seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+  if err != nil || seconds < 0 {
+    return 0
+  }
+  return time.Duration(seconds) * time.Second
+}
+
+func connectionDropped(err error) bool {
+  return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,282 @@
+package client
+
+import (
+  "testing"
+  "context"
+  "net/http"
+  "net/http/httptest"
+  "strconv"
+  "strings"
+  "sync/atomic"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers"
+  "github.com/rdibari84/GoHTTP/handlers/handlerstest"
+)
+
+const angryMonkeyHash = "ZEHhWB65gUlzdVwtDQArEyx+KVLzp/aTaRaPlBzYRIFj6vjFdqEb0Q5B8zVKCZ0vKbZPZklJz0Fd7su2A+gf7Q=="
+
+//////////////////////////////////////////////
+////////////// Client Unit Tests /////////////
+//////////////////////////////////////////////
+
+func TestHash(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{})
+
+  hash, err := New(ts.URL).Hash(context.Background(), "angryMonkey")
+  if err != nil {
+    t.Fatalf("Expected no error. got %v", err)
+  }
+  if hash != angryMonkeyHash {
+    t.Errorf("Expected %s. got %s", angryMonkeyHash, hash)
+  }
+}
+
+func TestHashAsyncAndResult(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{Delay: handlers.FixedDelay{Delay: 5 * time.Second}})
+  c := New(ts.URL)
+
+  id, err := c.HashAsync(context.Background(), "angryMonkey")
+  if err != nil {
+    t.Fatalf("Expected no error. got %v", err)
+  }
+  job, err := c.Result(context.Background(), id)
+  if err != nil {
+    t.Fatalf("Expected no error. got %v", err)
+  }
+  if job.Status != "pending" {
+    t.Errorf("Expected job to be pending right away. got %s", job.Status)
+  }
+
+  ts.Clock.WaitForSleepers(1)
+  ts.Clock.Advance(5 * time.Second)
+  deadline := time.Now().Add(10 * time.Second)
+  for job.Status == "pending" && time.Now().Before(deadline) {
+    time.Sleep(10 * time.Millisecond)
+    job, err = c.Result(context.Background(), id)
+    if err != nil {
+      t.Fatalf("Expected no error. got %v", err)
+    }
+  }
+  if job.Status != "done" || job.Hash != angryMonkeyHash {
+    t.Errorf("Expected done job with hash. got %+v", job)
+  }
+}
+
+func TestHashWithCallback(t *testing.T) {
+  t.Parallel()
+  secret := []byte("shh")
+  callbacks := make(chan *Job, 1)
+  receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    job, err := VerifyCallback(r, secret, time.Minute)
+    if err != nil {
+      t.Errorf("Expected a verified callback. got %v", err)
+      w.WriteHeader(http.StatusUnauthorized)
+      return
+    }
+    callbacks <- job
+  }))
+  defer receiver.Close()
+  // callbacks are signed with the server's clock, so use the real one
+  ts := handlerstest.NewServer(t, handlers.Config{Clock: handlers.RealClock, Webhooks: &handlers.WebhookConfig{Secret: secret, AllowPrivate: true}})
+
+  id, err := New(ts.URL).HashWithCallback(context.Background(), "angryMonkey", receiver.URL)
+  if err != nil {
+    t.Fatalf("Expected no error. got %v", err)
+  }
+  select {
+    case job := <-callbacks:
+      if job.ID != id || job.Hash != angryMonkeyHash {
+        t.Errorf("Expected the finished job. got %+v", job)
+      }
+    case <-time.After(5 * time.Second):
+      t.Fatalf("Expected a callback")
+  }
+}
+
+func TestVerifyCallbackRejectsForgeries(t *testing.T) {
+  req := httptest.NewRequest("POST", "/done", strings.NewReader(`{"ID":"1"}`))
+  req.Header.Set("X-GoHTTP-Timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+  req.Header.Set("X-GoHTTP-Signature", "sha256=00")
+  if _, err := VerifyCallback(req, []byte("shh"), time.Minute); err == nil {
+    t.Errorf("Expected a bad signature to be refused")
+  }
+}
+
+func TestResultUnknownJob(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{})
+
+  _, err := New(ts.URL).Result(context.Background(), "nope")
+  if !IsCode(err, "job_not_found") {
+    t.Errorf("Expected job_not_found. got %v", err)
+  }
+}
+
+func TestVerify(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{})
+  c := New(ts.URL)
+
+  match, err := c.Verify(context.Background(), "angryMonkey", angryMonkeyHash)
+  if err != nil || !match {
+    t.Errorf("Expected a match. got %v %v", match, err)
+  }
+  match, err = c.Verify(context.Background(), "happyMonkey", angryMonkeyHash)
+  if err != nil || match {
+    t.Errorf("Expected no match. got %v %v", match, err)
+  }
+}
+
+func TestStats(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{})
+
+  stats, err := New(ts.URL).Stats(context.Background())
+  if err != nil {
+    t.Fatalf("Expected no error. got %v", err)
+  }
+  if stats.Total < 0 || stats.Average < 0 {
+    t.Errorf("Expected sane stats. got %+v", stats)
+  }
+}
+
+func TestErrorsAreDecoded(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{})
+
+  _, err := New(ts.URL).Hash(context.Background(), "")
+  e, ok := err.(*Error)
+  if !ok {
+    t.Fatalf("Expected an *Error. got %v", err)
+  }
+  if e.StatusCode != 400 || e.Code != "password_too_short" || e.RequestID == "" {
+    t.Errorf("Expected 400 password_too_short with a request id. got %+v", e)
+  }
+}
+
+func TestAPIKeyIsSent(t *testing.T) {
+  t.Parallel()
+  keys, _ := handlers.LoadKeyStore("")
+  ts := handlerstest.NewServer(t, handlers.Config{Keys: keys})
+  key, err := keys.Create("alice", 0, ts.Clock.Now())
+  if err != nil {
+    t.Fatalf("Expected no error. got %v", err)
+  }
+
+  c := New(ts.URL)
+  if _, err := c.Hash(context.Background(), "angryMonkey"); !IsCode(err, "missing_api_key") {
+    t.Errorf("Expected missing_api_key without a key. got %v", err)
+  }
+  c.APIKey = key.Key
+  if _, err := c.Hash(context.Background(), "angryMonkey"); err != nil {
+    t.Fatalf("Expected no error. got %v", err)
+  }
+  stats, err := c.Stats(context.Background())
+  if err != nil {
+    t.Fatalf("Expected no error. got %v", err)
+  }
+  if len(stats.Keys) != 1 || stats.Keys[0].Name != "alice" || stats.Keys[0].Requests != 1 {
+    t.Errorf("Expected alice's request in the stats. got %+v", stats.Keys)
+  }
+}
+
+func TestRetriesOn503(t *testing.T) {
+  var calls int32
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if atomic.AddInt32(&calls, 1) <= 2 {
+      w.WriteHeader(http.StatusServiceUnavailable)
+      return
+    }
+    w.Write([]byte(`{"Match": true}`))
+  }))
+  defer ts.Close()
+  c := New(ts.URL)
+  c.Backoff = time.Millisecond
+
+  match, err := c.Verify(context.Background(), "angryMonkey", angryMonkeyHash)
+  if err != nil || !match {
+    t.Errorf("Expected success after retries. got %v %v", match, err)
+  }
+  if atomic.LoadInt32(&calls) != 3 {
+    t.Errorf("Expected 3 calls. got %d", calls)
+  }
+}
+
+func TestRetriesGiveUp(t *testing.T) {
+  var calls int32
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    atomic.AddInt32(&calls, 1)
+    w.WriteHeader(http.StatusTooManyRequests)
+  }))
+  defer ts.Close()
+  c := New(ts.URL)
+  c.Backoff = time.Millisecond
+  c.MaxRetries = 2
+
+  _, err := c.Stats(context.Background())
+  if e, ok := err.(*Error); !ok || e.StatusCode != 429 {
+    t.Errorf("Expected a 429 error. got %v", err)
+  }
+  if atomic.LoadInt32(&calls) != 3 {
+    t.Errorf("Expected 1 call and 2 retries. got %d", calls)
+  }
+}
+
+func TestContextCancelsRetries(t *testing.T) {
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    w.WriteHeader(http.StatusServiceUnavailable)
+  }))
+  defer ts.Close()
+  c := New(ts.URL)
+  c.Backoff = time.Hour
+  c.MaxBackoff = time.Hour
+
+  ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+  defer cancel()
+  _, err := c.Stats(ctx)
+  if err != context.DeadlineExceeded {
+    t.Errorf("Expected context.DeadlineExceeded. got %v", err)
+  }
+}
+
+func TestShutdownTreatsDroppedConnectionAsSuccess(t *testing.T) {
+  // the real server exits without answering; hang up the same way
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    conn, _, err := w.(http.Hijacker).Hijack()
+    if err == nil {
+      conn.Close()
+    }
+  }))
+  defer ts.Close()
+
+  if err := New(ts.URL).Shutdown(context.Background()); err != nil {
+    t.Errorf("Expected no error. got %v", err)
+  }
+}
+
+func TestScheduleAndCancelShutdown(t *testing.T) {
+  t.Parallel()
+  clock := handlerstest.NewFakeClock()
+  service := handlers.NewService(handlers.Config{Clock: clock, Delay: handlers.NoDelay{}})
+  ts := httptest.NewUnstartedServer(nil)
+  ts.Config.Handler = service.Routes(ts.Config)
+  ts.Start()
+  defer ts.Close()
+  c := New(ts.URL)
+
+  status, err := c.ScheduleShutdown(context.Background(), time.Minute, 30 * time.Second)
+  if err != nil || status.State != "scheduled" || !status.At.Equal(clock.Now().Add(time.Minute)) {
+    t.Fatalf("Expected a shutdown scheduled in a minute. got %+v %v", status, err)
+  }
+  if status, err = c.ShutdownStatus(context.Background()); err != nil || !status.Deadline.Equal(clock.Now().Add(90 * time.Second)) {
+    t.Errorf("Expected the drain deadline 90s from now. got %+v %v", status, err)
+  }
+  if status, err = c.CancelShutdown(context.Background()); err != nil || status.State != "running" {
+    t.Errorf("Expected the server to keep running. got %+v %v", status, err)
+  }
+  if _, err = c.CancelShutdown(context.Background()); !IsCode(err, "shutdown_not_scheduled") {
+    t.Errorf("Expected shutdown_not_scheduled. got %v", err)
+  }
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math/rand"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+    "github.com/rdibari84/GoHTTP/client"
+)
+
+//////////////////////////////////////////////
+//////////////// Load Generator //////////////
+//////////////////////////////////////////////
+
+// what gohttp bench measured for one endpoint
+type benchEndpoint struct {
+  Endpoint string // "POST /hash" or "GET /stats"
+  Requests int
+  Errors map[string]int `json:",omitempty"` // error code (or status) -> count
+  Throughput float64 // requests per second
+  P50 float64 // latency percentiles in microseconds, like /stats
+  P90 float64
+  P99 float64
+  Max float64
+  Average float64
+  ServerRequests int // how many requests the server's /stats counted during the run; -1 if unknown
+  ServerAverage float64 // the server's average response time for those requests, in microseconds
+}
+
+// the whole gohttp bench report
+type benchReport struct {
+  Duration float64 // seconds
+  Concurrency int
+  Rate float64 `json:",omitempty"`
+  Missed int `json:",omitempty"` // ticks skipped because every worker was busy at the target rate
+  Endpoints []benchEndpoint
+  Warnings []string `json:",omitempty"` // where client and server measurements disagree
+}
+
+// one request made by the load generator
+type benchSample struct {
+  latency time.Duration
+  err string
+}
+
+// gohttp bench [-duration 30s] [-concurrency 10] [-rate 0] [-stats-percent 10] [-output text|json|table]
+func (c *cli) bench(ctx context.Context, args []string) error {
+  fs := c.flags("bench")
+  duration := fs.Duration("duration", 30*time.Second, "how long to generate load")
+  concurrency := fs.Int("concurrency", 10, "requests in flight at once")
+  rate := fs.Float64("rate", 0, "target requests per second. 0 sends as fast as -concurrency allows")
+  statsPercent := fs.Int("stats-percent", 10, "percentage of requests sent to /stats instead of /hash")
+  password := fs.String("password", "angryMonkey", "password to hash")
+  format := fs.String("output", "text", "output format: text, json or table")
+  if err := fs.Parse(args); err != nil {
+    return err
+  }
+  if err := checkFormat(*format); err != nil {
+    return err
+  }
+  if *duration <= 0 || *concurrency < 1 || *statsPercent < 0 || *statsPercent > 100 {
+    return usageError("-duration and -concurrency must be positive and -stats-percent must be 0-100")
+  }
+  if *rate < 0 || *rate > 1e6 {
+    return usageError("-rate must be between 0 and 1000000")
+  }
+
+  // measure raw server behaviour; a retried request would hide the 429/503 it got
+  bc := *c.client
+  bc.MaxRetries = 0
+
+  before, err := bc.Stats(ctx)
+  if err != nil {
+    return fmt.Errorf("fetching starting stats: %v", err)
+  }
+
+  runCtx, cancel := context.WithTimeout(ctx, *duration)
+  defer cancel()
+  var mu sync.Mutex
+  samples := map[string][]benchSample{}
+  record := func(endpoint string, s benchSample) {
+    mu.Lock()
+    samples[endpoint] = append(samples[endpoint], s)
+    mu.Unlock()
+  }
+  // requests run on ctx, not runCtx: once the duration is up no new requests start,
+  // but the ones in flight finish so client and server counts line up
+  request := func(rng *rand.Rand) {
+    start := time.Now()
+    if rng.Intn(100) < *statsPercent {
+      _, err := bc.Stats(ctx)
+      record("GET /stats", benchSample{time.Since(start), errorKey(err)})
+      return
+    }
+    _, err := bc.Hash(ctx, *password)
+    record("POST /hash", benchSample{time.Since(start), errorKey(err)})
+  }
+
+  start := time.Now()
+  missed := runLoad(runCtx, *concurrency, *rate, request)
+  elapsed := time.Since(start)
+
+  after, err := bc.Stats(ctx)
+  if err != nil {
+    return fmt.Errorf("fetching final stats: %v", err)
+  }
+
+  report := buildReport(samples, before, after, elapsed)
+  report.Concurrency, report.Rate, report.Missed = *concurrency, *rate, missed
+  return render(c.stdout, *format, benchResult(report))
+}
+
+// calls request until ctx is done (finishing the requests in flight), either as fast as concurrency workers allow or at rate requests per second.
+// returns how many rate ticks were skipped because every worker was busy
+func runLoad(ctx context.Context, concurrency int, rate float64, request func(rng *rand.Rand)) int {
+  var wg sync.WaitGroup
+  work := make(chan struct{})
+  for i := 0; i < concurrency; i++ {
+    wg.Add(1)
+    go func(seed int64) {
+      defer wg.Done()
+      rng := rand.New(rand.NewSource(seed))
+      for {
+        if rate > 0 {
+          select {
+            case <-ctx.Done():
+              return
+            case <-work:
+          }
+        } else if ctx.Err() != nil {
+          return
+        }
+        request(rng)
+      }
+    }(time.Now().UnixNano() + int64(i))
+  }
+
+  missed := 0
+  if rate > 0 {
+    ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+    defer ticker.Stop()
+    for done := false; !done; {
+      select {
+        case <-ctx.Done():
+          done = true
+        case <-ticker.C:
+          select {
+            case work <- struct{}{}:
+            default:
+              missed++
+          }
+      }
+    }
+  }
+  wg.Wait()
+  return missed
+}
+
+func buildReport(samples map[string][]benchSample, before *client.Stats, after *client.Stats, elapsed time.Duration) benchReport {
+  report := benchReport{Duration: elapsed.Seconds()}
+  endpoints := make([]string, 0, len(samples))
+  for endpoint := range samples {
+    endpoints = append(endpoints, endpoint)
+  }
+  sort.Strings(endpoints)
+
+  for _, endpoint := range endpoints {
+    s := samples[endpoint]
+    latencies := make([]float64, len(s))
+    e := benchEndpoint{Endpoint: endpoint, Requests: len(s), Throughput: float64(len(s)) / elapsed.Seconds()}
+    sum := 0.0
+    for i, sample := range s {
+      latencies[i] = microseconds(sample.latency)
+      sum += latencies[i]
+      if sample.err != "" {
+        if e.Errors == nil {
+          e.Errors = map[string]int{}
+        }
+        e.Errors[sample.err]++
+      }
+    }
+    sort.Float64s(latencies)
+    e.P50, e.P90, e.P99 = percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99)
+    e.Max, e.Average = latencies[len(latencies)-1], sum / float64(len(latencies))
+
+    method, route, _ := strings.Cut(endpoint, " ")
+    e.ServerRequests, e.ServerAverage = serverDelta(before, after, route, method)
+    expected := e.Requests
+    if route == "/stats" {
+      expected++ // the starting /stats call is recorded too
+    }
+    if e.ServerRequests >= 0 && e.ServerRequests != expected {
+      report.Warnings = append(report.Warnings, fmt.Sprintf("%s: sent %d requests but the server counted %d; is something else using the server?", endpoint, expected, e.ServerRequests))
+    }
+    if e.ServerRequests > 0 && e.ServerAverage > e.Average {
+      report.Warnings = append(report.Warnings, fmt.Sprintf("%s: server average %.0fµs is slower than the client's %.0fµs", endpoint, e.ServerAverage, e.Average))
+    }
+    report.Endpoints = append(report.Endpoints, e)
+  }
+  return report
+}
+
+// requests and average latency the server recorded for route and method between two /stats snapshots.
+// -1 when the server doesn't report per endpoint stats
+func serverDelta(before *client.Stats, after *client.Stats, route string, method string) (int, float64) {
+  if after.Endpoints == nil {
+    return -1, 0
+  }
+  countBefore, sumBefore := endpointTotals(before, route, method)
+  countAfter, sumAfter := endpointTotals(after, route, method)
+  count := countAfter - countBefore
+  if count <= 0 {
+    return count, 0
+  }
+  return count, (sumAfter - sumBefore) / float64(count)
+}
+
+func endpointTotals(stats *client.Stats, route string, method string) (int, float64) {
+  count, sum := 0, 0.0
+  for _, e := range stats.Endpoints {
+    if e.Route == route && e.Method == method {
+      count += e.Count
+      sum += e.Average * float64(e.Count)
+    }
+  }
+  return count, sum
+}
+
+func benchResult(report benchReport) result {
+  res := result{
+    text: []string{fmt.Sprintf("%.1fs at concurrency %d", report.Duration, report.Concurrency)},
+    headers: []string{"ENDPOINT", "REQUESTS", "REQ/S", "P50(µs)", "P90(µs)", "P99(µs)", "MAX(µs)", "SERVER", "SERVER AVG(µs)", "ERRORS"},
+    value: report,
+  }
+  if report.Rate > 0 {
+    res.text[0] += fmt.Sprintf(", target %.1f req/s (%d ticks missed)", report.Rate, report.Missed)
+  }
+  for _, e := range report.Endpoints {
+    res.text = append(res.text,
+      fmt.Sprintf("%s: %d requests, %.2f req/s", e.Endpoint, e.Requests, e.Throughput),
+      fmt.Sprintf("  latency p50 %.0fµs p90 %.0fµs p99 %.0fµs max %.0fµs", e.P50, e.P90, e.P99, e.Max),
+      fmt.Sprintf("  server counted %d requests, average %.0fµs", e.ServerRequests, e.ServerAverage))
+    if len(e.Errors) > 0 {
+      res.text = append(res.text, "  errors " + formatCounts(e.Errors))
+    }
+    res.rows = append(res.rows, []string{
+      e.Endpoint, strconv.Itoa(e.Requests), fmt.Sprintf("%.2f", e.Throughput),
+      fmt.Sprintf("%.0f", e.P50), fmt.Sprintf("%.0f", e.P90), fmt.Sprintf("%.0f", e.P99), fmt.Sprintf("%.0f", e.Max),
+      strconv.Itoa(e.ServerRequests), fmt.Sprintf("%.0f", e.ServerAverage), formatCounts(e.Errors),
+    })
+  }
+  for _, warning := range report.Warnings {
+    res.text = append(res.text, "warning: " + warning)
+  }
+  return res
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// nearest rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+  if len(sorted) == 0 {
+    return 0
+  }
+  rank := int(p / 100 * float64(len(sorted)) + 0.5)
+  if rank < 1 {
+    rank = 1
+  }
+  if rank > len(sorted) {
+    rank = len(sorted)
+  }
+  return sorted[rank-1]
+}
+
+// the error code for the breakdown; the status when the server didn't send one
+func errorKey(err error) string {
+  if err == nil {
+    return ""
+  }
+  var e *client.Error
+  if errors.As(err, &e) {
+    if e.Code != "" {
+      return e.Code
+    }
+    return strconv.Itoa(e.StatusCode)
+  }
+  return "transport"
+}
+
+func microseconds(d time.Duration) float64 {
+  return float64(d) / float64(time.Microsecond)
+}
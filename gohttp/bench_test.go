@@ -0,0 +1,78 @@
+package main
+
+import (
+  "testing"
+  "encoding/json"
+  "strings"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers"
+  "github.com/rdibari84/GoHTTP/handlers/handlerstest"
+)
+
+//////////////////////////////////////////////
+//////////////// Bench Unit Tests ////////////
+//////////////////////////////////////////////
+
+func TestBenchStatsOnly(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{})
+
+  code, stdout, stderr := runCLI(t, "", "-server", ts.URL, "bench", "-duration", "200ms", "-concurrency", "2", "-stats-percent", "100", "-output", "json")
+  if code != 0 {
+    t.Fatalf("Expected exit code 0. got %d: %s", code, stderr)
+  }
+  report := benchReport{}
+  if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+    t.Fatalf("Expected a json report. got %s", stdout)
+  }
+  if len(report.Endpoints) != 1 || report.Endpoints[0].Endpoint != "GET /stats" {
+    t.Fatalf("Expected only GET /stats. got %+v", report.Endpoints)
+  }
+  e := report.Endpoints[0]
+  if e.Requests == 0 || e.Throughput <= 0 || e.P50 > e.P99 || e.P99 > e.Max {
+    t.Errorf("Expected sane numbers. got %+v", e)
+  }
+  if e.ServerRequests != e.Requests + 1 {
+    t.Errorf("Expected the server to count every request plus the starting /stats call. got %d vs %d", e.ServerRequests, e.Requests)
+  }
+  if len(report.Warnings) != 0 {
+    t.Errorf("Expected no warnings. got %v", report.Warnings)
+  }
+}
+
+func TestBenchHashAtRate(t *testing.T) {
+  t.Parallel()
+  // real time so the workers really are busy while the ticks go by
+  ts := handlerstest.NewServer(t, handlers.Config{Delay: handlers.FixedDelay{Delay: time.Second}, Clock: handlers.RealClock})
+
+  code, stdout, stderr := runCLI(t, "", "-server", ts.URL, "bench", "-duration", "300ms", "-rate", "10", "-concurrency", "2", "-stats-percent", "0")
+  if code != 0 {
+    t.Fatalf("Expected exit code 0. got %d: %s", code, stderr)
+  }
+  // two workers each get one tick and then sit in a 1 second hash, so the rest of the ticks are missed
+  if !strings.Contains(stdout, "POST /hash: 2 requests") {
+    t.Errorf("Expected 2 hash requests. got %s", stdout)
+  }
+  if !strings.Contains(stdout, "ticks missed") || !strings.Contains(stdout, "server counted 2 requests") {
+    t.Errorf("Expected missed ticks and a matching server count. got %s", stdout)
+  }
+}
+
+func TestBenchUsage(t *testing.T) {
+  if code, _, _ := runCLI(t, "", "bench", "-stats-percent", "101"); code != 2 {
+    t.Errorf("Expected exit code 2. got %d", code)
+  }
+  if code, _, _ := runCLI(t, "", "bench", "-rate", "-1"); code != 2 {
+    t.Errorf("Expected exit code 2. got %d", code)
+  }
+}
+
+func TestPercentile(t *testing.T) {
+  values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+  if percentile(values, 50) != 5 || percentile(values, 90) != 9 || percentile(values, 99) != 10 {
+    t.Errorf("Expected nearest rank percentiles 5, 9 and 10. got %v %v %v", percentile(values, 50), percentile(values, 90), percentile(values, 99))
+  }
+  if percentile(nil, 50) != 0 {
+    t.Errorf("Expected 0 for no values")
+  }
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+    "github.com/rdibari84/GoHTTP/audit"
+    "github.com/rdibari84/GoHTTP/client"
+    "golang.org/x/term"
+)
+
+//////////////////////////////////////////////
+///////////////// Commands ///////////////////
+//////////////////////////////////////////////
+
+// gohttp hash [-file path] [-parallel n] [-output text|json|table]
+func (c *cli) hash(ctx context.Context, args []string) error {
+  fs := c.flags("hash")
+  file := fs.String("file", "", "batch file with one password per line. - reads the batch from stdin")
+  parallel := fs.Int("parallel", 4, "how many batch passwords to hash at once")
+  format := fs.String("output", "text", "output format: text, json or table")
+  if err := fs.Parse(args); err != nil {
+    return err
+  }
+  if err := checkFormat(*format); err != nil {
+    return err
+  }
+
+  if *file == "" {
+    password, err := c.readPassword("Password: ")
+    if err != nil {
+      return err
+    }
+    hash, err := c.client.Hash(ctx, password)
+    if err != nil {
+      return err
+    }
+    return render(c.stdout, *format, result{
+      text: []string{hash},
+      headers: []string{"HASH"},
+      rows: [][]string{{hash}},
+      value: map[string]string{"Hash": hash},
+    })
+  }
+
+  passwords, err := c.readLines(*file)
+  if err != nil {
+    return err
+  }
+  outcomes := make([]batchOutcome, len(passwords))
+  forEach(len(passwords), *parallel, func(i int) {
+    outcomes[i].Line = passwords[i].number
+    outcomes[i].Hash, outcomes[i].err = c.client.Hash(ctx, passwords[i].text)
+  })
+  res := result{headers: []string{"LINE", "HASH", "ERROR"}}
+  for i := range outcomes {
+    o := &outcomes[i]
+    if o.err != nil {
+      o.Error = o.err.Error()
+      res.text = append(res.text, "ERROR " + o.Error)
+    } else {
+      res.text = append(res.text, o.Hash)
+    }
+    res.rows = append(res.rows, []string{strconv.Itoa(o.Line), o.Hash, o.Error})
+  }
+  res.value = outcomes
+  if err := render(c.stdout, *format, res); err != nil {
+    return err
+  }
+  return batchError(outcomes)
+}
+
+// gohttp verify -hash hash | -file path [-output text|json|table]
+func (c *cli) verify(ctx context.Context, args []string) error {
+  fs := c.flags("verify")
+  hash := fs.String("hash", "", "hash to check the password against")
+  file := fs.String("file", "", "batch file with one \"<hash> <password>\" pair per line. - reads the batch from stdin")
+  parallel := fs.Int("parallel", 4, "how many batch entries to verify at once")
+  format := fs.String("output", "text", "output format: text, json or table")
+  if err := fs.Parse(args); err != nil {
+    return err
+  }
+  if err := checkFormat(*format); err != nil {
+    return err
+  }
+
+  if *file == "" {
+    if *hash == "" {
+      return usageError("-hash or -file is required")
+    }
+    password, err := c.readPassword("Password: ")
+    if err != nil {
+      return err
+    }
+    match, err := c.client.Verify(ctx, password, *hash)
+    if err != nil {
+      return err
+    }
+    if err := render(c.stdout, *format, result{
+      text: []string{matchText(match)},
+      headers: []string{"MATCH"},
+      rows: [][]string{{strconv.FormatBool(match)}},
+      value: map[string]bool{"Match": match},
+    }); err != nil {
+      return err
+    }
+    if !match {
+      return fmt.Errorf("password does not match")
+    }
+    return nil
+  }
+
+  lines, err := c.readLines(*file)
+  if err != nil {
+    return err
+  }
+  outcomes := make([]batchOutcome, len(lines))
+  forEach(len(lines), *parallel, func(i int) {
+    outcomes[i].Line = lines[i].number
+    lineHash, password, ok := strings.Cut(lines[i].text, " ")
+    if !ok {
+      outcomes[i].err = fmt.Errorf("expected \"<hash> <password>\"")
+      return
+    }
+    match, err := c.client.Verify(ctx, password, lineHash)
+    outcomes[i].Match, outcomes[i].err = &match, err
+  })
+  res := result{headers: []string{"LINE", "MATCH", "ERROR"}}
+  for i := range outcomes {
+    o := &outcomes[i]
+    if o.err != nil {
+      o.Error, o.Match = o.err.Error(), nil
+      res.text = append(res.text, "ERROR " + o.Error)
+      res.rows = append(res.rows, []string{strconv.Itoa(o.Line), "", o.Error})
+    } else {
+      res.text = append(res.text, matchText(*o.Match))
+      res.rows = append(res.rows, []string{strconv.Itoa(o.Line), strconv.FormatBool(*o.Match), ""})
+    }
+  }
+  res.value = outcomes
+  if err := render(c.stdout, *format, res); err != nil {
+    return err
+  }
+  return batchError(outcomes)
+}
+
+// gohttp stats [-watch] [-interval 2s] [-output text|json|table]
+func (c *cli) stats(ctx context.Context, args []string) error {
+  fs := c.flags("stats")
+  watch := fs.Bool("watch", false, "keep refreshing until interrupted")
+  interval := fs.Duration("interval", 2*time.Second, "refresh interval for -watch")
+  format := fs.String("output", "text", "output format: text, json or table")
+  if err := fs.Parse(args); err != nil {
+    return err
+  }
+  if err := checkFormat(*format); err != nil {
+    return err
+  }
+  if *interval <= 0 {
+    return usageError("-interval must be positive")
+  }
+
+  clearScreen := false
+  if f, ok := c.stdout.(*os.File); ok && *format != "json" {
+    clearScreen = term.IsTerminal(int(f.Fd()))
+  }
+  for {
+    stats, err := c.client.Stats(ctx)
+    if err != nil {
+      if *watch && ctx.Err() != nil { // interrupted
+        return nil
+      }
+      return err
+    }
+    if *watch && clearScreen {
+      fmt.Fprint(c.stdout, "\033[H\033[2J")
+    }
+    if err := render(c.stdout, *format, statsResult(stats)); err != nil {
+      return err
+    }
+    if !*watch {
+      return nil
+    }
+    select {
+      case <-ctx.Done():
+        return nil
+      case <-time.After(*interval):
+    }
+    if !clearScreen && *format != "json" {
+      fmt.Fprintln(c.stdout)
+    }
+  }
+}
+
+// gohttp jobs [-status s] [-limit n] [-cursor c] [-cancel id] [-output text|json|table]
+func (c *cli) jobs(ctx context.Context, args []string) error {
+  fs := c.flags("jobs")
+  status := fs.String("status", "", "only list jobs that are pending, done, failed or cancelled")
+  limit := fs.Int("limit", 0, "jobs per page; the server's default when 0")
+  cursor := fs.String("cursor", "", "the next page, as printed after the previous one")
+  cancel := fs.String("cancel", "", "cancel this pending job instead of listing")
+  format := fs.String("output", "text", "output format: text, json or table")
+  if err := fs.Parse(args); err != nil {
+    return err
+  }
+  if err := checkFormat(*format); err != nil {
+    return err
+  }
+
+  if *cancel != "" {
+    job, err := c.client.CancelJob(ctx, *cancel)
+    if err != nil {
+      return err
+    }
+    return render(c.stdout, *format, jobsResult(&client.JobList{Jobs: []client.Job{*job}}))
+  }
+  list, err := c.client.ListJobs(ctx, *status, *cursor, *limit)
+  if err != nil {
+    return err
+  }
+  return render(c.stdout, *format, jobsResult(list))
+}
+
+// gohttp audit verify [-file path] [-output text|json|table]. reads the audit log straight from disk, so it runs
+// on the server's host rather than against -server
+func (c *cli) audit(ctx context.Context, args []string) error {
+  if len(args) == 0 || args[0] != "verify" {
+    return usageError("usage: gohttp audit verify [-file path]")
+  }
+  fs := c.flags("audit verify")
+  file := fs.String("file", envOr("GOHTTP_AUDIT_LOG", ""), "the audit log the server writes with -audit-log; its rotated files are checked too")
+  format := fs.String("output", "text", "output format: text, json or table")
+  if err := fs.Parse(args[1:]); err != nil {
+    return err
+  }
+  if err := checkFormat(*format); err != nil {
+    return err
+  }
+  if *file == "" {
+    return usageError("-file or $GOHTTP_AUDIT_LOG is required")
+  }
+
+  report, err := audit.Verify(audit.Files(*file)...)
+  if err != nil {
+    return err
+  }
+  text := fmt.Sprintf("ok: %d records in %d files, seq %d to %d", report.Records, report.Files, report.First, report.Last)
+  if !report.Anchored {
+    text += "; older records were rotated away, so the first one is taken on trust"
+  }
+  return render(c.stdout, *format, result{
+    text: []string{text},
+    headers: []string{"FILES", "RECORDS", "FIRST", "LAST", "ANCHORED"},
+    rows: [][]string{{strconv.Itoa(report.Files), strconv.Itoa(report.Records), strconv.FormatInt(report.First, 10),
+      strconv.FormatInt(report.Last, 10), strconv.FormatBool(report.Anchored)}},
+    value: report,
+  })
+}
+
+// gohttp shutdown [-after d] [-deadline d] [-status] [-cancel] [-output text|json|table]
+func (c *cli) shutdown(ctx context.Context, args []string) error {
+  fs := c.flags("shutdown")
+  after := fs.Duration("after", 0, "start draining after this long instead of straight away")
+  deadline := fs.Duration("deadline", 0, "give up on the hashing in progress after draining this long; as long as it takes when 0")
+  status := fs.Bool("status", false, "show whether a shutdown is scheduled instead of requesting one")
+  cancel := fs.Bool("cancel", false, "call off the scheduled shutdown instead of requesting one")
+  format := fs.String("output", "text", "output format: text, json or table")
+  if err := fs.Parse(args); err != nil {
+    return err
+  }
+  if err := checkFormat(*format); err != nil {
+    return err
+  }
+
+  var s *client.ShutdownStatus
+  var err error
+  switch {
+    case *status:
+      s, err = c.client.ShutdownStatus(ctx)
+    case *cancel:
+      s, err = c.client.CancelShutdown(ctx)
+    default:
+      s, err = c.client.ScheduleShutdown(ctx, *after, *deadline)
+      if err == nil && s == nil { // the server exited before answering
+        fmt.Fprintln(c.stdout, "shutdown requested")
+        return nil
+      }
+  }
+  if err != nil {
+    return err
+  }
+  return render(c.stdout, *format, shutdownResult(s))
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// one line of a batch file
+type batchOutcome struct {
+  Line int
+  Hash string `json:",omitempty"`
+  Match *bool `json:",omitempty"`
+  Error string `json:",omitempty"`
+  err error
+}
+
+func batchError(outcomes []batchOutcome) error {
+  failed := 0
+  for _, o := range outcomes {
+    if o.err != nil {
+      failed++
+    }
+  }
+  if failed > 0 {
+    return fmt.Errorf("%d of %d failed", failed, len(outcomes))
+  }
+  return nil
+}
+
+// calls fn for 0..n-1 with at most parallel calls running at once
+func forEach(n int, parallel int, fn func(i int)) {
+  if parallel < 1 {
+    parallel = 1
+  }
+  var wg sync.WaitGroup
+  slots := make(chan struct{}, parallel)
+  for i := 0; i < n; i++ {
+    wg.Add(1)
+    slots <- struct{}{}
+    go func(i int) {
+      defer wg.Done()
+      defer func() { <-slots }()
+      fn(i)
+    }(i)
+  }
+  wg.Wait()
+}
+
+func matchText(match bool) string {
+  if match {
+    return "match"
+  }
+  return "no match"
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "os/signal"
+    "strings"
+    "github.com/rdibari84/GoHTTP/client"
+    "golang.org/x/term"
+)
+
+//////////////////////////////////////////////
+//////////////// gohttp CLI //////////////////
+//////////////////////////////////////////////
+
+const usage = `usage: gohttp [-server url] [-api-key key] <command> [flags]
+
+commands:
+  hash      hash a password read from a prompt, stdin or a batch file
+  verify    check a password against a hash
+  stats     show server stats, optionally refreshing with -watch
+  jobs      list async hash jobs, or cancel a pending one with -cancel
+  audit     audit verify checks the hash chain of the server's audit log
+  shutdown  shut the server down, now or after -after; -status and -cancel for a scheduled one
+  bench     generate load against /hash and /stats and report latency and throughput
+
+run gohttp <command> -h for the command's flags
+`
+
+// where the command reads from and writes to; swapped out in tests
+type cli struct {
+  stdin io.Reader
+  stdout io.Writer
+  stderr io.Writer
+  client *client.Client
+}
+
+func main() {
+  ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+  defer stop()
+  os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run parses the global flags and dispatches to a command. returns the exit code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+  global := flag.NewFlagSet("gohttp", flag.ContinueOnError)
+  global.SetOutput(stderr)
+  global.Usage = func() { fmt.Fprint(stderr, usage) }
+  server := global.String("server", envOr("GOHTTP_SERVER", "http://localhost:8080"), "GoHTTP server url. defaults to $GOHTTP_SERVER")
+  apiKey := global.String("api-key", os.Getenv("GOHTTP_API_KEY"), "API key for servers that need one. defaults to $GOHTTP_API_KEY")
+  if err := global.Parse(args); err != nil {
+    return 2
+  }
+  if global.NArg() == 0 {
+    global.Usage()
+    return 2
+  }
+
+  c := &cli{stdin: stdin, stdout: stdout, stderr: stderr, client: client.New(*server)}
+  c.client.APIKey = *apiKey
+  command, commandArgs := global.Arg(0), global.Args()[1:]
+  var err error
+  switch command {
+    case "hash":
+      err = c.hash(ctx, commandArgs)
+    case "verify":
+      err = c.verify(ctx, commandArgs)
+    case "stats":
+      err = c.stats(ctx, commandArgs)
+    case "jobs":
+      err = c.jobs(ctx, commandArgs)
+    case "audit":
+      err = c.audit(ctx, commandArgs)
+    case "shutdown":
+      err = c.shutdown(ctx, commandArgs)
+    case "bench":
+      err = c.bench(ctx, commandArgs)
+    default:
+      fmt.Fprintf(stderr, "gohttp: unknown command %q\n\n", command)
+      global.Usage()
+      return 2
+  }
+  if err == flag.ErrHelp {
+    return 0
+  }
+  if err != nil {
+    fmt.Fprintf(stderr, "gohttp %s: %v\n", command, err)
+    if _, usageErr := err.(usageError); usageErr {
+      return 2
+    }
+    return 1
+  }
+  return 0
+}
+
+// a mistake in how the command was called rather than a failure talking to the server
+type usageError string
+
+func (u usageError) Error() string {
+  return string(u)
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+func (c *cli) flags(name string) *flag.FlagSet {
+  fs := flag.NewFlagSet(name, flag.ContinueOnError)
+  fs.SetOutput(c.stderr)
+  return fs
+}
+
+// reads the password for a single request: from a no-echo prompt when stdin is a terminal, otherwise the first line of stdin
+func (c *cli) readPassword(prompt string) (string, error) {
+  if f, ok := c.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+    fmt.Fprint(c.stderr, prompt)
+    password, err := term.ReadPassword(int(f.Fd()))
+    fmt.Fprintln(c.stderr)
+    return string(password), err
+  }
+  line, err := bufio.NewReader(c.stdin).ReadString('\n')
+  if err != nil && err != io.EOF {
+    return "", err
+  }
+  if err == io.EOF && line == "" {
+    return "", usageError("no password on stdin")
+  }
+  return strings.TrimRight(line, "\r\n"), nil
+}
+
+// one entry of a batch file and the line it's on, counting from 1
+type batchLine struct {
+  number int
+  text string
+}
+
+// reads one entry per line from path, or from stdin when path is "-". blank lines are skipped but still counted
+func (c *cli) readLines(path string) ([]batchLine, error) {
+  var r io.Reader = c.stdin
+  if path != "-" {
+    f, err := os.Open(path)
+    if err != nil {
+      return nil, err
+    }
+    defer f.Close()
+    r = f
+  }
+  var lines []batchLine
+  scanner := bufio.NewScanner(r)
+  for n := 1; scanner.Scan(); n++ {
+    if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+      lines = append(lines, batchLine{number: n, text: line})
+    }
+  }
+  return lines, scanner.Err()
+}
+
+func envOr(name string, fallback string) string {
+  if value := os.Getenv(name); value != "" {
+    return value
+  }
+  return fallback
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+  "testing"
+  "bytes"
+  "context"
+  "encoding/json"
+  "net/http"
+  "net/http/httptest"
+  "os"
+  "path/filepath"
+  "strings"
+  "time"
+  "github.com/rdibari84/GoHTTP/audit"
+  "github.com/rdibari84/GoHTTP/handlers"
+  "github.com/rdibari84/GoHTTP/handlers/handlerstest"
+)
+
+const angryMonkeyHash = "ZEHhWB65gUlzdVwtDQArEyx+KVLzp/aTaRaPlBzYRIFj6vjFdqEb0Q5B8zVKCZ0vKbZPZklJz0Fd7su2A+gf7Q=="
+
+//////////////////////////////////////////////
+///////////////// CLI Unit Tests /////////////
+//////////////////////////////////////////////
+
+func TestHashFromStdin(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{})
+
+  code, stdout, stderr := runCLI(t, "angryMonkey\n", "-server", ts.URL, "hash")
+  if code != 0 {
+    t.Fatalf("Expected exit code 0. got %d: %s", code, stderr)
+  }
+  if strings.TrimSpace(stdout) != angryMonkeyHash {
+    t.Errorf("Expected %s. got %s", angryMonkeyHash, stdout)
+  }
+}
+
+func TestHashBatchFile(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{})
+
+  path := filepath.Join(t.TempDir(), "passwords.txt")
+  tooLong := strings.Repeat("x", 2000)
+  os.WriteFile(path, []byte("angryMonkey\n\n" + tooLong + "\n"), 0600)
+
+  code, stdout, _ := runCLI(t, "", "-server", ts.URL, "hash", "-file", path, "-output", "json")
+  if code != 1 {
+    t.Errorf("Expected exit code 1 since one line failed. got %d", code)
+  }
+  var outcomes []batchOutcome
+  if err := json.Unmarshal([]byte(stdout), &outcomes); err != nil {
+    t.Fatalf("Expected json output. got %s", stdout)
+  }
+  if len(outcomes) != 2 || outcomes[0].Hash != angryMonkeyHash {
+    t.Errorf("Expected first line to be hashed. got %+v", outcomes)
+  }
+  if len(outcomes) == 2 && (outcomes[1].Line != 3 || !strings.Contains(outcomes[1].Error, "password_too_long")) {
+    t.Errorf("Expected line 3, after the blank one, to fail with password_too_long. got %+v", outcomes[1])
+  }
+}
+
+func TestVerify(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{})
+
+  code, stdout, _ := runCLI(t, "angryMonkey\n", "-server", ts.URL, "verify", "-hash", angryMonkeyHash)
+  if code != 0 || strings.TrimSpace(stdout) != "match" {
+    t.Errorf("Expected match and exit code 0. got %d %s", code, stdout)
+  }
+  code, stdout, _ = runCLI(t, "happyMonkey\n", "-server", ts.URL, "verify", "-hash", angryMonkeyHash)
+  if code != 1 || strings.TrimSpace(stdout) != "no match" {
+    t.Errorf("Expected no match and exit code 1. got %d %s", code, stdout)
+  }
+  code, _, _ = runCLI(t, "angryMonkey\n", "-server", ts.URL, "verify")
+  if code != 2 {
+    t.Errorf("Expected usage error without -hash. got %d", code)
+  }
+}
+
+func TestVerifyBatchTable(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{})
+
+  batch := angryMonkeyHash + " angryMonkey\n" + angryMonkeyHash + " happy Monkey\nnospace\n"
+  code, stdout, _ := runCLI(t, batch, "-server", ts.URL, "verify", "-file", "-", "-output", "table")
+  if code != 1 {
+    t.Errorf("Expected exit code 1 for the malformed line. got %d", code)
+  }
+  lines := strings.Split(strings.TrimSpace(stdout), "\n")
+  if len(lines) != 4 || !strings.HasPrefix(lines[0], "LINE") {
+    t.Fatalf("Expected a header and 3 rows. got %q", stdout)
+  }
+  if !strings.Contains(lines[1], "true") || !strings.Contains(lines[2], "false") || !strings.Contains(lines[3], "expected") {
+    t.Errorf("Expected true, false and an error. got %q", stdout)
+  }
+}
+
+func TestStatsFormats(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{})
+
+  code, stdout, _ := runCLI(t, "", "-server", ts.URL, "stats")
+  if code != 0 || !strings.HasPrefix(stdout, "Total: ") {
+    t.Errorf("Expected text stats. got %d %s", code, stdout)
+  }
+  code, stdout, _ = runCLI(t, "", "-server", ts.URL, "stats", "-output", "table")
+  if code != 0 || !strings.HasPrefix(stdout, "ROUTE") {
+    t.Errorf("Expected a stats table. got %d %s", code, stdout)
+  }
+  code, stdout, _ = runCLI(t, "", "-server", ts.URL, "stats", "-output", "json")
+  stats := map[string]interface{}{}
+  if code != 0 || json.Unmarshal([]byte(stdout), &stats) != nil || stats["Total"] == nil {
+    t.Errorf("Expected json stats. got %d %s", code, stdout)
+  }
+}
+
+func TestStatsWatchStopsOnInterrupt(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{})
+
+  ctx, cancel := context.WithTimeout(context.Background(), 250 * time.Millisecond)
+  defer cancel()
+  var stdout, stderr bytes.Buffer
+  code := run(ctx, []string{"-server", ts.URL, "stats", "-watch", "-interval", "50ms"}, strings.NewReader(""), &stdout, &stderr)
+  if code != 0 {
+    t.Errorf("Expected exit code 0. got %d: %s", code, stderr.String())
+  }
+  if strings.Count(stdout.String(), "Total: ") < 2 {
+    t.Errorf("Expected several refreshes. got %s", stdout.String())
+  }
+}
+
+func TestJobs(t *testing.T) {
+  t.Parallel()
+  ts := handlerstest.NewServer(t, handlers.Config{Delay: handlers.FixedDelay{Delay: time.Minute}})
+  job, apiErr := ts.Service.HashAsync(context.Background(), "angryMonkey", "")
+  if apiErr != nil {
+    t.Fatalf("Expected no error. got %+v", apiErr)
+  }
+
+  code, stdout, stderr := runCLI(t, "", "-server", ts.URL, "jobs", "-status", "pending")
+  if code != 0 || strings.TrimSpace(stdout) != job.ID + " pending" {
+    t.Errorf("Expected the pending job. got %d %s %s", code, stdout, stderr)
+  }
+  code, stdout, _ = runCLI(t, "", "-server", ts.URL, "jobs", "-cancel", job.ID)
+  if code != 0 || strings.TrimSpace(stdout) != job.ID + " cancelled" {
+    t.Errorf("Expected the job to be cancelled. got %d %s", code, stdout)
+  }
+  if code, _, stderr = runCLI(t, "", "-server", ts.URL, "jobs", "-cancel", job.ID); code != 1 || !strings.Contains(stderr, "job_finished") {
+    t.Errorf("Expected job_finished cancelling it again. got %d %s", code, stderr)
+  }
+}
+
+func TestAuditVerify(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "audit.log")
+  l, err := audit.Open(path, 0, 0)
+  if err != nil {
+    t.Fatalf("Did not expect an error but got one. err %v", err)
+  }
+  l.Record(audit.Event{Event: audit.EventKeyCreated, Actor: "admin"})
+  l.Record(audit.Event{Event: audit.EventShutdown, Actor: "admin"})
+  l.Close()
+
+  code, stdout, stderr := runCLI(t, "", "audit", "verify", "-file", path)
+  if code != 0 || !strings.HasPrefix(stdout, "ok: 2 records in 1 files") {
+    t.Errorf("Expected the chain to check out. got %d %s %s", code, stdout, stderr)
+  }
+  data, _ := os.ReadFile(path)
+  os.WriteFile(path, []byte(strings.Replace(string(data), "key_created", "key_revoked", 1)), 0600)
+  if code, _, stderr = runCLI(t, "", "audit", "verify", "-file", path); code != 1 || !strings.Contains(stderr, "has been changed") {
+    t.Errorf("Expected the changed line to be caught. got %d %s", code, stderr)
+  }
+  if code, _, _ = runCLI(t, "", "audit"); code != 2 {
+    t.Errorf("Expected exit code 2 without verify. got %d", code)
+  }
+}
+
+func TestShutdown(t *testing.T) {
+  // the real server exits without answering; hang up the same way
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    conn, _, err := w.(http.Hijacker).Hijack()
+    if err == nil {
+      conn.Close()
+    }
+  }))
+  defer ts.Close()
+
+  code, stdout, _ := runCLI(t, "", "-server", ts.URL, "shutdown")
+  if code != 0 || strings.TrimSpace(stdout) != "shutdown requested" {
+    t.Errorf("Expected shutdown requested. got %d %s", code, stdout)
+  }
+}
+
+func TestScheduledShutdown(t *testing.T) {
+  t.Parallel()
+  service := handlers.NewService(handlers.Config{Clock: handlerstest.NewFakeClock(), Delay: handlers.NoDelay{}})
+  ts := httptest.NewUnstartedServer(nil)
+  ts.Config.Handler = service.Routes(ts.Config)
+  ts.Start()
+  defer ts.Close()
+
+  code, stdout, stderr := runCLI(t, "", "-server", ts.URL, "shutdown", "-after", "1m", "-deadline", "30s")
+  if code != 0 || !strings.HasPrefix(stdout, "scheduled at 2020-01-01T00:01:00Z, giving up on hashing at 2020-01-01T00:01:30Z\n") {
+    t.Errorf("Expected the shutdown to be scheduled. got %d %s %s", code, stdout, stderr)
+  }
+  code, stdout, _ = runCLI(t, "", "-server", ts.URL, "shutdown", "-status", "-output", "json")
+  if code != 0 || !strings.Contains(stdout, `"State": "scheduled"`) {
+    t.Errorf("Expected the scheduled status. got %d %s", code, stdout)
+  }
+  code, stdout, _ = runCLI(t, "", "-server", ts.URL, "shutdown", "-cancel")
+  if code != 0 || !strings.HasPrefix(stdout, "running\n") {
+    t.Errorf("Expected the shutdown to be called off. got %d %s", code, stdout)
+  }
+  if code, _, stderr = runCLI(t, "", "-server", ts.URL, "shutdown", "-cancel"); code != 1 || !strings.Contains(stderr, "shutdown_not_scheduled") {
+    t.Errorf("Expected shutdown_not_scheduled cancelling it again. got %d %s", code, stderr)
+  }
+}
+
+func TestUsageErrors(t *testing.T) {
+  if code, _, _ := runCLI(t, ""); code != 2 {
+    t.Errorf("Expected exit code 2 without a command. got %d", code)
+  }
+  if code, _, _ := runCLI(t, "", "frobnicate"); code != 2 {
+    t.Errorf("Expected exit code 2 for an unknown command. got %d", code)
+  }
+  if code, _, _ := runCLI(t, "", "stats", "-output", "xml"); code != 2 {
+    t.Errorf("Expected exit code 2 for an unknown output format. got %d", code)
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+  var stdout, stderr bytes.Buffer
+  code := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+  return code, stdout.String(), stderr.String()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "sort"
+    "strconv"
+    "strings"
+    "text/tabwriter"
+    "time"
+    "github.com/rdibari84/GoHTTP/client"
+)
+
+//////////////////////////////////////////////
+/////////////////// Output ///////////////////
+//////////////////////////////////////////////
+
+// result is what a command prints. text lines for -output text, headers and rows for -output table, value for -output json
+type result struct {
+  text []string
+  headers []string
+  rows [][]string
+  value interface{}
+}
+
+func checkFormat(format string) error {
+  switch format {
+    case "text", "json", "table":
+      return nil
+  }
+  return usageError("unknown -output " + strconv.Quote(format) + "; use text, json or table")
+}
+
+func render(w io.Writer, format string, res result) error {
+  switch format {
+    case "json":
+      enc := json.NewEncoder(w)
+      enc.SetIndent("", "  ")
+      return enc.Encode(res.value)
+    case "table":
+      tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+      fmt.Fprintln(tw, strings.Join(res.headers, "\t"))
+      for _, row := range res.rows {
+        fmt.Fprintln(tw, strings.Join(row, "\t"))
+      }
+      return tw.Flush()
+    default:
+      for _, line := range res.text {
+        if _, err := fmt.Fprintln(w, line); err != nil {
+          return err
+        }
+      }
+      return nil
+  }
+}
+
+func statsResult(stats *client.Stats) result {
+  res := result{
+    text: []string{
+      fmt.Sprintf("Total: %d", stats.Total),
+      fmt.Sprintf("Average: %.0fµs", stats.Average),
+    },
+    headers: []string{"ROUTE", "METHOD", "STATUS", "COUNT", "AVG(µs)", "MAX(µs)", "ERRORS"},
+    value: stats,
+  }
+  if stats.Pool != nil {
+    line := fmt.Sprintf("Workers: %d busy of %d", stats.Pool.Busy, stats.Pool.Workers)
+    for _, lane := range stats.Pool.Lanes {
+      line += fmt.Sprintf(", %s %d queued (avg wait %.0fµs)", lane.Lane, lane.Queued, lane.WaitAverage)
+    }
+    res.text = append(res.text, line)
+  }
+  if len(stats.Protocols) > 0 {
+    res.text = append(res.text, "Protocols: " + formatCounts(stats.Protocols))
+  }
+  if c := stats.Cache; c != nil && c.Capacity > 0 {
+    res.text = append(res.text, fmt.Sprintf("Cache: %d of %d entries, %d hits, %d misses, %d coalesced", c.Entries, c.Capacity, c.Hits, c.Misses, c.Coalesced))
+  } else if c != nil && c.Coalesced > 0 {
+    res.text = append(res.text, fmt.Sprintf("Coalesced: %d", c.Coalesced))
+  }
+  for _, k := range stats.Keys {
+    quota := "no quota"
+    if k.DailyQuota > 0 {
+      quota = fmt.Sprintf("%d of %d today", k.Today, k.DailyQuota)
+    }
+    res.text = append(res.text, fmt.Sprintf("Key %s (%s): %d requests, %s, %d rejected, avg %.0fµs", k.Name, k.ID, k.Requests, quota, k.Rejected, k.Average))
+  }
+  for _, e := range stats.Endpoints {
+    errors := formatCounts(e.Errors)
+    line := fmt.Sprintf("%s %s %d: %d requests, avg %.0fµs, max %.0fµs", e.Method, e.Route, e.Status, e.Count, e.Average, e.Max)
+    if errors != "" {
+      line += " (" + errors + ")"
+    }
+    res.text = append(res.text, line)
+    res.rows = append(res.rows, []string{
+      e.Route, e.Method, strconv.Itoa(e.Status), strconv.Itoa(e.Count),
+      fmt.Sprintf("%.0f", e.Average), fmt.Sprintf("%.0f", e.Max), errors,
+    })
+  }
+  return res
+}
+
+func jobsResult(list *client.JobList) result {
+  res := result{headers: []string{"ID", "STATUS", "ALGORITHM", "CREATED", "DURATION"}, value: list}
+  for _, job := range list.Jobs {
+    duration := ""
+    if !job.Finished.IsZero() {
+      duration = (time.Duration(job.Duration) * time.Microsecond).String()
+    }
+    res.text = append(res.text, job.ID + " " + job.Status)
+    res.rows = append(res.rows, []string{job.ID, job.Status, job.Algorithm, job.Created.Format(time.RFC3339), duration})
+  }
+  if list.Next != "" {
+    res.text = append(res.text, "Next: " + list.Next)
+  }
+  return res
+}
+
+func shutdownResult(status *client.ShutdownStatus) result {
+  line, at, deadline := status.State, "", ""
+  if !status.At.IsZero() {
+    at = status.At.Format(time.RFC3339)
+    line += " at " + at
+  }
+  if !status.Deadline.IsZero() {
+    deadline = status.Deadline.Format(time.RFC3339)
+    line += ", giving up on hashing at " + deadline
+  }
+  return result{
+    text: []string{line, fmt.Sprintf("%d hashes in progress, %d pending jobs, %d requests in flight",
+      status.HashesInProgress, status.PendingJobs, status.RequestsInFlight)},
+    headers: []string{"STATE", "AT", "DEADLINE", "HASHES", "PENDING JOBS", "REQUESTS"},
+    rows: [][]string{{status.State, at, deadline, strconv.Itoa(status.HashesInProgress), strconv.Itoa(status.PendingJobs),
+      strconv.Itoa(status.RequestsInFlight)}},
+    value: status,
+  }
+}
+
+// name=count pairs, sorted so the output is stable between refreshes
+func formatCounts(counts map[string]int) string {
+  pairs := make([]string, 0, len(counts))
+  for name, count := range counts {
+    pairs = append(pairs, fmt.Sprintf("%s=%d", name, count))
+  }
+  sort.Strings(pairs)
+  return strings.Join(pairs, " ")
+}
@@ -0,0 +1,1495 @@
+// gRPC version of the gohttp api. the same Service backs both, so hashes, jobs and stats are shared with the http endpoints.
+// regenerate gohttp.pb.go and gohttp_grpc.pb.go after editing:
+//   protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative gohttppb/gohttp.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: gohttppb/gohttp.proto
+
+package gohttppb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type HashRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Password      string                 `protobuf:"bytes,1,opt,name=password,proto3" json:"password,omitempty"`
+	CallbackUrl   string                 `protobuf:"bytes,2,opt,name=callback_url,json=callbackUrl,proto3" json:"callback_url,omitempty"` // HashAsync only: the finished job is POSTed here, signed like the http api's callbacks
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HashRequest) Reset() {
+	*x = HashRequest{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HashRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HashRequest) ProtoMessage() {}
+
+func (x *HashRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HashRequest.ProtoReflect.Descriptor instead.
+func (*HashRequest) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *HashRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *HashRequest) GetCallbackUrl() string {
+	if x != nil {
+		return x.CallbackUrl
+	}
+	return ""
+}
+
+type HashResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HashResponse) Reset() {
+	*x = HashResponse{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HashResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HashResponse) ProtoMessage() {}
+
+func (x *HashResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HashResponse.ProtoReflect.Descriptor instead.
+func (*HashResponse) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *HashResponse) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+type GetResultRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResultRequest) Reset() {
+	*x = GetResultRequest{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResultRequest) ProtoMessage() {}
+
+func (x *GetResultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResultRequest.ProtoReflect.Descriptor instead.
+func (*GetResultRequest) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetResultRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CancelJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelJobRequest) Reset() {
+	*x = CancelJobRequest{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobRequest) ProtoMessage() {}
+
+func (x *CancelJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelJobRequest.ProtoReflect.Descriptor instead.
+func (*CancelJobRequest) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CancelJobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ListJobsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"` // pending, done, failed or cancelled. every job when empty
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`  // 100 when 0
+	Cursor        string                 `protobuf:"bytes,3,opt,name=cursor,proto3" json:"cursor,omitempty"` // next from the previous page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsRequest) Reset() {
+	*x = ListJobsRequest{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsRequest) ProtoMessage() {}
+
+func (x *ListJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListJobsRequest) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListJobsRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListJobsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type JobList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jobs          []*Job                 `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	Next          string                 `protobuf:"bytes,2,opt,name=next,proto3" json:"next,omitempty"` // empty on the last page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobList) Reset() {
+	*x = JobList{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobList) ProtoMessage() {}
+
+func (x *JobList) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobList.ProtoReflect.Descriptor instead.
+func (*JobList) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *JobList) GetJobs() []*Job {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+func (x *JobList) GetNext() string {
+	if x != nil {
+		return x.Next
+	}
+	return ""
+}
+
+type Job struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"` // pending, done, failed or cancelled
+	Hash          string                 `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`     // set once status is done
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`   // set once status is failed
+	Created       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created,proto3" json:"created,omitempty"`
+	Finished      *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=finished,proto3" json:"finished,omitempty"`
+	Callback      *Callback              `protobuf:"bytes,7,opt,name=callback,proto3" json:"callback,omitempty"`    // set when a callback_url was given
+	Algorithm     string                 `protobuf:"bytes,8,opt,name=algorithm,proto3" json:"algorithm,omitempty"`  // e.g. sha512
+	Started       *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=started,proto3" json:"started,omitempty"`      // when the delay was over and hashing began
+	Duration      float64                `protobuf:"fixed64,10,opt,name=duration,proto3" json:"duration,omitempty"` // from created to finished, in microseconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Job) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Job) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Job) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Job) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Job) GetCreated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+func (x *Job) GetFinished() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Finished
+	}
+	return nil
+}
+
+func (x *Job) GetCallback() *Callback {
+	if x != nil {
+		return x.Callback
+	}
+	return nil
+}
+
+func (x *Job) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+func (x *Job) GetStarted() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Started
+	}
+	return nil
+}
+
+func (x *Job) GetDuration() float64 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+type Callback struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"` // pending, delivered or failed
+	Attempts      []*CallbackAttempt     `protobuf:"bytes,3,rep,name=attempts,proto3" json:"attempts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Callback) Reset() {
+	*x = Callback{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Callback) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Callback) ProtoMessage() {}
+
+func (x *Callback) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Callback.ProtoReflect.Descriptor instead.
+func (*Callback) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Callback) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Callback) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Callback) GetAttempts() []*CallbackAttempt {
+	if x != nil {
+		return x.Attempts
+	}
+	return nil
+}
+
+type CallbackAttempt struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	At            *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=at,proto3" json:"at,omitempty"`
+	StatusCode    int32                  `protobuf:"varint,2,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"` // the receiver's response, if it answered
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallbackAttempt) Reset() {
+	*x = CallbackAttempt{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallbackAttempt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallbackAttempt) ProtoMessage() {}
+
+func (x *CallbackAttempt) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallbackAttempt.ProtoReflect.Descriptor instead.
+func (*CallbackAttempt) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CallbackAttempt) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+func (x *CallbackAttempt) GetStatusCode() int32 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *CallbackAttempt) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type VerifyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Password      string                 `protobuf:"bytes,1,opt,name=password,proto3" json:"password,omitempty"`
+	Hash          string                 `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyRequest) Reset() {
+	*x = VerifyRequest{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyRequest) ProtoMessage() {}
+
+func (x *VerifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyRequest.ProtoReflect.Descriptor instead.
+func (*VerifyRequest) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *VerifyRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *VerifyRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+type VerifyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Match         bool                   `protobuf:"varint,1,opt,name=match,proto3" json:"match,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyResponse) Reset() {
+	*x = VerifyResponse{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyResponse) ProtoMessage() {}
+
+func (x *VerifyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyResponse.ProtoReflect.Descriptor instead.
+func (*VerifyResponse) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *VerifyResponse) GetMatch() bool {
+	if x != nil {
+		return x.Match
+	}
+	return false
+}
+
+type GetStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsRequest) Reset() {
+	*x = GetStatsRequest{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsRequest) ProtoMessage() {}
+
+func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{11}
+}
+
+type Stats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Total         int64                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`      // number of successful hashes
+	Average       float64                `protobuf:"fixed64,2,opt,name=average,proto3" json:"average,omitempty"` // average hash response time in microseconds
+	Endpoints     []*EndpointStats       `protobuf:"bytes,3,rep,name=endpoints,proto3" json:"endpoints,omitempty"`
+	Pool          *PoolStats             `protobuf:"bytes,4,opt,name=pool,proto3" json:"pool,omitempty"`
+	Protocols     map[string]int64       `protobuf:"bytes,5,rep,name=protocols,proto3" json:"protocols,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // requests per protocol: HTTP/1.1, HTTP/2.0
+	Keys          []*KeyStats            `protobuf:"bytes,6,rep,name=keys,proto3" json:"keys,omitempty"`                                                                                      // usage per API key, when the server has API keys
+	Cache         *CacheStats            `protobuf:"bytes,7,opt,name=cache,proto3" json:"cache,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Stats) Reset() {
+	*x = Stats{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Stats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stats) ProtoMessage() {}
+
+func (x *Stats) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stats.ProtoReflect.Descriptor instead.
+func (*Stats) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Stats) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Stats) GetAverage() float64 {
+	if x != nil {
+		return x.Average
+	}
+	return 0
+}
+
+func (x *Stats) GetEndpoints() []*EndpointStats {
+	if x != nil {
+		return x.Endpoints
+	}
+	return nil
+}
+
+func (x *Stats) GetPool() *PoolStats {
+	if x != nil {
+		return x.Pool
+	}
+	return nil
+}
+
+func (x *Stats) GetProtocols() map[string]int64 {
+	if x != nil {
+		return x.Protocols
+	}
+	return nil
+}
+
+func (x *Stats) GetKeys() []*KeyStats {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *Stats) GetCache() *CacheStats {
+	if x != nil {
+		return x.Cache
+	}
+	return nil
+}
+
+type EndpointStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Route         string                 `protobuf:"bytes,1,opt,name=route,proto3" json:"route,omitempty"`
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`  // GRPC for gRPC calls
+	Status        int32                  `protobuf:"varint,3,opt,name=status,proto3" json:"status,omitempty"` // http status, or the http equivalent of the gRPC code
+	Count         int64                  `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	Average       float64                `protobuf:"fixed64,5,opt,name=average,proto3" json:"average,omitempty"`                                                                        // microseconds
+	Max           float64                `protobuf:"fixed64,6,opt,name=max,proto3" json:"max,omitempty"`                                                                                // microseconds
+	Errors        map[string]int64       `protobuf:"bytes,7,rep,name=errors,proto3" json:"errors,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // error code -> count
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EndpointStats) Reset() {
+	*x = EndpointStats{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EndpointStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EndpointStats) ProtoMessage() {}
+
+func (x *EndpointStats) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EndpointStats.ProtoReflect.Descriptor instead.
+func (*EndpointStats) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *EndpointStats) GetRoute() string {
+	if x != nil {
+		return x.Route
+	}
+	return ""
+}
+
+func (x *EndpointStats) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *EndpointStats) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetAverage() float64 {
+	if x != nil {
+		return x.Average
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetMax() float64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *EndpointStats) GetErrors() map[string]int64 {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+type PoolStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Workers       int32                  `protobuf:"varint,1,opt,name=workers,proto3" json:"workers,omitempty"`
+	Busy          int32                  `protobuf:"varint,2,opt,name=busy,proto3" json:"busy,omitempty"`
+	QueueCapacity int32                  `protobuf:"varint,3,opt,name=queue_capacity,json=queueCapacity,proto3" json:"queue_capacity,omitempty"`
+	Lanes         []*LaneStats           `protobuf:"bytes,4,rep,name=lanes,proto3" json:"lanes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PoolStats) Reset() {
+	*x = PoolStats{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PoolStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PoolStats) ProtoMessage() {}
+
+func (x *PoolStats) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PoolStats.ProtoReflect.Descriptor instead.
+func (*PoolStats) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PoolStats) GetWorkers() int32 {
+	if x != nil {
+		return x.Workers
+	}
+	return 0
+}
+
+func (x *PoolStats) GetBusy() int32 {
+	if x != nil {
+		return x.Busy
+	}
+	return 0
+}
+
+func (x *PoolStats) GetQueueCapacity() int32 {
+	if x != nil {
+		return x.QueueCapacity
+	}
+	return 0
+}
+
+func (x *PoolStats) GetLanes() []*LaneStats {
+	if x != nil {
+		return x.Lanes
+	}
+	return nil
+}
+
+type LaneStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Lane          string                 `protobuf:"bytes,1,opt,name=lane,proto3" json:"lane,omitempty"`
+	Queued        int32                  `protobuf:"varint,2,opt,name=queued,proto3" json:"queued,omitempty"`
+	Completed     int64                  `protobuf:"varint,3,opt,name=completed,proto3" json:"completed,omitempty"`
+	Rejected      int64                  `protobuf:"varint,4,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	Cancelled     int64                  `protobuf:"varint,5,opt,name=cancelled,proto3" json:"cancelled,omitempty"`
+	WaitAverage   float64                `protobuf:"fixed64,6,opt,name=wait_average,json=waitAverage,proto3" json:"wait_average,omitempty"` // microseconds
+	WaitMax       float64                `protobuf:"fixed64,7,opt,name=wait_max,json=waitMax,proto3" json:"wait_max,omitempty"`             // microseconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LaneStats) Reset() {
+	*x = LaneStats{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LaneStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LaneStats) ProtoMessage() {}
+
+func (x *LaneStats) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LaneStats.ProtoReflect.Descriptor instead.
+func (*LaneStats) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *LaneStats) GetLane() string {
+	if x != nil {
+		return x.Lane
+	}
+	return ""
+}
+
+func (x *LaneStats) GetQueued() int32 {
+	if x != nil {
+		return x.Queued
+	}
+	return 0
+}
+
+func (x *LaneStats) GetCompleted() int64 {
+	if x != nil {
+		return x.Completed
+	}
+	return 0
+}
+
+func (x *LaneStats) GetRejected() int64 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *LaneStats) GetCancelled() int64 {
+	if x != nil {
+		return x.Cancelled
+	}
+	return 0
+}
+
+func (x *LaneStats) GetWaitAverage() float64 {
+	if x != nil {
+		return x.WaitAverage
+	}
+	return 0
+}
+
+func (x *LaneStats) GetWaitMax() float64 {
+	if x != nil {
+		return x.WaitMax
+	}
+	return 0
+}
+
+type KeyStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Requests      int64                  `protobuf:"varint,3,opt,name=requests,proto3" json:"requests,omitempty"`                       // since the server started
+	Today         int64                  `protobuf:"varint,4,opt,name=today,proto3" json:"today,omitempty"`                             // counted against the daily quota
+	DailyQuota    int64                  `protobuf:"varint,5,opt,name=daily_quota,json=dailyQuota,proto3" json:"daily_quota,omitempty"` // 0 for no limit
+	Rejected      int64                  `protobuf:"varint,6,opt,name=rejected,proto3" json:"rejected,omitempty"`                       // refused for being over the quota
+	Average       float64                `protobuf:"fixed64,7,opt,name=average,proto3" json:"average,omitempty"`                        // microseconds
+	Max           float64                `protobuf:"fixed64,8,opt,name=max,proto3" json:"max,omitempty"`                                // microseconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyStats) Reset() {
+	*x = KeyStats{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyStats) ProtoMessage() {}
+
+func (x *KeyStats) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyStats.ProtoReflect.Descriptor instead.
+func (*KeyStats) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *KeyStats) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *KeyStats) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *KeyStats) GetRequests() int64 {
+	if x != nil {
+		return x.Requests
+	}
+	return 0
+}
+
+func (x *KeyStats) GetToday() int64 {
+	if x != nil {
+		return x.Today
+	}
+	return 0
+}
+
+func (x *KeyStats) GetDailyQuota() int64 {
+	if x != nil {
+		return x.DailyQuota
+	}
+	return 0
+}
+
+func (x *KeyStats) GetRejected() int64 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *KeyStats) GetAverage() float64 {
+	if x != nil {
+		return x.Average
+	}
+	return 0
+}
+
+func (x *KeyStats) GetMax() float64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+type CacheStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       int64                  `protobuf:"varint,1,opt,name=entries,proto3" json:"entries,omitempty"`
+	Capacity      int64                  `protobuf:"varint,2,opt,name=capacity,proto3" json:"capacity,omitempty"` // 0 when the cache is off
+	Hits          int64                  `protobuf:"varint,3,opt,name=hits,proto3" json:"hits,omitempty"`
+	Misses        int64                  `protobuf:"varint,4,opt,name=misses,proto3" json:"misses,omitempty"`
+	Coalesced     int64                  `protobuf:"varint,5,opt,name=coalesced,proto3" json:"coalesced,omitempty"` // requests that waited on an identical hash in progress
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CacheStats) Reset() {
+	*x = CacheStats{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CacheStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheStats) ProtoMessage() {}
+
+func (x *CacheStats) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheStats.ProtoReflect.Descriptor instead.
+func (*CacheStats) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *CacheStats) GetEntries() int64 {
+	if x != nil {
+		return x.Entries
+	}
+	return 0
+}
+
+func (x *CacheStats) GetCapacity() int64 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *CacheStats) GetHits() int64 {
+	if x != nil {
+		return x.Hits
+	}
+	return 0
+}
+
+func (x *CacheStats) GetMisses() int64 {
+	if x != nil {
+		return x.Misses
+	}
+	return 0
+}
+
+func (x *CacheStats) GetCoalesced() int64 {
+	if x != nil {
+		return x.Coalesced
+	}
+	return 0
+}
+
+type ShutdownRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShutdownRequest) Reset() {
+	*x = ShutdownRequest{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShutdownRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShutdownRequest) ProtoMessage() {}
+
+func (x *ShutdownRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShutdownRequest.ProtoReflect.Descriptor instead.
+func (*ShutdownRequest) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{18}
+}
+
+type ShutdownResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShutdownResponse) Reset() {
+	*x = ShutdownResponse{}
+	mi := &file_gohttppb_gohttp_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShutdownResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShutdownResponse) ProtoMessage() {}
+
+func (x *ShutdownResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gohttppb_gohttp_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShutdownResponse.ProtoReflect.Descriptor instead.
+func (*ShutdownResponse) Descriptor() ([]byte, []int) {
+	return file_gohttppb_gohttp_proto_rawDescGZIP(), []int{19}
+}
+
+var File_gohttppb_gohttp_proto protoreflect.FileDescriptor
+
+const file_gohttppb_gohttp_proto_rawDesc = "" +
+	"\n" +
+	"\x15gohttppb/gohttp.proto\x12\tgohttp.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"L\n" +
+	"\vHashRequest\x12\x1a\n" +
+	"\bpassword\x18\x01 \x01(\tR\bpassword\x12!\n" +
+	"\fcallback_url\x18\x02 \x01(\tR\vcallbackUrl\"\"\n" +
+	"\fHashResponse\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\"\"\n" +
+	"\x10GetResultRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\"\n" +
+	"\x10CancelJobRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"W\n" +
+	"\x0fListJobsRequest\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\x03 \x01(\tR\x06cursor\"A\n" +
+	"\aJobList\x12\"\n" +
+	"\x04jobs\x18\x01 \x03(\v2\x0e.gohttp.v1.JobR\x04jobs\x12\x12\n" +
+	"\x04next\x18\x02 \x01(\tR\x04next\"\xe6\x02\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x12\n" +
+	"\x04hash\x18\x03 \x01(\tR\x04hash\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x124\n" +
+	"\acreated\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\acreated\x126\n" +
+	"\bfinished\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\bfinished\x12/\n" +
+	"\bcallback\x18\a \x01(\v2\x13.gohttp.v1.CallbackR\bcallback\x12\x1c\n" +
+	"\talgorithm\x18\b \x01(\tR\talgorithm\x124\n" +
+	"\astarted\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\astarted\x12\x1a\n" +
+	"\bduration\x18\n" +
+	" \x01(\x01R\bduration\"l\n" +
+	"\bCallback\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x126\n" +
+	"\battempts\x18\x03 \x03(\v2\x1a.gohttp.v1.CallbackAttemptR\battempts\"t\n" +
+	"\x0fCallbackAttempt\x12*\n" +
+	"\x02at\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x02at\x12\x1f\n" +
+	"\vstatus_code\x18\x02 \x01(\x05R\n" +
+	"statusCode\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"?\n" +
+	"\rVerifyRequest\x12\x1a\n" +
+	"\bpassword\x18\x01 \x01(\tR\bpassword\x12\x12\n" +
+	"\x04hash\x18\x02 \x01(\tR\x04hash\"&\n" +
+	"\x0eVerifyResponse\x12\x14\n" +
+	"\x05match\x18\x01 \x01(\bR\x05match\"\x11\n" +
+	"\x0fGetStatsRequest\"\xec\x02\n" +
+	"\x05Stats\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x03R\x05total\x12\x18\n" +
+	"\aaverage\x18\x02 \x01(\x01R\aaverage\x126\n" +
+	"\tendpoints\x18\x03 \x03(\v2\x18.gohttp.v1.EndpointStatsR\tendpoints\x12(\n" +
+	"\x04pool\x18\x04 \x01(\v2\x14.gohttp.v1.PoolStatsR\x04pool\x12=\n" +
+	"\tprotocols\x18\x05 \x03(\v2\x1f.gohttp.v1.Stats.ProtocolsEntryR\tprotocols\x12'\n" +
+	"\x04keys\x18\x06 \x03(\v2\x13.gohttp.v1.KeyStatsR\x04keys\x12+\n" +
+	"\x05cache\x18\a \x01(\v2\x15.gohttp.v1.CacheStatsR\x05cache\x1a<\n" +
+	"\x0eProtocolsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x90\x02\n" +
+	"\rEndpointStats\x12\x14\n" +
+	"\x05route\x18\x01 \x01(\tR\x05route\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\x05R\x06status\x12\x14\n" +
+	"\x05count\x18\x04 \x01(\x03R\x05count\x12\x18\n" +
+	"\aaverage\x18\x05 \x01(\x01R\aaverage\x12\x10\n" +
+	"\x03max\x18\x06 \x01(\x01R\x03max\x12<\n" +
+	"\x06errors\x18\a \x03(\v2$.gohttp.v1.EndpointStats.ErrorsEntryR\x06errors\x1a9\n" +
+	"\vErrorsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x8c\x01\n" +
+	"\tPoolStats\x12\x18\n" +
+	"\aworkers\x18\x01 \x01(\x05R\aworkers\x12\x12\n" +
+	"\x04busy\x18\x02 \x01(\x05R\x04busy\x12%\n" +
+	"\x0equeue_capacity\x18\x03 \x01(\x05R\rqueueCapacity\x12*\n" +
+	"\x05lanes\x18\x04 \x03(\v2\x14.gohttp.v1.LaneStatsR\x05lanes\"\xcd\x01\n" +
+	"\tLaneStats\x12\x12\n" +
+	"\x04lane\x18\x01 \x01(\tR\x04lane\x12\x16\n" +
+	"\x06queued\x18\x02 \x01(\x05R\x06queued\x12\x1c\n" +
+	"\tcompleted\x18\x03 \x01(\x03R\tcompleted\x12\x1a\n" +
+	"\brejected\x18\x04 \x01(\x03R\brejected\x12\x1c\n" +
+	"\tcancelled\x18\x05 \x01(\x03R\tcancelled\x12!\n" +
+	"\fwait_average\x18\x06 \x01(\x01R\vwaitAverage\x12\x19\n" +
+	"\bwait_max\x18\a \x01(\x01R\awaitMax\"\xc9\x01\n" +
+	"\bKeyStats\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
+	"\brequests\x18\x03 \x01(\x03R\brequests\x12\x14\n" +
+	"\x05today\x18\x04 \x01(\x03R\x05today\x12\x1f\n" +
+	"\vdaily_quota\x18\x05 \x01(\x03R\n" +
+	"dailyQuota\x12\x1a\n" +
+	"\brejected\x18\x06 \x01(\x03R\brejected\x12\x18\n" +
+	"\aaverage\x18\a \x01(\x01R\aaverage\x12\x10\n" +
+	"\x03max\x18\b \x01(\x01R\x03max\"\x8c\x01\n" +
+	"\n" +
+	"CacheStats\x12\x18\n" +
+	"\aentries\x18\x01 \x01(\x03R\aentries\x12\x1a\n" +
+	"\bcapacity\x18\x02 \x01(\x03R\bcapacity\x12\x12\n" +
+	"\x04hits\x18\x03 \x01(\x03R\x04hits\x12\x16\n" +
+	"\x06misses\x18\x04 \x01(\x03R\x06misses\x12\x1c\n" +
+	"\tcoalesced\x18\x05 \x01(\x03R\tcoalesced\"\x11\n" +
+	"\x0fShutdownRequest\"\x12\n" +
+	"\x10ShutdownResponse2\xe9\x03\n" +
+	"\vHashService\x127\n" +
+	"\x04Hash\x12\x16.gohttp.v1.HashRequest\x1a\x17.gohttp.v1.HashResponse\x123\n" +
+	"\tHashAsync\x12\x16.gohttp.v1.HashRequest\x1a\x0e.gohttp.v1.Job\x128\n" +
+	"\tGetResult\x12\x1b.gohttp.v1.GetResultRequest\x1a\x0e.gohttp.v1.Job\x128\n" +
+	"\tCancelJob\x12\x1b.gohttp.v1.CancelJobRequest\x1a\x0e.gohttp.v1.Job\x12:\n" +
+	"\bListJobs\x12\x1a.gohttp.v1.ListJobsRequest\x1a\x12.gohttp.v1.JobList\x12=\n" +
+	"\x06Verify\x12\x18.gohttp.v1.VerifyRequest\x1a\x19.gohttp.v1.VerifyResponse\x128\n" +
+	"\bGetStats\x12\x1a.gohttp.v1.GetStatsRequest\x1a\x10.gohttp.v1.Stats\x12C\n" +
+	"\bShutdown\x12\x1a.gohttp.v1.ShutdownRequest\x1a\x1b.gohttp.v1.ShutdownResponseB&Z$github.com/rdibari84/GoHTTP/gohttppbb\x06proto3"
+
+var (
+	file_gohttppb_gohttp_proto_rawDescOnce sync.Once
+	file_gohttppb_gohttp_proto_rawDescData []byte
+)
+
+func file_gohttppb_gohttp_proto_rawDescGZIP() []byte {
+	file_gohttppb_gohttp_proto_rawDescOnce.Do(func() {
+		file_gohttppb_gohttp_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gohttppb_gohttp_proto_rawDesc), len(file_gohttppb_gohttp_proto_rawDesc)))
+	})
+	return file_gohttppb_gohttp_proto_rawDescData
+}
+
+var file_gohttppb_gohttp_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
+var file_gohttppb_gohttp_proto_goTypes = []any{
+	(*HashRequest)(nil),           // 0: gohttp.v1.HashRequest
+	(*HashResponse)(nil),          // 1: gohttp.v1.HashResponse
+	(*GetResultRequest)(nil),      // 2: gohttp.v1.GetResultRequest
+	(*CancelJobRequest)(nil),      // 3: gohttp.v1.CancelJobRequest
+	(*ListJobsRequest)(nil),       // 4: gohttp.v1.ListJobsRequest
+	(*JobList)(nil),               // 5: gohttp.v1.JobList
+	(*Job)(nil),                   // 6: gohttp.v1.Job
+	(*Callback)(nil),              // 7: gohttp.v1.Callback
+	(*CallbackAttempt)(nil),       // 8: gohttp.v1.CallbackAttempt
+	(*VerifyRequest)(nil),         // 9: gohttp.v1.VerifyRequest
+	(*VerifyResponse)(nil),        // 10: gohttp.v1.VerifyResponse
+	(*GetStatsRequest)(nil),       // 11: gohttp.v1.GetStatsRequest
+	(*Stats)(nil),                 // 12: gohttp.v1.Stats
+	(*EndpointStats)(nil),         // 13: gohttp.v1.EndpointStats
+	(*PoolStats)(nil),             // 14: gohttp.v1.PoolStats
+	(*LaneStats)(nil),             // 15: gohttp.v1.LaneStats
+	(*KeyStats)(nil),              // 16: gohttp.v1.KeyStats
+	(*CacheStats)(nil),            // 17: gohttp.v1.CacheStats
+	(*ShutdownRequest)(nil),       // 18: gohttp.v1.ShutdownRequest
+	(*ShutdownResponse)(nil),      // 19: gohttp.v1.ShutdownResponse
+	nil,                           // 20: gohttp.v1.Stats.ProtocolsEntry
+	nil,                           // 21: gohttp.v1.EndpointStats.ErrorsEntry
+	(*timestamppb.Timestamp)(nil), // 22: google.protobuf.Timestamp
+}
+var file_gohttppb_gohttp_proto_depIdxs = []int32{
+	6,  // 0: gohttp.v1.JobList.jobs:type_name -> gohttp.v1.Job
+	22, // 1: gohttp.v1.Job.created:type_name -> google.protobuf.Timestamp
+	22, // 2: gohttp.v1.Job.finished:type_name -> google.protobuf.Timestamp
+	7,  // 3: gohttp.v1.Job.callback:type_name -> gohttp.v1.Callback
+	22, // 4: gohttp.v1.Job.started:type_name -> google.protobuf.Timestamp
+	8,  // 5: gohttp.v1.Callback.attempts:type_name -> gohttp.v1.CallbackAttempt
+	22, // 6: gohttp.v1.CallbackAttempt.at:type_name -> google.protobuf.Timestamp
+	13, // 7: gohttp.v1.Stats.endpoints:type_name -> gohttp.v1.EndpointStats
+	14, // 8: gohttp.v1.Stats.pool:type_name -> gohttp.v1.PoolStats
+	20, // 9: gohttp.v1.Stats.protocols:type_name -> gohttp.v1.Stats.ProtocolsEntry
+	16, // 10: gohttp.v1.Stats.keys:type_name -> gohttp.v1.KeyStats
+	17, // 11: gohttp.v1.Stats.cache:type_name -> gohttp.v1.CacheStats
+	21, // 12: gohttp.v1.EndpointStats.errors:type_name -> gohttp.v1.EndpointStats.ErrorsEntry
+	15, // 13: gohttp.v1.PoolStats.lanes:type_name -> gohttp.v1.LaneStats
+	0,  // 14: gohttp.v1.HashService.Hash:input_type -> gohttp.v1.HashRequest
+	0,  // 15: gohttp.v1.HashService.HashAsync:input_type -> gohttp.v1.HashRequest
+	2,  // 16: gohttp.v1.HashService.GetResult:input_type -> gohttp.v1.GetResultRequest
+	3,  // 17: gohttp.v1.HashService.CancelJob:input_type -> gohttp.v1.CancelJobRequest
+	4,  // 18: gohttp.v1.HashService.ListJobs:input_type -> gohttp.v1.ListJobsRequest
+	9,  // 19: gohttp.v1.HashService.Verify:input_type -> gohttp.v1.VerifyRequest
+	11, // 20: gohttp.v1.HashService.GetStats:input_type -> gohttp.v1.GetStatsRequest
+	18, // 21: gohttp.v1.HashService.Shutdown:input_type -> gohttp.v1.ShutdownRequest
+	1,  // 22: gohttp.v1.HashService.Hash:output_type -> gohttp.v1.HashResponse
+	6,  // 23: gohttp.v1.HashService.HashAsync:output_type -> gohttp.v1.Job
+	6,  // 24: gohttp.v1.HashService.GetResult:output_type -> gohttp.v1.Job
+	6,  // 25: gohttp.v1.HashService.CancelJob:output_type -> gohttp.v1.Job
+	5,  // 26: gohttp.v1.HashService.ListJobs:output_type -> gohttp.v1.JobList
+	10, // 27: gohttp.v1.HashService.Verify:output_type -> gohttp.v1.VerifyResponse
+	12, // 28: gohttp.v1.HashService.GetStats:output_type -> gohttp.v1.Stats
+	19, // 29: gohttp.v1.HashService.Shutdown:output_type -> gohttp.v1.ShutdownResponse
+	22, // [22:30] is the sub-list for method output_type
+	14, // [14:22] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_gohttppb_gohttp_proto_init() }
+func file_gohttppb_gohttp_proto_init() {
+	if File_gohttppb_gohttp_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gohttppb_gohttp_proto_rawDesc), len(file_gohttppb_gohttp_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   22,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_gohttppb_gohttp_proto_goTypes,
+		DependencyIndexes: file_gohttppb_gohttp_proto_depIdxs,
+		MessageInfos:      file_gohttppb_gohttp_proto_msgTypes,
+	}.Build()
+	File_gohttppb_gohttp_proto = out.File
+	file_gohttppb_gohttp_proto_goTypes = nil
+	file_gohttppb_gohttp_proto_depIdxs = nil
+}
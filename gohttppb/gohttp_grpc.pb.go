@@ -0,0 +1,413 @@
+// gRPC version of the gohttp api. the same Service backs both, so hashes, jobs and stats are shared with the http endpoints.
+// regenerate gohttp.pb.go and gohttp_grpc.pb.go after editing:
+//   protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative gohttppb/gohttp.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: gohttppb/gohttp.proto
+
+package gohttppb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	HashService_Hash_FullMethodName      = "/gohttp.v1.HashService/Hash"
+	HashService_HashAsync_FullMethodName = "/gohttp.v1.HashService/HashAsync"
+	HashService_GetResult_FullMethodName = "/gohttp.v1.HashService/GetResult"
+	HashService_CancelJob_FullMethodName = "/gohttp.v1.HashService/CancelJob"
+	HashService_ListJobs_FullMethodName  = "/gohttp.v1.HashService/ListJobs"
+	HashService_Verify_FullMethodName    = "/gohttp.v1.HashService/Verify"
+	HashService_GetStats_FullMethodName  = "/gohttp.v1.HashService/GetStats"
+	HashService_Shutdown_FullMethodName  = "/gohttp.v1.HashService/Shutdown"
+)
+
+// HashServiceClient is the client API for HashService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// once the server has API keys, Hash, HashAsync, GetResult, CancelJob, ListJobs and Verify need one
+// in the authorization ("Bearer <key>") or x-api-key metadata
+type HashServiceClient interface {
+	// Hash hashes a password after the server's delay, like POST /hash
+	Hash(ctx context.Context, in *HashRequest, opts ...grpc.CallOption) (*HashResponse, error)
+	// HashAsync starts hashing in the background, like POST /hash with async=true
+	HashAsync(ctx context.Context, in *HashRequest, opts ...grpc.CallOption) (*Job, error)
+	// GetResult fetches an async job, like GET /hash/{id}
+	GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*Job, error)
+	// CancelJob stops a pending async job, like DELETE /hash/{id}
+	CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// ListJobs lists the caller's async jobs newest first, like GET /hash
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*JobList, error)
+	// Verify checks a password against a hash, like POST /verify
+	Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error)
+	// GetStats returns the same numbers as GET /stats
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error)
+	// Shutdown stops the server once hashing in progress has finished, like POST /shutdown
+	Shutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*ShutdownResponse, error)
+}
+
+type hashServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHashServiceClient(cc grpc.ClientConnInterface) HashServiceClient {
+	return &hashServiceClient{cc}
+}
+
+func (c *hashServiceClient) Hash(ctx context.Context, in *HashRequest, opts ...grpc.CallOption) (*HashResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HashResponse)
+	err := c.cc.Invoke(ctx, HashService_Hash_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hashServiceClient) HashAsync(ctx context.Context, in *HashRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, HashService_HashAsync_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hashServiceClient) GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, HashService_GetResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hashServiceClient) CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, HashService_CancelJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hashServiceClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*JobList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobList)
+	err := c.cc.Invoke(ctx, HashService_ListJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hashServiceClient) Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VerifyResponse)
+	err := c.cc.Invoke(ctx, HashService_Verify_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hashServiceClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Stats)
+	err := c.cc.Invoke(ctx, HashService_GetStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hashServiceClient) Shutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*ShutdownResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ShutdownResponse)
+	err := c.cc.Invoke(ctx, HashService_Shutdown_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HashServiceServer is the server API for HashService service.
+// All implementations must embed UnimplementedHashServiceServer
+// for forward compatibility.
+//
+// once the server has API keys, Hash, HashAsync, GetResult, CancelJob, ListJobs and Verify need one
+// in the authorization ("Bearer <key>") or x-api-key metadata
+type HashServiceServer interface {
+	// Hash hashes a password after the server's delay, like POST /hash
+	Hash(context.Context, *HashRequest) (*HashResponse, error)
+	// HashAsync starts hashing in the background, like POST /hash with async=true
+	HashAsync(context.Context, *HashRequest) (*Job, error)
+	// GetResult fetches an async job, like GET /hash/{id}
+	GetResult(context.Context, *GetResultRequest) (*Job, error)
+	// CancelJob stops a pending async job, like DELETE /hash/{id}
+	CancelJob(context.Context, *CancelJobRequest) (*Job, error)
+	// ListJobs lists the caller's async jobs newest first, like GET /hash
+	ListJobs(context.Context, *ListJobsRequest) (*JobList, error)
+	// Verify checks a password against a hash, like POST /verify
+	Verify(context.Context, *VerifyRequest) (*VerifyResponse, error)
+	// GetStats returns the same numbers as GET /stats
+	GetStats(context.Context, *GetStatsRequest) (*Stats, error)
+	// Shutdown stops the server once hashing in progress has finished, like POST /shutdown
+	Shutdown(context.Context, *ShutdownRequest) (*ShutdownResponse, error)
+	mustEmbedUnimplementedHashServiceServer()
+}
+
+// UnimplementedHashServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedHashServiceServer struct{}
+
+func (UnimplementedHashServiceServer) Hash(context.Context, *HashRequest) (*HashResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Hash not implemented")
+}
+func (UnimplementedHashServiceServer) HashAsync(context.Context, *HashRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HashAsync not implemented")
+}
+func (UnimplementedHashServiceServer) GetResult(context.Context, *GetResultRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetResult not implemented")
+}
+func (UnimplementedHashServiceServer) CancelJob(context.Context, *CancelJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelJob not implemented")
+}
+func (UnimplementedHashServiceServer) ListJobs(context.Context, *ListJobsRequest) (*JobList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListJobs not implemented")
+}
+func (UnimplementedHashServiceServer) Verify(context.Context, *VerifyRequest) (*VerifyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Verify not implemented")
+}
+func (UnimplementedHashServiceServer) GetStats(context.Context, *GetStatsRequest) (*Stats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedHashServiceServer) Shutdown(context.Context, *ShutdownRequest) (*ShutdownResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Shutdown not implemented")
+}
+func (UnimplementedHashServiceServer) mustEmbedUnimplementedHashServiceServer() {}
+func (UnimplementedHashServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeHashServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HashServiceServer will
+// result in compilation errors.
+type UnsafeHashServiceServer interface {
+	mustEmbedUnimplementedHashServiceServer()
+}
+
+func RegisterHashServiceServer(s grpc.ServiceRegistrar, srv HashServiceServer) {
+	// If the following call pancis, it indicates UnimplementedHashServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&HashService_ServiceDesc, srv)
+}
+
+func _HashService_Hash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HashServiceServer).Hash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HashService_Hash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HashServiceServer).Hash(ctx, req.(*HashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HashService_HashAsync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HashServiceServer).HashAsync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HashService_HashAsync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HashServiceServer).HashAsync(ctx, req.(*HashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HashService_GetResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HashServiceServer).GetResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HashService_GetResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HashServiceServer).GetResult(ctx, req.(*GetResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HashService_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HashServiceServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HashService_CancelJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HashServiceServer).CancelJob(ctx, req.(*CancelJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HashService_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HashServiceServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HashService_ListJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HashServiceServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HashService_Verify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HashServiceServer).Verify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HashService_Verify_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HashServiceServer).Verify(ctx, req.(*VerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HashService_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HashServiceServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HashService_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HashServiceServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HashService_Shutdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShutdownRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HashServiceServer).Shutdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HashService_Shutdown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HashServiceServer).Shutdown(ctx, req.(*ShutdownRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// HashService_ServiceDesc is the grpc.ServiceDesc for HashService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HashService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gohttp.v1.HashService",
+	HandlerType: (*HashServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Hash",
+			Handler:    _HashService_Hash_Handler,
+		},
+		{
+			MethodName: "HashAsync",
+			Handler:    _HashService_HashAsync_Handler,
+		},
+		{
+			MethodName: "GetResult",
+			Handler:    _HashService_GetResult_Handler,
+		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _HashService_CancelJob_Handler,
+		},
+		{
+			MethodName: "ListJobs",
+			Handler:    _HashService_ListJobs_Handler,
+		},
+		{
+			MethodName: "Verify",
+			Handler:    _HashService_Verify_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _HashService_GetStats_Handler,
+		},
+		{
+			MethodName: "Shutdown",
+			Handler:    _HashService_Shutdown_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "gohttppb/gohttp.proto",
+}
@@ -0,0 +1,394 @@
+package grpcserver
+
+import (
+    "context"
+    "crypto/subtle"
+    "fmt"
+    "net/http"
+    "strings"
+    "github.com/rdibari84/GoHTTP/audit"
+    "github.com/rdibari84/GoHTTP/gohttppb"
+    "github.com/rdibari84/GoHTTP/handlers"
+    "google.golang.org/genproto/googleapis/rpc/errdetails"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/health"
+    healthpb "google.golang.org/grpc/health/grpc_health_v1"
+    "google.golang.org/grpc/metadata"
+    "google.golang.org/grpc/peer"
+    "google.golang.org/grpc/reflection"
+    "google.golang.org/grpc/status"
+    "google.golang.org/protobuf/protoadapt"
+    "google.golang.org/protobuf/types/known/durationpb"
+    "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//////////////////////////////////////////////
+//////////////// gRPC Service ////////////////
+//////////////////////////////////////////////
+
+// name the health service reports the hash service under
+const serviceName = "gohttp.v1.HashService"
+
+// ErrorDomain is set on the ErrorInfo detail of every error, whose Reason is the handlers.ErrorCode
+const ErrorDomain = "gohttp"
+
+// Server answers the gohttp.v1.HashService rpcs from the same handlers.Service as the http endpoints
+type Server struct {
+  gohttppb.UnimplementedHashServiceServer
+  Service *handlers.Service
+  Stop func() // stops the servers once Shutdown has drained; Shutdown is refused when nil
+  AdminSplit bool // the server sits next to the public routes of an admin split, so GetStats and Shutdown are refused
+  health *health.Server
+}
+
+// NewServer returns a grpc.Server serving the hash service, the standard health service and server reflection.
+// every call is recorded in the Service's stats under method GRPC, and the hashing calls need an API key once
+// the Service has keys. stop is called by the Shutdown rpc. with adminSplit the stats and shutdown are left to the
+// admin listener: GetStats answers PermissionDenied and Shutdown Unimplemented
+func NewServer(service *handlers.Service, stop func(), adminSplit bool) *grpc.Server {
+  if adminSplit {
+    stop = nil
+  }
+  s := &Server{Service: service, Stop: stop, AdminSplit: adminSplit, health: health.NewServer()}
+  srv := grpc.NewServer(grpc.ChainUnaryInterceptor(s.recordStats, s.authorize))
+  gohttppb.RegisterHashServiceServer(srv, s)
+  healthpb.RegisterHealthServer(srv, s.health)
+  reflection.Register(srv)
+  s.health.SetServingStatus(serviceName, healthpb.HealthCheckResponse_SERVING)
+  return srv
+}
+
+func (s *Server) Hash(ctx context.Context, req *gohttppb.HashRequest) (*gohttppb.HashResponse, error) {
+  hash, apiErr := s.Service.Hash(ctx, req.GetPassword())
+  if apiErr != nil {
+    return nil, toStatus(ctx, apiErr)
+  }
+  return &gohttppb.HashResponse{Hash: hash}, nil
+}
+
+func (s *Server) HashAsync(ctx context.Context, req *gohttppb.HashRequest) (*gohttppb.Job, error) {
+  job, apiErr := s.Service.HashAsync(ctx, req.GetPassword(), req.GetCallbackUrl())
+  if apiErr != nil {
+    return nil, toStatus(ctx, apiErr)
+  }
+  return toProtoJob(job), nil
+}
+
+func (s *Server) GetResult(ctx context.Context, req *gohttppb.GetResultRequest) (*gohttppb.Job, error) {
+  job, apiErr := s.Service.Result(ctx, req.GetId())
+  if apiErr != nil {
+    return nil, toStatus(ctx, apiErr)
+  }
+  return toProtoJob(job), nil
+}
+
+func (s *Server) CancelJob(ctx context.Context, req *gohttppb.CancelJobRequest) (*gohttppb.Job, error) {
+  job, apiErr := s.Service.CancelJob(ctx, req.GetId())
+  if apiErr != nil {
+    return nil, toStatus(ctx, apiErr)
+  }
+  return toProtoJob(job), nil
+}
+
+func (s *Server) ListJobs(ctx context.Context, req *gohttppb.ListJobsRequest) (*gohttppb.JobList, error) {
+  list, apiErr := s.Service.ListJobs(ctx, req.GetStatus(), req.GetCursor(), int(req.GetLimit()))
+  if apiErr != nil {
+    return nil, toStatus(ctx, apiErr)
+  }
+  pb := &gohttppb.JobList{Next: list.Next}
+  for _, job := range list.Jobs {
+    pb.Jobs = append(pb.Jobs, toProtoJob(job))
+  }
+  return pb, nil
+}
+
+func (s *Server) Verify(ctx context.Context, req *gohttppb.VerifyRequest) (*gohttppb.VerifyResponse, error) {
+  match, apiErr := s.Service.Verify(ctx, req.GetPassword(), req.GetHash())
+  if apiErr != nil {
+    return nil, toStatus(ctx, apiErr)
+  }
+  return &gohttppb.VerifyResponse{Match: match}, nil
+}
+
+func (s *Server) GetStats(ctx context.Context, req *gohttppb.GetStatsRequest) (*gohttppb.Stats, error) {
+  if s.AdminSplit {
+    return nil, status.Error(codes.PermissionDenied, "stats are only served on the admin listener")
+  }
+  return toProtoStats(s.Service.Snapshot()), nil
+}
+
+// Shutdown waits until hashing is not in progress, then stops the servers after answering. it needs the admin
+// token, sent as authorization: Bearer <token> metadata, once the Service has one
+func (s *Server) Shutdown(ctx context.Context, req *gohttppb.ShutdownRequest) (*gohttppb.ShutdownResponse, error) {
+  if s.Stop == nil {
+    return nil, status.Error(codes.Unimplemented, "shutdown is not enabled on this server")
+  }
+  if s.Service.AdminToken != "" && !s.admin(ctx) {
+    s.audit(ctx, audit.EventAuthFailed, string(handlers.CodeAdminRequired) + " " + gohttppb.HashService_Shutdown_FullMethodName)
+    return nil, toStatus(ctx, handlers.NewError(handlers.CodeAdminRequired, "Send the admin token as authorization: Bearer <token> metadata"))
+  }
+  s.audit(ctx, audit.EventShutdown, "")
+  s.health.Shutdown() // health checks report NOT_SERVING from now on
+  // not on ctx: a caller that hangs up mustn't leave the server refusing work without ever stopping
+  s.Service.Drain(context.Background())
+  fmt.Printf("Received gRPC shutdown request... shutting down\n")
+  go s.Stop() // a graceful stop waits for this call, so it can't happen on this goroutine
+  return &gohttppb.ShutdownResponse{}, nil
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// records every call for the /stats endpoint, under the full method name with the http status it matches
+func (s *Server) recordStats(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+  start := s.Service.Clock.Now()
+  resp, err := handler(ctx, req)
+  s.Service.Stats.CountProtocol("HTTP/2.0") // gRPC always runs over HTTP/2
+  st := status.Convert(err)
+  reason := ""
+  if err != nil {
+    reason = errorReason(st)
+  }
+  s.Service.Stats.Record(info.FullMethod, "GRPC", httpStatus(st.Code(), reason), s.Service.Clock.Now().Sub(start), reason)
+  return resp, err
+}
+
+// the calls that need an API key, like the http endpoints RequireAPIKey guards
+var keyedMethods = map[string]bool{
+  gohttppb.HashService_Hash_FullMethodName: true,
+  gohttppb.HashService_HashAsync_FullMethodName: true,
+  gohttppb.HashService_GetResult_FullMethodName: true,
+  gohttppb.HashService_CancelJob_FullMethodName: true,
+  gohttppb.HashService_ListJobs_FullMethodName: true,
+  gohttppb.HashService_Verify_FullMethodName: true,
+}
+
+// refuses hashing calls without a valid API key within its quota once the Service has keys, and records each one against the key
+func (s *Server) authorize(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+  if s.Service.Keys == nil || !keyedMethods[info.FullMethod] {
+    return handler(ctx, req)
+  }
+  key, apiErr := s.Service.Authorize(apiKeyFrom(ctx))
+  if apiErr != nil {
+    if apiErr.Status == http.StatusUnauthorized {
+      s.audit(ctx, audit.EventAuthFailed, string(apiErr.Code) + " " + info.FullMethod)
+    }
+    return nil, toStatus(ctx, apiErr)
+  }
+  start := s.Service.Clock.Now()
+  resp, err := handler(handlers.WithAPIKey(ctx, key.ID), req)
+  s.Service.Keys.Record(key.ID, s.Service.Clock.Now().Sub(start))
+  return resp, err
+}
+
+// audits a call the way the http handlers audit requests: by admin with the admin token, anonymous otherwise
+func (s *Server) audit(ctx context.Context, event string, detail string) {
+  e := audit.Event{Event: event, Actor: "anonymous", Detail: detail}
+  if s.admin(ctx) {
+    e.Actor = "admin"
+  }
+  if p, ok := peer.FromContext(ctx); ok {
+    e.Remote = p.Addr.String()
+  }
+  s.Service.Audit(e)
+}
+
+// turns an APIError into a status carrying the error code in an ErrorInfo detail, and when to retry in a RetryInfo detail.
+// a caller that went away gets Canceled or DeadlineExceeded instead
+func toStatus(ctx context.Context, apiErr *handlers.APIError) error {
+  if ctx.Err() != nil {
+    return status.FromContextError(ctx.Err()).Err()
+  }
+  code := codes.Internal
+  switch {
+    case apiErr.Code == handlers.CodeQueueFull, apiErr.Code == handlers.CodeQuotaExceeded:
+      code = codes.ResourceExhausted
+    case apiErr.Code == handlers.CodeShuttingDown:
+      code = codes.Unavailable
+    case apiErr.Code == handlers.CodeJobFinished:
+      code = codes.FailedPrecondition
+    case apiErr.Status == http.StatusUnauthorized:
+      code = codes.Unauthenticated
+    case apiErr.Status == http.StatusNotFound:
+      code = codes.NotFound
+    case apiErr.Status >= 400 && apiErr.Status < 500:
+      code = codes.InvalidArgument
+  }
+  st := status.New(code, apiErr.Message)
+  details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: string(apiErr.Code), Domain: ErrorDomain}}
+  if apiErr.RetryAfter > 0 {
+    details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(apiErr.RetryAfter)})
+  }
+  detailed, err := st.WithDetails(details...)
+  if err != nil {
+    return st.Err()
+  }
+  return detailed.Err()
+}
+
+// the error code from the ErrorInfo detail, or the gRPC code for errors that didn't come from an APIError
+func errorReason(st *status.Status) string {
+  for _, detail := range st.Details() {
+    if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == ErrorDomain {
+      return info.Reason
+    }
+  }
+  return strings.ToLower(st.Code().String())
+}
+
+// the http status matching a gRPC code and error reason, so gRPC calls line up with the http ones in /stats
+func httpStatus(code codes.Code, reason string) int {
+  switch code {
+    case codes.OK:
+      return http.StatusOK
+    case codes.InvalidArgument:
+      return http.StatusBadRequest
+    case codes.Unauthenticated:
+      return http.StatusUnauthorized
+    case codes.ResourceExhausted:
+      if reason == string(handlers.CodeQuotaExceeded) {
+        return http.StatusTooManyRequests
+      }
+      return http.StatusServiceUnavailable
+    case codes.NotFound:
+      return http.StatusNotFound
+    case codes.FailedPrecondition:
+      return http.StatusConflict
+    case codes.Unavailable:
+      return http.StatusServiceUnavailable
+    case codes.Unimplemented:
+      return http.StatusNotImplemented
+    case codes.DeadlineExceeded:
+      return http.StatusGatewayTimeout
+    case codes.Canceled:
+      return 499 // client closed request
+  }
+  return http.StatusInternalServerError
+}
+
+// whether the call carries the Service's admin token. constant time so the comparison doesn't leak how much of it matched
+func (s *Server) admin(ctx context.Context) bool {
+  token := bearerFrom(ctx)
+  return s.Service.AdminToken != "" && token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.Service.AdminToken)) == 1
+}
+
+// the token from the authorization ("Bearer <token>") metadata
+func bearerFrom(ctx context.Context) string {
+  md, _ := metadata.FromIncomingContext(ctx)
+  for _, auth := range md.Get("authorization") {
+    if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+      return strings.TrimSpace(auth[7:])
+    }
+  }
+  return ""
+}
+
+// the API key from the authorization ("Bearer <key>") or x-api-key metadata
+func apiKeyFrom(ctx context.Context) string {
+  if key := bearerFrom(ctx); key != "" {
+    return key
+  }
+  md, _ := metadata.FromIncomingContext(ctx)
+  for _, key := range md.Get("x-api-key") {
+    return strings.TrimSpace(key)
+  }
+  return ""
+}
+
+func toProtoJob(job handlers.Job) *gohttppb.Job {
+  pb := &gohttppb.Job{
+    Id: job.ID,
+    Status: job.Status,
+    Hash: job.Hash,
+    Error: job.Error,
+    Algorithm: job.Algorithm,
+    Created: timestamppb.New(job.Created),
+    Duration: job.Duration,
+  }
+  if !job.Started.IsZero() {
+    pb.Started = timestamppb.New(job.Started)
+  }
+  if !job.Finished.IsZero() {
+    pb.Finished = timestamppb.New(job.Finished)
+  }
+  if job.Callback != nil {
+    pb.Callback = &gohttppb.Callback{Url: job.Callback.URL, Status: job.Callback.Status}
+    for _, a := range job.Callback.Attempts {
+      pb.Callback.Attempts = append(pb.Callback.Attempts, &gohttppb.CallbackAttempt{
+        At: timestamppb.New(a.At),
+        StatusCode: int32(a.StatusCode),
+        Error: a.Error,
+      })
+    }
+  }
+  return pb
+}
+
+func toProtoStats(stats handlers.Stats) *gohttppb.Stats {
+  pb := &gohttppb.Stats{Total: int64(stats.Total), Average: stats.Average}
+  if len(stats.Protocols) > 0 {
+    pb.Protocols = make(map[string]int64, len(stats.Protocols))
+    for proto, count := range stats.Protocols {
+      pb.Protocols[proto] = int64(count)
+    }
+  }
+  for _, e := range stats.Endpoints {
+    endpoint := &gohttppb.EndpointStats{
+      Route: e.Route,
+      Method: e.Method,
+      Status: int32(e.Status),
+      Count: int64(e.Count),
+      Average: e.Average,
+      Max: e.Max,
+    }
+    if len(e.Errors) > 0 {
+      endpoint.Errors = make(map[string]int64, len(e.Errors))
+      for reason, count := range e.Errors {
+        endpoint.Errors[reason] = int64(count)
+      }
+    }
+    pb.Endpoints = append(pb.Endpoints, endpoint)
+  }
+  if stats.Pool != nil {
+    pb.Pool = &gohttppb.PoolStats{
+      Workers: int32(stats.Pool.Workers),
+      Busy: int32(stats.Pool.Busy),
+      QueueCapacity: int32(stats.Pool.QueueCapacity),
+    }
+    for _, l := range stats.Pool.Lanes {
+      pb.Pool.Lanes = append(pb.Pool.Lanes, &gohttppb.LaneStats{
+        Lane: l.Lane,
+        Queued: int32(l.Queued),
+        Completed: int64(l.Completed),
+        Rejected: int64(l.Rejected),
+        Cancelled: int64(l.Cancelled),
+        WaitAverage: l.WaitAverage,
+        WaitMax: l.WaitMax,
+      })
+    }
+  }
+  for _, k := range stats.Keys {
+    pb.Keys = append(pb.Keys, &gohttppb.KeyStats{
+      Id: k.ID,
+      Name: k.Name,
+      Requests: int64(k.Requests),
+      Today: int64(k.Today),
+      DailyQuota: int64(k.DailyQuota),
+      Rejected: int64(k.Rejected),
+      Average: k.Average,
+      Max: k.Max,
+    })
+  }
+  if stats.Cache != nil {
+    pb.Cache = &gohttppb.CacheStats{
+      Entries: int64(stats.Cache.Entries),
+      Capacity: int64(stats.Cache.Capacity),
+      Hits: int64(stats.Cache.Hits),
+      Misses: int64(stats.Cache.Misses),
+      Coalesced: int64(stats.Cache.Coalesced),
+    }
+  }
+  return pb
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+    "fmt"
+    "net/http"
+    "github.com/rdibari84/GoHTTP/audit"
+)
+
+//////////////////////////////////////////////
+//////////////// Admin Routes ////////////////
+//////////////////////////////////////////////
+
+// which endpoints a route tree serves; see PublicRoutes and AdminRoutes
+type routeSet int
+
+const (
+  publicRoutes routeSet = 1 << iota // hashing and verification
+  adminRoutes // shutdown, stats and key management
+  allRoutes = publicRoutes | adminRoutes // both on one listener, the way the server always worked
+)
+
+// ConfigDump is the effective configuration served at /config on the admin listener. secrets are never included
+type ConfigDump struct {
+  MinPasswordLength int
+  MaxPasswordLength int // 0 for no limit
+  MaxBodyBytes int64 // 0 for no limit
+  Normalization string `json:",omitempty"` // NFC or NFKC
+  BreachedPasswords int // entries in the breached password list
+  HashWorkers int
+  HashQueue int // per lane
+  CacheSize int // hashes the cache keeps; 0 when it's off
+  CacheTTL string `json:",omitempty"`
+  Delay string // in the -delay flag's format, e.g. fixed:5s
+  StatsInterval string
+  Webhooks bool // whether callback_url is accepted
+  WebhookAttempts int `json:",omitempty"`
+  WebhookBackoff string `json:",omitempty"`
+  Deprecations map[string]Deprecation `json:",omitempty"`
+  APIKeys bool // whether the hashing endpoints need an API key
+  AdminToken bool // whether /admin/keys needs the admin token
+  Debug bool // whether /debug/pprof and /debug/vars are served
+  AuditLog bool // whether administrative and security events are audited
+}
+
+// ConfigDump describes the configuration the Service is running with
+func (s *Service) ConfigDump() ConfigDump {
+  pool := s.Pool.Stats()
+  dump := ConfigDump{
+    MinPasswordLength: s.Policy.MinLength,
+    MaxPasswordLength: s.Policy.MaxLength,
+    MaxBodyBytes: s.Policy.MaxBodyBytes,
+    Normalization: s.Policy.Normalization,
+    BreachedPasswords: len(s.Policy.Breached),
+    HashWorkers: pool.Workers,
+    HashQueue: pool.QueueCapacity,
+    Delay: formatDelayPolicy(s.Delay),
+    StatsInterval: s.StatsInterval.String(),
+    Webhooks: s.Webhooks != nil,
+    APIKeys: s.Keys != nil,
+    AdminToken: s.AdminToken != "",
+    Debug: s.Debug,
+    AuditLog: s.AuditLog != nil,
+  }
+  if s.Cache != nil {
+    dump.CacheSize = s.Cache.size
+    dump.CacheTTL = s.Cache.TTL().String()
+  }
+  if s.Webhooks != nil {
+    dump.WebhookAttempts = s.Webhooks.MaxAttempts
+    dump.WebhookBackoff = s.Webhooks.InitialBackoff.String()
+  }
+  if len(s.Deprecations) > 0 {
+    dump.Deprecations = s.Deprecations
+  }
+  return dump
+}
+
+var configMethods = []string{"GET", "HEAD", "OPTIONS"}
+
+// ConfigHandler serves the ConfigDump
+type ConfigHandler struct {
+  Service *Service // DefaultService() when nil
+}
+// needs a ServeHTTP method from HandlerFunc Interface
+func (h *ConfigHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  s := serviceOrDefault(h.Service)
+  switch r.Method {
+    case "GET", "HEAD":
+      writeJSON(w, r, http.StatusOK, s.ConfigDump())
+    case "OPTIONS":
+      writeOptions(w, configMethods)
+    default:
+      writeMethodNotAllowed(w, r, configMethods)
+  }
+}
+
+// Restarted answers POST /restart
+type Restarted struct {
+  PID int // the new process
+}
+
+var restartMethods = []string{"POST", "OPTIONS"}
+
+// RestartHandler runs Config.Restart. the new process is already serving by the time it answers
+type RestartHandler struct {
+  Service *Service // DefaultService() when nil
+}
+// needs a ServeHTTP method from HandlerFunc Interface
+func (h *RestartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  s := serviceOrDefault(h.Service)
+  switch r.Method {
+    case "POST":
+      if s.Restart == nil {
+        writeError(w, r, NewError(CodeNotFound, "Restarts are not set up on this server"))
+        return
+      }
+      s.auditRequest(r, audit.EventRestart, "") // before the new process takes over the audit log
+      pid, err := s.Restart()
+      if err != nil {
+        writeError(w, r, NewError(CodeRestartFailed, err.Error()))
+        return
+      }
+      writeJSON(w, r, http.StatusAccepted, Restarted{PID: pid})
+    case "OPTIONS":
+      writeOptions(w, restartMethods)
+    default:
+      writeMethodNotAllowed(w, r, restartMethods)
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// the delay policy in ParseDelayPolicy's format
+func formatDelayPolicy(d DelayPolicy) string {
+  switch d := d.(type) {
+    case NoDelay:
+      return "none"
+    case FixedDelay:
+      return "fixed:" + d.Delay.String()
+    case JitterDelay:
+      return fmt.Sprintf("jitter:%s-%s", d.Min, d.Max)
+    case ExponentialDelay:
+      return "exponential:" + d.Mean.String()
+  }
+  return fmt.Sprintf("%T", d) // a policy from outside this package
+}
@@ -0,0 +1,296 @@
+package handlers_test
+
+import (
+  "testing"
+  "encoding/json"
+  "errors"
+  "io/ioutil"
+  "net/http"
+  "net/http/httptest"
+  "net/url"
+  "path/filepath"
+  "strings"
+  "sync/atomic"
+  "github.com/rdibari84/GoHTTP/audit"
+  "github.com/rdibari84/GoHTTP/handlers"
+  "github.com/rdibari84/GoHTTP/handlers/handlerstest"
+  "github.com/rdibari84/GoHTTP/handlers/openapi/openapitest"
+)
+
+//////////////////////////////////////////////
+/////////////// Admin Unit Tests /////////////
+//////////////////////////////////////////////
+
+func TestPublicRoutesLeaveOutAdmin(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewSplitServer(t, handlers.Config{Keys: newKeyStore(t, ""), AdminToken: adminToken, Debug: true})
+
+  for _, path := range []string{"/stats", "/v2/stats", "/stats/stream", "/config", "/admin/keys", "/debug/pprof/", "/debug/vars"} {
+    resp, err := http.Get(srv.URL + path)
+    if err != nil {
+      t.Fatalf("Expected no error. Error: %s", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 404 {
+      t.Errorf("Expected %s to be left off the public routes. got %d", path, resp.StatusCode)
+    }
+  }
+
+  // hashing and the docs are still public
+  for _, path := range []string{"/openapi.json", "/errors"} {
+    resp, err := http.Get(srv.URL + path)
+    if err != nil {
+      t.Fatalf("Expected no error. Error: %s", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 200 {
+      t.Errorf("Expected %s on the public routes. got %d", path, resp.StatusCode)
+    }
+  }
+  resp, err := http.PostForm(srv.URL + "/hash", url.Values{"password": {"angryMonkey"}})
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != 401 {
+    t.Errorf("Expected /hash to still need an API key. got %d", resp.StatusCode)
+  }
+}
+
+func TestAdminRoutesResetStats(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewSplitServer(t, handlers.Config{})
+  postHash(t, srv.URL, "angryMonkey")
+
+  stats := getStats(t, srv.Admin.URL)
+  if stats.Total != 1 || !hasEndpoint(stats, "/hash") {
+    t.Fatalf("Expected the public hash in the admin stats. got %+v", stats)
+  }
+
+  req, _ := http.NewRequest("DELETE", srv.Admin.URL + "/stats", nil)
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != 204 {
+    t.Fatalf("Expected the stats reset. got %d", resp.StatusCode)
+  }
+  stats = getStats(t, srv.Admin.URL)
+  if stats.Total != 0 || hasEndpoint(stats, "/hash") {
+    t.Errorf("Expected the stats to start over. got %+v", stats)
+  }
+
+  // the combined routes don't reset
+  combined := handlerstest.NewServer(t, handlers.Config{})
+  req, _ = http.NewRequest("DELETE", combined.URL + "/stats", nil)
+  resp, err = http.DefaultClient.Do(req)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != 405 {
+    t.Errorf("Expected DELETE /stats refused without an admin listener. got %d", resp.StatusCode)
+  }
+}
+
+func TestAdminConfigLeavesOutSecrets(t *testing.T) {
+  t.Parallel()
+  delay, _ := handlers.ParseDelayPolicy("jitter:1s-5s")
+  srv := handlerstest.NewSplitServer(t, handlers.Config{
+    Delay: delay,
+    Webhooks: &handlers.WebhookConfig{Secret: []byte("hush-hush")},
+    AdminToken: adminToken,
+  })
+
+  resp, err := http.Get(srv.Admin.URL + "/config")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  dump := handlers.ConfigDump{}
+  json.Unmarshal(body, &dump)
+  if dump.Delay != "jitter:1s-5s" || dump.HashWorkers != 2 || !dump.Webhooks || dump.WebhookAttempts != 5 || !dump.AdminToken {
+    t.Errorf("Expected the running config. got %+v", dump)
+  }
+  if strings.Contains(string(body), "hush-hush") || strings.Contains(string(body), adminToken) {
+    t.Errorf("Expected no secrets in the config. got %s", body)
+  }
+}
+
+func TestAdminKeysOpenOnAdminListener(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewSplitServer(t, handlers.Config{Keys: newKeyStore(t, "")})
+
+  resp, err := http.PostForm(srv.Admin.URL + "/admin/keys", url.Values{"name": {"alice"}})
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != 201 {
+    t.Errorf("Expected a key without an admin token on the admin listener. got %d", resp.StatusCode)
+  }
+}
+
+func TestAdminServesPprof(t *testing.T) {
+  t.Parallel()
+  service := handlers.NewService(handlers.Config{Delay: handlers.NoDelay{}, Debug: true})
+  ts := httptest.NewServer(openapitest.Check(t, service.AdminRoutes(nil)))
+  defer ts.Close()
+
+  resp, err := http.Get(ts.URL + "/debug/pprof/goroutine?debug=1")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  if resp.StatusCode != 200 || !strings.Contains(string(body), "goroutine profile") {
+    t.Errorf("Expected a goroutine profile. got %d", resp.StatusCode)
+  }
+}
+
+func TestDebugRoutesAreOptional(t *testing.T) {
+  t.Parallel()
+  split := handlerstest.NewSplitServer(t, handlers.Config{})
+  together := handlerstest.NewServer(t, handlers.Config{Debug: true}) // no admin token to guard them with
+
+  for _, url := range []string{split.Admin.URL + "/debug/pprof/", split.Admin.URL + "/debug/vars", together.URL + "/debug/vars"} {
+    resp, err := http.Get(url)
+    if err != nil {
+      t.Fatalf("Expected no error. Error: %s", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 404 {
+      t.Errorf("Expected %s to be off. got %d", url, resp.StatusCode)
+    }
+  }
+}
+
+func TestDebugVarsNeedTheAdminToken(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{AdminToken: adminToken, Debug: true})
+  postHash(t, srv.URL, "angryMonkey")
+
+  for _, path := range []string{"/debug/vars", "/debug/pprof/"} {
+    resp, err := http.Get(srv.URL + path)
+    if err != nil {
+      t.Fatalf("Expected no error. Error: %s", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 401 {
+      t.Errorf("Expected 401 for %s without the admin token. got %d", path, resp.StatusCode)
+    }
+  }
+
+  resp := adminRequest(t, srv, "GET", "/debug/vars", nil)
+  vars := struct {
+    Memstats json.RawMessage
+    Gohttp handlers.DebugVars
+  }{}
+  json.NewDecoder(resp.Body).Decode(&vars)
+  resp.Body.Close()
+  if resp.StatusCode != 200 || len(vars.Memstats) == 0 {
+    t.Fatalf("Expected expvar's usual vars. got %d", resp.StatusCode)
+  }
+  if vars.Gohttp.Stats.Total != 1 || vars.Gohttp.Goroutines == 0 || vars.Gohttp.HashesInProgress != 0 {
+    t.Errorf("Expected the service's counters. got %+v", vars.Gohttp)
+  }
+  if vars.Gohttp.GC.HeapAlloc == 0 {
+    t.Errorf("Expected GC stats. got %+v", vars.Gohttp.GC)
+  }
+}
+
+func TestAdminEventsAreAudited(t *testing.T) {
+  t.Parallel()
+  path := filepath.Join(t.TempDir(), "audit.log")
+  auditLog, err := audit.Open(path, 0, 0)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  defer auditLog.Close()
+  srv := handlerstest.NewSplitServer(t, handlers.Config{Keys: newKeyStore(t, ""), AdminToken: adminToken, AuditLog: auditLog})
+
+  admin := &handlerstest.Server{Server: srv.Admin, Service: srv.Service}
+  key := createKey(t, admin, url.Values{"name": {"alice"}})
+  adminRequest(t, admin, "DELETE", "/admin/keys/" + key.ID, nil).Body.Close()
+  resp := postWithHeader(t, srv.URL + "/hash", "X-API-Key", "nope")
+  resp.Body.Close()
+  req, _ := http.NewRequest("DELETE", srv.Admin.URL + "/stats", nil)
+  resp, err = http.DefaultClient.Do(req)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  req, _ = http.NewRequest("GET", srv.Admin.URL + "/admin/keys", nil)
+  req.Header.Set("Authorization", "Bearer wrong")
+  resp, err = http.DefaultClient.Do(req)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+
+  if _, err := audit.Verify(path); err != nil {
+    t.Errorf("Expected an intact chain. err %v", err)
+  }
+  data, _ := ioutil.ReadFile(path)
+  var got []string
+  for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+    e := audit.Event{}
+    json.Unmarshal([]byte(line), &e)
+    got = append(got, e.Event + " " + e.Actor)
+    if e.Remote == "" || !e.Time.Equal(srv.Clock.Now()) {
+      t.Errorf("Expected the caller's address and the clock's time. got %+v", e)
+    }
+  }
+  expected := []string{"key_created admin", "key_revoked admin", "auth_failed anonymous", "stats_reset anonymous", "auth_failed anonymous"}
+  if strings.Join(got, ",") != strings.Join(expected, ",") {
+    t.Errorf("Expected %v. got %v", expected, got)
+  }
+  if strings.Contains(string(data), key.Key) {
+    t.Errorf("Expected the new key itself to be left out")
+  }
+}
+
+func TestAdminRestart(t *testing.T) {
+  t.Parallel()
+  restarts := atomic.Int32{}
+  restart := func() (int, error) {
+    if restarts.Add(1) > 1 {
+      return 0, errors.New("a restart is already under way")
+    }
+    return 4242, nil
+  }
+  srv := handlerstest.NewSplitServer(t, handlers.Config{Restart: restart})
+
+  resp, err := http.Post(srv.URL + "/restart", "", nil)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != 404 || restarts.Load() != 0 {
+    t.Errorf("Expected no restarts from the public listener. got %d", resp.StatusCode)
+  }
+
+  resp, err = http.Post(srv.Admin.URL + "/v2/restart", "", nil)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  envelope := handlers.Envelope{Data: &handlers.Restarted{}}
+  json.NewDecoder(resp.Body).Decode(&envelope)
+  resp.Body.Close()
+  if resp.StatusCode != 202 || envelope.Data.(*handlers.Restarted).PID != 4242 {
+    t.Errorf("Expected the new process id. got %d %+v", resp.StatusCode, envelope.Data)
+  }
+
+  resp, err = http.Post(srv.Admin.URL + "/restart", "", nil)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  message := handlers.ErrorMessage{}
+  json.NewDecoder(resp.Body).Decode(&message)
+  resp.Body.Close()
+  if resp.StatusCode != 500 || message.Code != handlers.CodeRestartFailed {
+    t.Errorf("Expected a failed restart to say so. got %d %+v", resp.StatusCode, message)
+  }
+}
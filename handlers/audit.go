@@ -0,0 +1,52 @@
+package handlers
+
+import (
+    "crypto/subtle"
+    "fmt"
+    "net/http"
+    "github.com/rdibari84/GoHTTP/audit"
+)
+
+//////////////////////////////////////////////
+////////////////// Auditing //////////////////
+//////////////////////////////////////////////
+
+// Audit appends e to the AuditLog, timestamped by the Clock. does nothing without an AuditLog.
+// a failed write is printed rather than failing the request it records
+func (s *Service) Audit(e audit.Event) {
+  if s.AuditLog == nil {
+    return
+  }
+  if e.Time.IsZero() {
+    e.Time = s.Clock.Now()
+  }
+  if err := s.AuditLog.Record(e); err != nil {
+    fmt.Printf("Could not write the audit log: %v\n", err)
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// audits what r did, by whoever sent it
+func (s *Service) auditRequest(r *http.Request, event string, detail string) {
+  s.Audit(audit.Event{
+    Event: event,
+    Actor: s.actor(r),
+    Remote: r.RemoteAddr,
+    RequestID: RequestIDFrom(r.Context()),
+    Detail: detail,
+  })
+}
+
+// who sent r: admin with the admin token, key:<id> with an API key, anonymous otherwise
+func (s *Service) actor(r *http.Request) string {
+  if token := bearerToken(r); s.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) == 1 {
+    return "admin"
+  }
+  if id := APIKeyFrom(r.Context()); id != "" {
+    return "key:" + id
+  }
+  return "anonymous"
+}
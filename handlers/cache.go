@@ -0,0 +1,241 @@
+package handlers
+
+import (
+    "container/list"
+    "context"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "sync"
+    "time"
+)
+
+//////////////////////////////////////////////
+//////////////// Hash Caching ////////////////
+//////////////////////////////////////////////
+
+// the algorithm every hash uses. it is deterministic, so identical passwords can share one hash.
+// a salted KDF would give every caller its own salt, so it has to call delayedHash directly instead of cachedHash
+const algorithmSHA512 = "sha512"
+
+// how long cached hashes are kept unless NewHashCache is told otherwise
+var DefaultCacheTTL = 10 * time.Minute
+
+// HashCache remembers recent hashes by a keyed digest of the algorithm and password, so the passwords themselves are
+// never kept. entries expire after the TTL, and the least recently used are dropped once the cache is full
+type HashCache struct {
+  size int
+  ttl time.Duration
+
+  mu sync.Mutex
+  entries map[hashDigest]*list.Element
+  order *list.List // of *cacheEntry, most recently used first
+  hits int
+  misses int
+}
+
+// cache section of the /stats message
+type CacheStats struct {
+  Entries int
+  Capacity int // 0 when the cache is off
+  Hits int
+  Misses int
+  Coalesced int // requests that waited on an identical hash already in progress instead of starting their own
+}
+
+// HMAC-SHA256 of the algorithm and password under the Service's digest key
+type hashDigest [sha256.Size]byte
+
+type cacheEntry struct {
+  digest hashDigest
+  hash string
+  expires time.Time
+}
+
+// NewHashCache keeps up to size hashes for ttl each; DefaultCacheTTL when ttl isn't positive. nil when size < 1
+func NewHashCache(size int, ttl time.Duration) *HashCache {
+  if size < 1 {
+    return nil
+  }
+  if ttl <= 0 {
+    ttl = DefaultCacheTTL
+  }
+  return &HashCache{size: size, ttl: ttl, entries: make(map[hashDigest]*list.Element), order: list.New()}
+}
+
+// TTL is how long the cache keeps a hash
+func (c *HashCache) TTL() time.Duration {
+  return c.ttl
+}
+
+// Stats returns the cache's size and hit counters. Coalesced is left for the Service to fill in
+func (c *HashCache) Stats() CacheStats {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  return CacheStats{Entries: c.order.Len(), Capacity: c.size, Hits: c.hits, Misses: c.misses}
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// the cached hash for digest, unless it's missing or expired by now
+func (c *HashCache) get(digest hashDigest, now time.Time) (string, bool) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  element, ok := c.entries[digest]
+  if ok && now.Before(element.Value.(*cacheEntry).expires) {
+    c.order.MoveToFront(element)
+    c.hits++
+    return element.Value.(*cacheEntry).hash, true
+  }
+  if ok {
+    c.order.Remove(element)
+    delete(c.entries, digest)
+  }
+  c.misses++
+  return "", false
+}
+
+// keeps hash until now plus the TTL, dropping the least recently used entry if the cache is full
+func (c *HashCache) add(digest hashDigest, hash string, now time.Time) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  if element, ok := c.entries[digest]; ok {
+    entry := element.Value.(*cacheEntry)
+    entry.hash, entry.expires = hash, now.Add(c.ttl)
+    c.order.MoveToFront(element)
+    return
+  }
+  if c.order.Len() >= c.size {
+    oldest := c.order.Back()
+    c.order.Remove(oldest)
+    delete(c.entries, oldest.Value.(*cacheEntry).digest)
+  }
+  c.entries[digest] = c.order.PushFront(&cacheEntry{digest: digest, hash: hash, expires: now.Add(c.ttl)})
+}
+
+// one hash in progress that identical requests wait on together
+type flight struct {
+  done chan struct{} // closed once hash and err are set
+  hash string
+  err error
+  waiters int
+  cancel context.CancelFunc // stops the hash once every waiter has gone away
+  started bool // the delay is over and hashing has begun
+  onStart []func() // run once it's started
+}
+
+// in flight hashes by lane and digest, so a sync request never waits behind batch work
+type flightKey struct {
+  lane Lane
+  digest hashDigest
+}
+
+// a random key for the digests, so they can't be looked up in a table of known password hashes
+func newDigestKey() []byte {
+  key := make([]byte, 32)
+  if _, err := rand.Read(key); err != nil {
+    panic(err) // crypto/rand doesn't fail on the platforms we run on
+  }
+  return key
+}
+
+func (s *Service) digest(algorithm string, password string) hashDigest {
+  mac := hmac.New(sha256.New, s.digestKey)
+  mac.Write([]byte(algorithm))
+  mac.Write([]byte{0})
+  mac.Write([]byte(password))
+  var digest hashDigest
+  copy(digest[:], mac.Sum(nil))
+  return digest
+}
+
+// delayedHash for deterministic algorithms: answers from the cache when it can, and otherwise waits on an identical
+// hash already in progress in lane before starting a new one. started, when set, is called once hashing begins.
+// stops early if ctx is done
+func (s *Service) cachedHash(ctx context.Context, lane Lane, password string, started func()) (string, error) {
+  if started == nil {
+    started = func() {}
+  }
+  digest := s.digest(algorithmSHA512, password)
+  if s.Cache != nil {
+    if hash, ok := s.Cache.get(digest, s.Clock.Now()); ok {
+      started()
+      return hash, nil
+    }
+  }
+  key := flightKey{lane: lane, digest: digest}
+  s.flightMu.Lock()
+  f, ok := s.flights[key]
+  if ok {
+    s.coalesced++
+  } else {
+    // the hash outlives the caller that started it as long as someone is still waiting
+    flightCtx, cancel := context.WithCancel(context.Background())
+    f = &flight{done: make(chan struct{}), cancel: cancel}
+    s.flights[key] = f
+    go s.fly(flightCtx, key, password, f)
+  }
+  f.waiters++
+  alreadyStarted := f.started
+  if !alreadyStarted {
+    f.onStart = append(f.onStart, started)
+  }
+  s.flightMu.Unlock()
+  if alreadyStarted {
+    started()
+  }
+
+  select {
+    case <-f.done:
+      return f.hash, f.err
+    case <-ctx.Done():
+      s.flightMu.Lock()
+      f.waiters--
+      if f.waiters == 0 {
+        f.cancel()
+        if s.flights[key] == f {
+          delete(s.flights, key) // a later request starts over rather than joining a cancelled hash
+        }
+      }
+      s.flightMu.Unlock()
+      return "", ctx.Err()
+  }
+}
+
+// runs the hash for a flight and hands the result to everyone waiting on it
+func (s *Service) fly(ctx context.Context, key flightKey, password string, f *flight) {
+  f.hash, f.err = s.delayedHash(ctx, key.lane, password, func() {
+    s.flightMu.Lock()
+    f.started = true
+    onStart := f.onStart
+    f.onStart = nil
+    s.flightMu.Unlock()
+    for _, started := range onStart {
+      started()
+    }
+  })
+  if f.err == nil && s.Cache != nil {
+    s.Cache.add(key.digest, f.hash, s.Clock.Now())
+  }
+  s.flightMu.Lock()
+  if s.flights[key] == f {
+    delete(s.flights, key)
+  }
+  s.flightMu.Unlock()
+  f.cancel()
+  close(f.done)
+}
+
+// the cache section of /stats
+func (s *Service) cacheStats() *CacheStats {
+  stats := CacheStats{}
+  if s.Cache != nil {
+    stats = s.Cache.Stats()
+  }
+  s.flightMu.Lock()
+  defer s.flightMu.Unlock()
+  stats.Coalesced = s.coalesced
+  return &stats
+}
@@ -0,0 +1,118 @@
+package handlers_test
+
+import (
+  "testing"
+  "context"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers"
+  "github.com/rdibari84/GoHTTP/handlers/handlerstest"
+)
+
+//////////////////////////////////////////////
+////////////// Cache Unit Tests //////////////
+//////////////////////////////////////////////
+
+func TestIdenticalHashesShareOneFlight(t *testing.T) {
+  t.Parallel()
+  clock := handlerstest.NewFakeClock()
+  s := newCachingService(t, clock, handlers.FixedDelay{Delay: time.Second}, nil)
+
+  // the first caller going away doesn't stop the hash the others are waiting on
+  ctx, cancel := context.WithCancel(context.Background())
+  results := make(chan string, 3)
+  go func() {
+    _, apiErr := s.Hash(ctx, "angryMonkey")
+    if apiErr == nil {
+      t.Errorf("Expected the cancelled caller to give up")
+    }
+  }()
+  clock.WaitForSleepers(1)
+  for i := 0; i < 3; i++ {
+    go func() {
+      hash, _ := s.Hash(context.Background(), "angryMonkey")
+      results <- hash
+    }()
+  }
+  waitFor(t, func() bool { return s.Snapshot().Cache.Coalesced == 3 })
+  cancel()
+  clock.Advance(time.Second)
+
+  for i := 0; i < 3; i++ {
+    if hash := <-results; hash != angryMonkeyHash {
+      t.Errorf("Expected every caller to get the hash. got %q", hash)
+    }
+  }
+  if completed := s.Pool.Stats().Lanes[0].Completed; completed != 1 {
+    t.Errorf("Expected one hash for four identical requests. got %d", completed)
+  }
+}
+
+func TestHashCacheAnswersRepeats(t *testing.T) {
+  t.Parallel()
+  clock := handlerstest.NewFakeClock()
+  s := newCachingService(t, clock, handlers.NoDelay{}, handlers.NewHashCache(2, time.Minute))
+
+  for _, password := range []string{"angryMonkey", "angryMonkey", "happyMonkey"} {
+    if _, apiErr := s.Hash(context.Background(), password); apiErr != nil {
+      t.Fatalf("Expected no error. Error: %+v", apiErr)
+    }
+  }
+  cache := s.Snapshot().Cache
+  if cache.Hits != 1 || cache.Misses != 2 || cache.Entries != 2 || cache.Capacity != 2 {
+    t.Errorf("Expected one hit and two misses. got %+v", cache)
+  }
+  if completed := s.Pool.Stats().Lanes[0].Completed; completed != 2 {
+    t.Errorf("Expected the repeat to skip the pool. got %d hashes", completed)
+  }
+
+  // the least recently used goes first once the cache is full
+  s.Hash(context.Background(), "angryMonkey")
+  s.Hash(context.Background(), "sillyMonkey")
+  s.Hash(context.Background(), "happyMonkey")
+  if cache := s.Snapshot().Cache; cache.Hits != 2 || cache.Misses != 4 {
+    t.Errorf("Expected happyMonkey to have been evicted. got %+v", cache)
+  }
+
+  // and nothing outlives the ttl
+  clock.Advance(time.Minute)
+  s.Hash(context.Background(), "happyMonkey")
+  if cache := s.Snapshot().Cache; cache.Hits != 2 || cache.Misses != 5 {
+    t.Errorf("Expected an expired entry to miss. got %+v", cache)
+  }
+}
+
+func TestHashCacheOffByDefault(t *testing.T) {
+  if handlers.NewHashCache(0, time.Minute) != nil {
+    t.Errorf("Expected no cache without a size")
+  }
+  s := newCachingService(t, handlerstest.NewFakeClock(), handlers.NoDelay{}, nil)
+  s.Hash(context.Background(), "angryMonkey")
+  s.Hash(context.Background(), "angryMonkey")
+  if cache := s.Snapshot().Cache; cache.Hits != 0 || cache.Capacity != 0 {
+    t.Errorf("Expected no cache hits. got %+v", cache)
+  }
+  if completed := s.Pool.Stats().Lanes[0].Completed; completed != 2 {
+    t.Errorf("Expected every request hashed. got %d", completed)
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// a Service with a pool of its own, so the pool's counters only see this test's hashes
+func newCachingService(t *testing.T, clock handlers.Clock, delay handlers.DelayPolicy, cache *handlers.HashCache) *handlers.Service {
+  pool := handlers.NewHashPool(1, 4)
+  t.Cleanup(pool.Close)
+  return handlers.NewService(handlers.Config{Pool: pool, Delay: delay, Clock: clock, Cache: cache})
+}
+
+func waitFor(t *testing.T, done func() bool) {
+  for i := 0; i < 100; i++ {
+    if done() {
+      return
+    }
+    time.Sleep(10 * time.Millisecond)
+  }
+  t.Fatalf("Gave up waiting")
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+//////////////////////////////////////////////
+/////////////////// CORS /////////////////////
+//////////////////////////////////////////////
+
+// CORSConfig controls which browser origins may call the API. An empty AllowedOrigins disables CORS
+type CORSConfig struct {
+  AllowedOrigins []string // exact origins such as https://tools.example.com, or "*" for any origin
+  AllowedHeaders []string // request headers allowed on preflight; defaults to whatever the browser asks for
+  ExposedHeaders []string // response headers scripts may read
+  AllowCredentials bool // allow cookies/authorization, for the exact origins only; refused by Check along with "*"
+  MaxAge time.Duration // how long browsers may cache a preflight response
+}
+
+// Check refuses a config that would let any site make credentialed calls
+func (cfg CORSConfig) Check() error {
+  if cfg.AllowCredentials && cfg.allowsAny() {
+    return errors.New("CORS credentials can't be allowed for any origin (*); list the origins instead")
+  }
+  return nil
+}
+
+// ParseOrigins splits a comma separated list of origins, e.g. from a command line flag
+func ParseOrigins(list string) []string {
+  var origins []string
+  for _, origin := range strings.Split(list, ",") {
+    origin = strings.TrimSpace(origin)
+    if origin != "" {
+      origins = append(origins, origin)
+    }
+  }
+  return origins
+}
+
+// CORS wraps a handler and adds the Access-Control-* headers for allowed origins.
+// Preflight requests are passed to the handler so its OPTIONS response decides the allowed methods
+func CORS(cfg CORSConfig, next http.Handler) http.Handler {
+  if len(cfg.AllowedOrigins) == 0 {
+    return next
+  }
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Add("Vary", "Origin")
+    origin := r.Header.Get("Origin")
+    if origin == "" || !cfg.allows(origin) {
+      next.ServeHTTP(w, r)
+      return
+    }
+
+    // an origin only allowed by the wildcard gets a literal *, which browsers never send credentials to
+    if cfg.lists(origin) && (cfg.AllowCredentials || !cfg.allowsAny()) {
+      w.Header().Set("Access-Control-Allow-Origin", origin)
+      if cfg.AllowCredentials {
+        w.Header().Set("Access-Control-Allow-Credentials", "true")
+      }
+    } else {
+      w.Header().Set("Access-Control-Allow-Origin", "*")
+    }
+
+    requestMethod := r.Header.Get("Access-Control-Request-Method")
+    if r.Method != "OPTIONS" || requestMethod == "" { // simple or actual request
+      if len(cfg.ExposedHeaders) > 0 {
+        w.Header().Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposedHeaders, ", "))
+      }
+      next.ServeHTTP(w, r)
+      return
+    }
+
+    // preflight request
+    w.Header().Add("Vary", "Access-Control-Request-Method")
+    w.Header().Add("Vary", "Access-Control-Request-Headers")
+    if len(cfg.AllowedHeaders) > 0 {
+      w.Header().Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+    } else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+      w.Header().Set("Access-Control-Allow-Headers", requested)
+    }
+    if cfg.MaxAge > 0 {
+      w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge / time.Second)))
+    }
+    next.ServeHTTP(&preflightWriter{ResponseWriter: w}, r)
+  })
+}
+
+func (cfg CORSConfig) allows(origin string) bool {
+  for _, allowed := range cfg.AllowedOrigins {
+    if allowed == "*" || allowed == origin {
+      return true
+    }
+  }
+  return false
+}
+
+// whether origin is listed by name rather than only matched by "*"
+func (cfg CORSConfig) lists(origin string) bool {
+  for _, allowed := range cfg.AllowedOrigins {
+    if allowed == origin {
+      return true
+    }
+  }
+  return false
+}
+
+func (cfg CORSConfig) allowsAny() bool {
+  for _, allowed := range cfg.AllowedOrigins {
+    if allowed == "*" {
+      return true
+    }
+  }
+  return false
+}
+
+// preflightWriter copies the handler's Allow header into Access-Control-Allow-Methods
+type preflightWriter struct {
+  http.ResponseWriter
+  wroteHeader bool
+}
+
+func (pw *preflightWriter) WriteHeader(code int) {
+  if !pw.wroteHeader {
+    pw.wroteHeader = true
+    if allow := pw.Header().Get("Allow"); allow != "" {
+      pw.Header().Set("Access-Control-Allow-Methods", allow)
+    }
+  }
+  pw.ResponseWriter.WriteHeader(code)
+}
+
+func (pw *preflightWriter) Write(b []byte) (int, error) {
+  if !pw.wroteHeader {
+    pw.WriteHeader(http.StatusOK)
+  }
+  return pw.ResponseWriter.Write(b)
+}
+
+func (pw *preflightWriter) Unwrap() http.ResponseWriter {
+  return pw.ResponseWriter
+}
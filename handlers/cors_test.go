@@ -0,0 +1,129 @@
+package handlers
+
+import (
+  "testing"
+  "net/http"
+  "net/http/httptest"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers/openapi/openapitest"
+)
+
+//////////////////////////////////////////////
+/////////////// CORS Unit Tests //////////////
+//////////////////////////////////////////////
+
+func TestCORSDisabledByDefault(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, CORS(CORSConfig{}, &StatsHandler{})))
+  defer ts.Close()
+
+  resp := doCORSRequest(t, "GET", ts.URL + "/stats", "https://tools.example.com", "")
+  if resp.Header.Get("Access-Control-Allow-Origin") != "" {
+    t.Errorf("Expected no Access-Control-Allow-Origin header. Got %s", resp.Header.Get("Access-Control-Allow-Origin"))
+  }
+}
+
+func TestCORSAllowsConfiguredOrigin(t *testing.T) {
+  cfg := CORSConfig{AllowedOrigins: []string{"https://tools.example.com"}}
+  ts := httptest.NewServer(openapitest.Check(t, CORS(cfg, &StatsHandler{})))
+  defer ts.Close()
+
+  resp := doCORSRequest(t, "GET", ts.URL + "/stats", "https://tools.example.com", "")
+  if resp.StatusCode != 200 {
+    t.Errorf("Expected 200 code. Got %d", resp.StatusCode)
+  }
+  if resp.Header.Get("Access-Control-Allow-Origin") != "https://tools.example.com" {
+    t.Errorf("Expected origin to be echoed back. Got %s", resp.Header.Get("Access-Control-Allow-Origin"))
+  }
+
+  resp = doCORSRequest(t, "GET", ts.URL + "/stats", "https://evil.example.com", "")
+  if resp.Header.Get("Access-Control-Allow-Origin") != "" {
+    t.Errorf("Expected unknown origin to be refused. Got %s", resp.Header.Get("Access-Control-Allow-Origin"))
+  }
+}
+
+func TestCORSPreflightUsesHandlerMethods(t *testing.T) {
+  cfg := CORSConfig{AllowedOrigins: []string{"*"}, MaxAge: time.Minute}
+  ts := httptest.NewServer(openapitest.Check(t, CORS(cfg, &HashHandler{})))
+  defer ts.Close()
+
+  resp := doCORSRequest(t, "OPTIONS", ts.URL + "/hash", "https://tools.example.com", "POST")
+  if resp.StatusCode != 204 {
+    t.Errorf("Expected 204 code. Got %d", resp.StatusCode)
+  }
+  if resp.Header.Get("Access-Control-Allow-Origin") != "*" {
+    t.Errorf("Expected wildcard origin. Got %s", resp.Header.Get("Access-Control-Allow-Origin"))
+  }
+  if resp.Header.Get("Access-Control-Allow-Methods") != "GET, HEAD, POST, OPTIONS" {
+    t.Errorf("Expected Access-Control-Allow-Methods: GET, HEAD, POST, OPTIONS. Got %s", resp.Header.Get("Access-Control-Allow-Methods"))
+  }
+  if resp.Header.Get("Access-Control-Allow-Headers") != "Content-Type" {
+    t.Errorf("Expected requested headers to be allowed. Got %s", resp.Header.Get("Access-Control-Allow-Headers"))
+  }
+  if resp.Header.Get("Access-Control-Max-Age") != "60" {
+    t.Errorf("Expected max age of 60. Got %s", resp.Header.Get("Access-Control-Max-Age"))
+  }
+}
+
+func TestCORSCredentialsEchoOrigin(t *testing.T) {
+  cfg := CORSConfig{AllowedOrigins: []string{"https://tools.example.com"}, AllowCredentials: true}
+  ts := httptest.NewServer(openapitest.Check(t, CORS(cfg, &StatsHandler{})))
+  defer ts.Close()
+
+  resp := doCORSRequest(t, "GET", ts.URL + "/stats", "https://tools.example.com", "")
+  if resp.Header.Get("Access-Control-Allow-Origin") != "https://tools.example.com" {
+    t.Errorf("Expected origin instead of * when credentials are allowed. Got %s", resp.Header.Get("Access-Control-Allow-Origin"))
+  }
+  if resp.Header.Get("Access-Control-Allow-Credentials") != "true" {
+    t.Errorf("Expected Access-Control-Allow-Credentials: true. Got %s", resp.Header.Get("Access-Control-Allow-Credentials"))
+  }
+}
+
+func TestCORSWildcardNeverAllowsCredentials(t *testing.T) {
+  cfg := CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+  if cfg.Check() == nil {
+    t.Errorf("Expected credentials for any origin to be refused")
+  }
+  ts := httptest.NewServer(openapitest.Check(t, CORS(cfg, &StatsHandler{})))
+  defer ts.Close()
+
+  // even when the check is skipped, a wildcard match gets a literal * and no credentials
+  resp := doCORSRequest(t, "GET", ts.URL + "/stats", "https://evil.example.com", "")
+  if resp.Header.Get("Access-Control-Allow-Origin") != "*" {
+    t.Errorf("Expected wildcard origin. Got %s", resp.Header.Get("Access-Control-Allow-Origin"))
+  }
+  if resp.Header.Get("Access-Control-Allow-Credentials") != "" {
+    t.Errorf("Expected no Access-Control-Allow-Credentials. Got %s", resp.Header.Get("Access-Control-Allow-Credentials"))
+  }
+  if (CORSConfig{AllowedOrigins: []string{"https://tools.example.com"}, AllowCredentials: true}).Check() != nil {
+    t.Errorf("Expected credentials for listed origins to be allowed")
+  }
+}
+
+func TestParseOrigins(t *testing.T) {
+  origins := ParseOrigins(" https://a.example.com, ,https://b.example.com ")
+  if len(origins) != 2 || origins[0] != "https://a.example.com" || origins[1] != "https://b.example.com" {
+    t.Errorf("Expected two trimmed origins. Got %v", origins)
+  }
+  if ParseOrigins("") != nil {
+    t.Errorf("Expected no origins for an empty list")
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+func doCORSRequest(t *testing.T, method string, url string, origin string, requestMethod string) *http.Response {
+  req, _ := http.NewRequest(method, url, nil)
+  req.Header.Set("Origin", origin)
+  if requestMethod != "" {
+    req.Header.Set("Access-Control-Request-Method", requestMethod)
+    req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+  }
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  return resp
+}
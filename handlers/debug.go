@@ -0,0 +1,119 @@
+package handlers
+
+import (
+    "encoding/json"
+    "expvar"
+    "net/http"
+    "net/http/pprof"
+    "runtime"
+    "time"
+)
+
+//////////////////////////////////////////////
+/////////////// Debug Endpoints //////////////
+//////////////////////////////////////////////
+
+// DebugVars is what the Service adds to /debug/vars under "gohttp", next to the usual cmdline and memstats
+type DebugVars struct {
+  Goroutines int
+  HashesInProgress int // sync and async hashes that haven't finished, the number a shutdown waits on
+  ShuttingDown bool
+  Stats Stats // the /stats message
+  GC GCStats
+}
+
+// GCStats is the garbage collector's side of runtime.MemStats, with times in microseconds
+type GCStats struct {
+  NumGC uint32
+  LastGC time.Time `json:",omitzero"`
+  LastPause float64
+  PauseTotal float64
+  HeapAlloc uint64 // bytes
+  HeapObjects uint64
+  NextGC uint64 // heap size the next collection starts at, in bytes
+}
+
+// DebugVars gathers the runtime numbers for /debug/vars
+func (s *Service) DebugVars() DebugVars {
+  var mem runtime.MemStats
+  runtime.ReadMemStats(&mem)
+  gc := GCStats{
+    NumGC: mem.NumGC,
+    PauseTotal: microseconds(time.Duration(mem.PauseTotalNs)),
+    HeapAlloc: mem.HeapAlloc,
+    HeapObjects: mem.HeapObjects,
+    NextGC: mem.NextGC,
+  }
+  if mem.NumGC > 0 {
+    gc.LastGC = time.Unix(0, int64(mem.LastGC))
+    gc.LastPause = microseconds(time.Duration(mem.PauseNs[(mem.NumGC + 255) % 256]))
+  }
+  return DebugVars{
+    Goroutines: runtime.NumGoroutine(),
+    HashesInProgress: int(s.hashesInProgress.Load()),
+    ShuttingDown: s.shuttingDown.Load(),
+    Stats: s.Snapshot(),
+    GC: gc,
+  }
+}
+
+var debugVarsMethods = []string{"GET", "HEAD", "OPTIONS"}
+
+// DebugVarsHandler serves /debug/vars the way expvar does, with the Service's DebugVars under "gohttp".
+// the Service's numbers aren't published to expvar itself since a process can run more than one Service
+type DebugVarsHandler struct {
+  Service *Service // DefaultService() when nil
+}
+// needs a ServeHTTP method from HandlerFunc Interface
+func (h *DebugVarsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  s := serviceOrDefault(h.Service)
+  switch r.Method {
+    case "GET", "HEAD":
+      vars := map[string]json.RawMessage{}
+      expvar.Do(func(kv expvar.KeyValue) {
+        vars[kv.Key] = json.RawMessage(kv.Value.String())
+      })
+      gohttp, err := json.Marshal(s.DebugVars())
+      if err != nil {
+        writeError(w, r, NewError(CodeInternal, "Issue fetching data"))
+        return
+      }
+      vars["gohttp"] = gohttp
+      jsonMessage, err := json.MarshalIndent(vars, "", "  ")
+      if err != nil {
+        writeError(w, r, NewError(CodeInternal, "Issue fetching data"))
+        return
+      }
+      w.Header().Set("Content-Type", "application/json; charset=utf-8")
+      w.Write(jsonMessage)
+    case "OPTIONS":
+      writeOptions(w, debugVarsMethods)
+    default:
+      writeMethodNotAllowed(w, r, debugVarsMethods)
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// adds /debug/pprof and /debug/vars to mux when Config.Debug is set. they need the AdminToken when there is one;
+// without it they're only served on an admin listener of their own, like /admin/keys
+func (s *Service) debugRoutes(mux *http.ServeMux, set routeSet) {
+  if !s.Debug {
+    return
+  }
+  guard := func(h http.Handler) http.Handler { return s.RequireAdmin(h) }
+  if s.AdminToken == "" {
+    if set != adminRoutes {
+      return
+    }
+    guard = func(h http.Handler) http.Handler { return h }
+  }
+  mux.Handle("/debug/pprof/", s.RecordStats("/debug/pprof", guard(http.HandlerFunc(pprof.Index))))
+  mux.Handle("/debug/pprof/cmdline", s.RecordStats("/debug/pprof", guard(http.HandlerFunc(pprof.Cmdline))))
+  mux.Handle("/debug/pprof/profile", s.RecordStats("/debug/pprof", guard(http.HandlerFunc(pprof.Profile))))
+  mux.Handle("/debug/pprof/symbol", s.RecordStats("/debug/pprof", guard(http.HandlerFunc(pprof.Symbol))))
+  mux.Handle("/debug/pprof/trace", s.RecordStats("/debug/pprof", guard(http.HandlerFunc(pprof.Trace))))
+  mux.Handle("/debug/vars", s.RecordStats("/debug/vars", guard(&DebugVarsHandler{Service: s})))
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "math/rand"
+    "strings"
+    "time"
+)
+
+//////////////////////////////////////////////
+////////////// Artificial Delay //////////////
+//////////////////////////////////////////////
+
+// DelayPolicy decides how long /hash waits before hashing
+type DelayPolicy interface {
+  Next() time.Duration
+}
+
+// NoDelay hashes straight away
+type NoDelay struct{}
+
+func (NoDelay) Next() time.Duration { return 0 }
+
+// FixedDelay always waits the same amount of time
+type FixedDelay struct {
+  Delay time.Duration
+}
+
+func (f FixedDelay) Next() time.Duration { return f.Delay }
+
+// JitterDelay waits a uniformly random time between Min and Max
+type JitterDelay struct {
+  Min time.Duration
+  Max time.Duration
+}
+
+func (j JitterDelay) Next() time.Duration {
+  if j.Max <= j.Min {
+    return j.Min
+  }
+  return j.Min + time.Duration(rand.Int63n(int64(j.Max - j.Min) + 1))
+}
+
+// ExponentialDelay waits an exponentially distributed time averaging Mean, so most waits are short with a long tail.
+// waits are capped at Max when it is set
+type ExponentialDelay struct {
+  Mean time.Duration
+  Max time.Duration
+}
+
+func (e ExponentialDelay) Next() time.Duration {
+  d := time.Duration(rand.ExpFloat64() * float64(e.Mean))
+  if e.Max > 0 && d > e.Max {
+    return e.Max
+  }
+  return d
+}
+
+// the original behaviour: every hash waits 5 seconds
+var DefaultDelayPolicy DelayPolicy = FixedDelay{Delay: 5 * time.Second}
+
+// ParseDelayPolicy reads a delay policy from config:
+// none, fixed:5s, jitter:1s-5s or exponential:2s (the mean; capped at 10 times the mean)
+func ParseDelayPolicy(spec string) (DelayPolicy, error) {
+  kind, arg, _ := strings.Cut(strings.TrimSpace(spec), ":")
+  switch strings.ToLower(kind) {
+    case "none":
+      if arg != "" {
+        break
+      }
+      return NoDelay{}, nil
+    case "fixed":
+      d, err := parseDelay(arg)
+      if err != nil {
+        return nil, err
+      }
+      return FixedDelay{Delay: d}, nil
+    case "jitter":
+      lo, hi, ok := strings.Cut(arg, "-")
+      if !ok {
+        break
+      }
+      min, err := parseDelay(lo)
+      if err != nil {
+        return nil, err
+      }
+      max, err := parseDelay(hi)
+      if err != nil {
+        return nil, err
+      }
+      if max < min {
+        return nil, fmt.Errorf("jitter delay %s is smaller than %s", hi, lo)
+      }
+      return JitterDelay{Min: min, Max: max}, nil
+    case "exponential":
+      mean, err := parseDelay(arg)
+      if err != nil {
+        return nil, err
+      }
+      return ExponentialDelay{Mean: mean, Max: 10 * mean}, nil
+  }
+  return nil, fmt.Errorf("unknown delay policy %q; use none, fixed:5s, jitter:1s-5s or exponential:2s", spec)
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+func parseDelay(s string) (time.Duration, error) {
+  d, err := time.ParseDuration(s)
+  if err != nil {
+    return 0, fmt.Errorf("bad delay %q: %v", s, err)
+  }
+  if d < 0 {
+    return 0, fmt.Errorf("delay %s is negative", s)
+  }
+  return d, nil
+}
+
+// waits d on clock. returns ctx.Err() early if ctx is done first
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+  if d <= 0 {
+    return ctx.Err()
+  }
+  select {
+    case <-ctx.Done():
+      return ctx.Err()
+    case <-clock.After(d):
+      return nil
+  }
+}
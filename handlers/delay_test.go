@@ -0,0 +1,50 @@
+package handlers
+
+import (
+  "testing"
+  "context"
+  "time"
+)
+
+//////////////////////////////////////////////
+/////////////// Delay Unit Tests /////////////
+//////////////////////////////////////////////
+
+func TestParseDelayPolicy(t *testing.T) {
+  valid := map[string]DelayPolicy{
+    "none": NoDelay{},
+    "fixed:5s": FixedDelay{Delay: 5 * time.Second},
+    "jitter:1s-3s": JitterDelay{Min: time.Second, Max: 3 * time.Second},
+    "exponential:2s": ExponentialDelay{Mean: 2 * time.Second, Max: 20 * time.Second},
+  }
+  for spec, expected := range valid {
+    policy, err := ParseDelayPolicy(spec)
+    if err != nil || policy != expected {
+      t.Errorf("Expected %s to parse as %+v. got %+v %v", spec, expected, policy, err)
+    }
+  }
+  for _, spec := range []string{"", "sleepy", "fixed", "fixed:-1s", "jitter:3s", "jitter:3s-1s", "none:5s"} {
+    if _, err := ParseDelayPolicy(spec); err == nil {
+      t.Errorf("Expected an error for %q", spec)
+    }
+  }
+}
+
+func TestRandomDelaysStayInRange(t *testing.T) {
+  jitter := JitterDelay{Min: time.Second, Max: 2 * time.Second}
+  exponential := ExponentialDelay{Mean: time.Second, Max: 3 * time.Second}
+  for i := 0; i < 1000; i++ {
+    if d := jitter.Next(); d < jitter.Min || d > jitter.Max {
+      t.Fatalf("Expected jitter between 1s and 2s. got %v", d)
+    }
+    if d := exponential.Next(); d < 0 || d > exponential.Max {
+      t.Fatalf("Expected exponential delay between 0 and 3s. got %v", d)
+    }
+  }
+}
+
+func TestSleepWithoutDelay(t *testing.T) {
+  if err := sleep(context.Background(), RealClock, 0); err != nil {
+    t.Errorf("Expected no error. got %v", err)
+  }
+}
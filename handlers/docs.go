@@ -0,0 +1,48 @@
+package handlers
+
+import (
+    "net/http"
+    "github.com/rdibari84/GoHTTP/handlers/openapi"
+)
+
+//////////////////////////////////////////////
+/////////////// Documentation ////////////////
+//////////////////////////////////////////////
+
+var docsMethods = []string{"GET", "HEAD", "OPTIONS"}
+
+// OpenAPIHandler serves the OpenAPI 3 document describing every endpoint
+type OpenAPIHandler struct {
+  Spec []byte // the document for one api version; openapi.Spec (v1) when nil
+}
+// needs a ServeHTTP method from HandlerFunc Interface
+func (h *OpenAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  switch r.Method {
+    case "GET", "HEAD":
+      spec := h.Spec
+      if spec == nil {
+        spec = openapi.Spec
+      }
+      write200Msg(w, spec)
+    case "OPTIONS":
+      writeOptions(w, docsMethods)
+    default:
+      writeMethodNotAllowed(w, r, docsMethods)
+  }
+}
+
+// DocsHandler serves a page rendering the OpenAPI document for people
+type DocsHandler struct {}
+// needs a ServeHTTP method from HandlerFunc Interface
+func (h *DocsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  switch r.Method {
+    case "GET", "HEAD":
+      w.Header().Set("Content-Type", "text/html; charset=utf-8")
+      w.WriteHeader(http.StatusOK)
+      w.Write(openapi.Docs)
+    case "OPTIONS":
+      writeOptions(w, docsMethods)
+    default:
+      writeMethodNotAllowed(w, r, docsMethods)
+  }
+}
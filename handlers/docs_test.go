@@ -0,0 +1,77 @@
+package handlers
+
+import (
+  "testing"
+  "encoding/json"
+  "io/ioutil"
+  "net/http"
+  "net/http/httptest"
+  "sort"
+  "strings"
+  "github.com/rdibari84/GoHTTP/handlers/openapi/openapitest"
+)
+
+//////////////////////////////////////////////
+/////////////// Docs Unit Tests //////////////
+//////////////////////////////////////////////
+
+func TestOpenAPIServed(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, NewService(Config{}).Routes(nil)))
+  defer ts.Close()
+
+  resp, err := http.Get(ts.URL + "/openapi.json")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  defer resp.Body.Close()
+  doc := struct {
+    OpenAPI string
+    Paths map[string]interface{}
+  }{}
+  if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+    t.Fatalf("Expected a json document. Error: %s", err)
+  }
+  if !strings.HasPrefix(doc.OpenAPI, "3.") {
+    t.Errorf("Expected an OpenAPI 3 document. got %q", doc.OpenAPI)
+  }
+  for _, path := range []string{"/hash", "/hash/{id}", "/stats", "/shutdown", "/errors"} {
+    if _, ok := doc.Paths[path]; !ok {
+      t.Errorf("Expected %s to be documented", path)
+    }
+  }
+}
+
+func TestDocsPage(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, NewService(Config{}).Routes(nil)))
+  defer ts.Close()
+
+  resp, err := http.Get(ts.URL + "/docs")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  defer resp.Body.Close()
+  body, _ := ioutil.ReadAll(resp.Body)
+  if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") || !strings.Contains(string(body), "openapi.json") {
+    t.Errorf("Expected a page loading openapi.json. got %s", resp.Header.Get("Content-Type"))
+  }
+}
+
+func TestOpenAPIListsEveryErrorCode(t *testing.T) {
+  doc, err := openapitest.Document("v1")
+  if err != nil {
+    t.Fatalf("Expected a valid document. Error: %s", err)
+  }
+  documented := []string{}
+  for _, code := range doc.Components.Schemas["ErrorCode"].Value.Enum {
+    documented = append(documented, code.(string))
+  }
+  catalog := []string{}
+  for _, def := range ErrorCatalog {
+    catalog = append(catalog, string(def.Code))
+  }
+  sort.Strings(documented)
+  sort.Strings(catalog)
+  if strings.Join(documented, ",") != strings.Join(catalog, ",") {
+    t.Errorf("Expected the ErrorCode schema to match the ErrorCatalog. got %v want %v", documented, catalog)
+  }
+}
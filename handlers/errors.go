@@ -0,0 +1,252 @@
+package handlers
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "math"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+//////////////////////////////////////////////
+//////////////// Error Model /////////////////
+//////////////////////////////////////////////
+
+// ErrorCode is a stable, machine readable identifier for an error. Clients should branch on these, not on messages
+type ErrorCode string
+
+const (
+  CodeMissingPassword ErrorCode = "missing_password"
+  CodeMultiplePasswords ErrorCode = "multiple_passwords"
+  CodeInvalidForm ErrorCode = "invalid_form"
+  CodeBodyTooLarge ErrorCode = "body_too_large"
+  CodeInvalidUTF8 ErrorCode = "invalid_utf8"
+  CodePasswordTooShort ErrorCode = "password_too_short"
+  CodePasswordTooLong ErrorCode = "password_too_long"
+  CodeBreachedPassword ErrorCode = "breached_password"
+  CodeMissingHash ErrorCode = "missing_hash"
+  CodeInvalidParameter ErrorCode = "invalid_parameter"
+  CodeInvalidMessage ErrorCode = "invalid_message"
+  CodeInvalidCallback ErrorCode = "invalid_callback"
+  CodeUpgradeRequired ErrorCode = "upgrade_required"
+  CodeMissingAPIKey ErrorCode = "missing_api_key"
+  CodeInvalidAPIKey ErrorCode = "invalid_api_key"
+  CodeQuotaExceeded ErrorCode = "quota_exceeded"
+  CodeAdminRequired ErrorCode = "admin_required"
+  CodeKeyNotFound ErrorCode = "key_not_found"
+  CodeJobNotFound ErrorCode = "job_not_found"
+  CodeJobFinished ErrorCode = "job_finished"
+  CodeMethodNotAllowed ErrorCode = "method_not_allowed"
+  CodeNotFound ErrorCode = "not_found"
+  CodeShuttingDown ErrorCode = "shutting_down"
+  CodeShutdownNotScheduled ErrorCode = "shutdown_not_scheduled"
+  CodeRestartFailed ErrorCode = "restart_failed"
+  CodeQueueFull ErrorCode = "queue_full"
+  CodeInternal ErrorCode = "internal_error"
+)
+
+// ErrorDefinition documents one error code: the status it is returned with and what it means
+type ErrorDefinition struct {
+  Code ErrorCode
+  Status int
+  Title string
+}
+
+// ErrorCatalog lists every error code the api can return. served at /errors
+var ErrorCatalog = []ErrorDefinition{
+  {CodeMissingPassword, http.StatusBadRequest, "The password form parameter is missing"},
+  {CodeMultiplePasswords, http.StatusBadRequest, "More than one password form parameter was sent"},
+  {CodeInvalidForm, http.StatusBadRequest, "The request body is not a valid urlencoded form"},
+  {CodeBodyTooLarge, http.StatusRequestEntityTooLarge, "The request body is bigger than the server accepts"},
+  {CodeInvalidUTF8, http.StatusBadRequest, "The password is not valid UTF-8"},
+  {CodePasswordTooShort, http.StatusBadRequest, "The password is shorter than the minimum length"},
+  {CodePasswordTooLong, http.StatusBadRequest, "The password is longer than the maximum length"},
+  {CodeBreachedPassword, http.StatusBadRequest, "The password is on the breached password list"},
+  {CodeMissingHash, http.StatusBadRequest, "The hash form parameter is missing or sent more than once"},
+  {CodeInvalidParameter, http.StatusBadRequest, "A query or form parameter has an invalid value"},
+  {CodeInvalidMessage, http.StatusBadRequest, "A websocket message is not a valid JobSubmission"},
+  {CodeInvalidCallback, http.StatusBadRequest, "The callback_url is not an http(s) url, or callbacks are not enabled on this server"},
+  {CodeUpgradeRequired, http.StatusUpgradeRequired, "The resource is only available over a websocket"},
+  {CodeMissingAPIKey, http.StatusUnauthorized, "The endpoint needs an API key, sent as Authorization: Bearer <key> or X-API-Key"},
+  {CodeInvalidAPIKey, http.StatusUnauthorized, "The API key is unknown or has been revoked"},
+  {CodeQuotaExceeded, http.StatusTooManyRequests, "The API key has used its daily quota; retry after Retry-After seconds"},
+  {CodeAdminRequired, http.StatusUnauthorized, "The admin api needs the admin token, sent as Authorization: Bearer <token>"},
+  {CodeKeyNotFound, http.StatusNotFound, "No API key exists with this id"},
+  {CodeJobNotFound, http.StatusNotFound, "No hash job exists with this id, or it has expired"},
+  {CodeJobFinished, http.StatusConflict, "The hash job has already finished, so it can't be cancelled"},
+  {CodeMethodNotAllowed, http.StatusMethodNotAllowed, "The resource does not support this method; see the Allow header"},
+  {CodeNotFound, http.StatusNotFound, "No resource exists at this path"},
+  {CodeShuttingDown, http.StatusServiceUnavailable, "The server is shutting down and no longer accepts work"},
+  {CodeShutdownNotScheduled, http.StatusConflict, "No shutdown is scheduled, so there is nothing to cancel"},
+  {CodeRestartFailed, http.StatusInternalServerError, "The new process did not start or report ready; the running one keeps serving"},
+  {CodeQueueFull, http.StatusServiceUnavailable, "Every hashing worker is busy and the queue is full; retry after Retry-After seconds"},
+  {CodeInternal, http.StatusInternalServerError, "Something went wrong on the server"},
+}
+
+// APIError is an error that knows how it should be reported to the caller
+type APIError struct {
+  Code ErrorCode
+  Status int
+  Message string
+  RetryAfter time.Duration // sent as a Retry-After header, in whole seconds, when set
+}
+
+func (e *APIError) Error() string {
+  return string(e.Code) + ": " + e.Message
+}
+
+// NewError builds an APIError using the status registered for code in the ErrorCatalog
+func NewError(code ErrorCode, message string) *APIError {
+  status := http.StatusInternalServerError
+  if def, ok := lookupError(code); ok {
+    status = def.Status
+  }
+  return &APIError{Code: code, Status: status, Message: message}
+}
+
+// RFC 7807 problem details; returned when the client sends Accept: application/problem+json
+type ProblemDetails struct {
+  Type string `json:"type"`
+  Title string `json:"title"`
+  Status int `json:"status"`
+  Detail string `json:"detail,omitempty"`
+  Instance string `json:"instance,omitempty"`
+  Code ErrorCode `json:"code"`
+  RequestID string `json:"request_id,omitempty"`
+}
+
+var errorsMethods = []string{"GET", "HEAD", "OPTIONS"}
+
+// ErrorsHandler serves the error catalog so clients can discover every code
+type ErrorsHandler struct {}
+// needs a ServeHTTP method from HandlerFunc Interface
+func (e *ErrorsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  switch r.Method {
+    case "GET", "HEAD":
+      writeJSON(w, r, http.StatusOK, ErrorCatalog)
+    case "OPTIONS":
+      writeOptions(w, errorsMethods)
+    default:
+      writeMethodNotAllowed(w, r, errorsMethods)
+  }
+}
+
+//////////////////////////////////////////////
+///////////////// Request IDs ////////////////
+//////////////////////////////////////////////
+
+type contextKey string
+
+const requestIDKey contextKey = "request-id"
+
+// RequestID makes sure every request has an id: the caller's X-Request-ID if it looks sane, otherwise a new one.
+// the id is echoed back in the X-Request-ID header and included in error responses
+func RequestID(next http.Handler) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    id := r.Header.Get("X-Request-ID")
+    if !validRequestID(id) {
+      id = newRequestID()
+    }
+    w.Header().Set("X-Request-ID", id)
+    next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+  })
+}
+
+// RequestIDFrom returns the id assigned by the RequestID middleware, or "" if there is none
+func RequestIDFrom(ctx context.Context) string {
+  id, _ := ctx.Value(requestIDKey).(string)
+  return id
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// writeError reports an APIError as an ErrorMessage (an Envelope for /v2), or as problem details if the client asked for them
+func writeError(w http.ResponseWriter, r *http.Request, apiErr *APIError) {
+  setErrorReason(w, string(apiErr.Code)) // show up in the /stats error breakdown
+  requestID := RequestIDFrom(r.Context())
+  tree := treeOf(r)
+  if apiErr.RetryAfter > 0 {
+    w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(apiErr.RetryAfter.Seconds()))))
+  }
+
+  if acceptsProblemJSON(r) {
+    title := apiErr.Message
+    if def, ok := lookupError(apiErr.Code); ok {
+      title = def.Title
+    }
+    problem := ProblemDetails{
+      Type: tree.base + "/errors#" + string(apiErr.Code),
+      Title: title,
+      Status: apiErr.Status,
+      Detail: apiErr.Message,
+      Instance: tree.base + r.URL.Path,
+      Code: apiErr.Code,
+      RequestID: requestID,
+    }
+    jsonMessage, err := json.Marshal(problem)
+    if err == nil {
+      w.Header().Set("Content-Type", "application/problem+json")
+      w.WriteHeader(apiErr.Status)
+      w.Write(jsonMessage)
+      return
+    }
+  }
+
+  var m interface{} = ErrorMessage{Error: apiErr.Message, Code: apiErr.Code, RequestID: requestID}
+  if tree.version == Version2 {
+    m = Envelope{Error: &EnvelopeError{Code: apiErr.Code, Message: apiErr.Message}, RequestID: requestID}
+  }
+  jsonMessage, err := json.Marshal(m)
+  if err != nil {
+    jsonMessage = []byte("{\"Error\": \"\"}")
+  }
+  w.Header().Set("Content-Type", "application/json")
+  w.WriteHeader(apiErr.Status)
+  w.Write(jsonMessage)
+}
+
+func acceptsProblemJSON(r *http.Request) bool {
+  for _, accept := range r.Header.Values("Accept") {
+    for _, mediaType := range strings.Split(accept, ",") {
+      mediaType = strings.TrimSpace(strings.Split(mediaType, ";")[0])
+      if mediaType == "application/problem+json" {
+        return true
+      }
+    }
+  }
+  return false
+}
+
+func lookupError(code ErrorCode) (ErrorDefinition, bool) {
+  for _, def := range ErrorCatalog {
+    if def.Code == code {
+      return def, true
+    }
+  }
+  return ErrorDefinition{}, false
+}
+
+// request ids are echoed into headers and logs, so only accept short printable ones
+func validRequestID(id string) bool {
+  if id == "" || len(id) > 64 {
+    return false
+  }
+  for _, c := range id {
+    if c < '!' || c > '~' {
+      return false
+    }
+  }
+  return true
+}
+
+func newRequestID() string {
+  b := make([]byte, 16)
+  rand.Read(b)
+  return hex.EncodeToString(b)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+  "testing"
+  "net/http"
+  "net/http/httptest"
+  "io/ioutil"
+  "strings"
+  "encoding/json"
+  "github.com/rdibari84/GoHTTP/handlers/openapi/openapitest"
+)
+
+//////////////////////////////////////////////
+/////////////// Error Unit Tests /////////////
+//////////////////////////////////////////////
+
+func TestHashErrorsCarryCodes(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, RequestID(&HashHandler{})))
+  defer ts.Close()
+
+  cases := []struct {
+    body string
+    code ErrorCode
+  }{
+    {"", CodeMissingPassword},
+    {"password=a&password=b", CodeMultiplePasswords},
+  }
+  for _, c := range cases {
+    resp, err := http.Post(ts.URL + "/hash", "application/x-www-form-urlencoded", strings.NewReader(c.body))
+    if err != nil {
+      t.Fatalf("Expected no error. Error: %s", err)
+    }
+    m := readErrorMessage(t, resp)
+    if resp.StatusCode != 400 {
+      t.Errorf("Expected 400 error code. Got %d", resp.StatusCode)
+    }
+    if m.Code != c.code {
+      t.Errorf("Expected code %s. Got %s", c.code, m.Code)
+    }
+    if m.RequestID == "" || m.RequestID != resp.Header.Get("X-Request-ID") {
+      t.Errorf("Expected request id %s in body. Got %s", resp.Header.Get("X-Request-ID"), m.RequestID)
+    }
+  }
+}
+
+func TestMethodNotAllowedCode(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, &StatsHandler{}))
+  defer ts.Close()
+
+  resp, err := http.Post(ts.URL + "/stats", "application/x-www-form-urlencoded", strings.NewReader(""))
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  m := readErrorMessage(t, resp)
+  if m.Code != CodeMethodNotAllowed || m.Error != "POST is not supported" {
+    t.Errorf("Expected method_not_allowed. Got %+v", m)
+  }
+}
+
+func TestShuttingDownRefusesHashes(t *testing.T) {
+  service := newTestService(t)
+  service.shuttingDown.Store(true)
+  ts := httptest.NewServer(openapitest.Check(t, &HashHandler{Service: service}))
+  defer ts.Close()
+
+  resp, err := http.Post(ts.URL + "/hash", "application/x-www-form-urlencoded", strings.NewReader("password=angryMonkey"))
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  m := readErrorMessage(t, resp)
+  if resp.StatusCode != 503 || m.Code != CodeShuttingDown {
+    t.Errorf("Expected 503 shutting_down. Got %d %+v", resp.StatusCode, m)
+  }
+}
+
+func TestProblemJSONWhenAccepted(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, RequestID(&HashHandler{})))
+  defer ts.Close()
+
+  req, _ := http.NewRequest("POST", ts.URL + "/hash", strings.NewReader(""))
+  req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+  req.Header.Set("Accept", "application/json;q=0.5, application/problem+json")
+  req.Header.Set("X-Request-ID", "abc-123")
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+
+  if resp.Header.Get("Content-Type") != "application/problem+json" {
+    t.Errorf("Expected application/problem+json. Got %s", resp.Header.Get("Content-Type"))
+  }
+  problem := ProblemDetails{}
+  if err := json.Unmarshal(body, &problem); err != nil {
+    t.Fatalf("Expected problem json. Error: %s", err)
+  }
+  if problem.Type != "/errors#missing_password" || problem.Status != 400 || problem.Code != CodeMissingPassword {
+    t.Errorf("Expected missing_password problem. Got %+v", problem)
+  }
+  if problem.Instance != "/hash" || problem.RequestID != "abc-123" {
+    t.Errorf("Expected instance /hash and caller's request id. Got %+v", problem)
+  }
+}
+
+func TestRequestIDReplacesBadIDs(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, RequestID(&StatsHandler{})))
+  defer ts.Close()
+
+  req, _ := http.NewRequest("GET", ts.URL + "/stats", nil)
+  req.Header.Set("X-Request-ID", strings.Repeat("x", 100))
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  id := resp.Header.Get("X-Request-ID")
+  if len(id) != 32 {
+    t.Errorf("Expected a newly generated request id. Got %s", id)
+  }
+}
+
+func TestErrorCatalogEndpoint(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, &ErrorsHandler{}))
+  defer ts.Close()
+
+  resp, err := http.Get(ts.URL + "/errors")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+
+  var catalog []ErrorDefinition
+  if err := json.Unmarshal(body, &catalog); err != nil {
+    t.Fatalf("Expected catalog json. Error: %s", err)
+  }
+  if len(catalog) != len(ErrorCatalog) {
+    t.Errorf("Expected %d catalog entries. Got %d", len(ErrorCatalog), len(catalog))
+  }
+}
+
+func TestNewErrorUsesCatalogStatus(t *testing.T) {
+  if NewError(CodeShuttingDown, "").Status != 503 {
+    t.Errorf("Expected shutting_down to be a 503")
+  }
+  if NewError("made_up", "").Status != 500 {
+    t.Errorf("Expected unknown codes to be a 500")
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+func readErrorMessage(t *testing.T, resp *http.Response) ErrorMessage {
+  body, err := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  m := ErrorMessage{}
+  if err := json.Unmarshal(body, &m); err != nil {
+    t.Fatalf("Expected an ErrorMessage. Got %s", body)
+  }
+  return m
+}
@@ -9,99 +9,262 @@ import (
     "time"
     "encoding/json"
     "context"
+    "errors"
+    "strconv"
+    "strings"
+    "github.com/rdibari84/GoHTTP/audit"
 )
 
 //////////////////////////////////////////////
 ///////////// Global Variables ///////////////
 //////////////////////////////////////////////
 
-// InProgress tracker for hashing; used in shutdown to ensure all hashing work has completed
-var hashInProgress = false;
-
 // defines an error message structure- to make an error a little pretty
 type ErrorMessage struct {
     Error string
+    Code ErrorCode `json:",omitempty"` // stable machine readable code; see ErrorCatalog
+    RequestID string `json:",omitempty"` // matches the X-Request-ID response header
+}
+
+// verify endpoint return message format
+type Verification struct {
+    Match bool
 }
 
 // stats endpoint return message format
 type Stats struct {
-    Total int
-    Average float64
+    Total int // number of successful /hash calls
+    Average float64 // average /hash response time in microseconds
+    Endpoints []EndpointStats // every request, grouped by route, method and status code
+    Protocols map[string]int `json:",omitempty"` // requests per protocol: HTTP/1.1, HTTP/2.0
+    Pool *PoolStats `json:",omitempty"` // hashing worker pool queue depth and wait times
+    Keys []KeyStats `json:",omitempty"` // requests, quota use and response times per API key, when keys are required
+    Cache *CacheStats `json:",omitempty"` // hash cache hits and misses, and requests that shared an identical hash in progress
 }
 
-// initialize empty slice of time.Duration.
-// This will track the running time total it takes for the /hash endpoint to return
-// used in the /stats endpoint
-var summedHashResponseTimes []time.Duration
+// methods each handler answers; sent back in the Allow header
+var hashMethods = []string{"GET", "HEAD", "POST", "OPTIONS"}
+var jobMethods = []string{"GET", "HEAD", "DELETE", "OPTIONS"}
+var verifyMethods = []string{"POST", "OPTIONS"}
+var statsMethods = []string{"GET", "HEAD", "OPTIONS"}
+var statsResetMethods = []string{"GET", "HEAD", "DELETE", "OPTIONS"}
+var shutdownMethods = []string{"GET", "HEAD", "POST", "DELETE", "OPTIONS"}
 
 //////////////////////////////////////////////
 ///////////// Handlers ///////////////
 //////////////////////////////////////////////
 
-type HashHandler struct {}
+type HashHandler struct {
+  Service *Service // DefaultService() when nil
+}
 // needs a ServeHTTP method from HandlerFunc Interface
 func (h *HashHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  s := serviceOrDefault(h.Service)
+  if strings.HasPrefix(r.URL.Path, "/hash/") { // async job lookup
+    switch r.Method {
+      case "GET", "HEAD":
+        job, apiErr := s.Result(r.Context(), strings.TrimPrefix(r.URL.Path, "/hash/"))
+        if apiErr != nil {
+          writeError(w, r, apiErr)
+          return
+        }
+        writeJob(w, r, http.StatusOK, job)
+      case "DELETE":
+        job, apiErr := s.CancelJob(r.Context(), strings.TrimPrefix(r.URL.Path, "/hash/"))
+        if apiErr != nil {
+          writeError(w, r, apiErr)
+          return
+        }
+        writeJob(w, r, http.StatusOK, job)
+      case "OPTIONS":
+        writeOptions(w, jobMethods)
+      default:
+        writeMethodNotAllowed(w, r, jobMethods)
+    }
+    return
+  }
   switch r.Method {
+      case "GET", "HEAD": // the caller's async jobs
+        query := r.URL.Query()
+        limit := 0
+        if v := query.Get("limit"); v != "" {
+          n, err := strconv.Atoi(v)
+          if err != nil || n < 1 { // 0 would mean the default
+            writeError(w, r, NewError(CodeInvalidParameter, fmt.Sprintf("limit must be between 1 and %d", MaxJobListLimit)))
+            return
+          }
+          limit = n
+        }
+        list, apiErr := s.ListJobs(r.Context(), query.Get("status"), query.Get("cursor"), limit)
+        if apiErr != nil {
+          writeError(w, r, apiErr)
+          return
+        }
+        writeJSON(w, r, http.StatusOK, list)
       case "POST":
-        start := time.Now() // capture starting time
-        r.ParseForm()
+        if !parseForm(w, r, s.Policy) {
+          return
+        }
         formData := r.Form["password"]
         if formData == nil {
-          writeErrorMsg(w, "Missing input data in request", 400)
+          writeError(w, r, NewError(CodeMissingPassword, "Missing input data in request"))
           return
         }
         if len(formData) != 1 {
-          writeErrorMsg(w, "Bad input data in request", 400)
+          writeError(w, r, NewError(CodeMultiplePasswords, "Bad input data in request"))
+          return
+        }
+        async, err := parseBool(r.Form.Get("async"))
+        if r.Form.Get("async") == "" && treeOf(r).version == Version2 {
+          async = true // v2 hands back a job id unless the caller asks to wait
+        }
+        if err != nil {
+          writeError(w, r, NewError(CodeInvalidForm, "async must be true or false"))
+          return
+        }
+        callbackURL := r.Form.Get("callback_url") // the result is delivered later, so a callback implies async
+        if async || callbackURL != "" {
+          job, apiErr := s.HashAsync(r.Context(), formData[0], callbackURL)
+          if apiErr != nil {
+            writeHashError(w, r, apiErr)
+            return
+          }
+          writeJob(w, r, http.StatusAccepted, job)
+          return
+        }
+        hash, apiErr := s.Hash(r.Context(), formData[0]) // stops early if the caller goes away
+        if apiErr != nil {
+          writeHashError(w, r, apiErr)
           return
         }
-        hashInProgress = true;
-        fmt.Printf("Waiting 5 sec before returning hash\n")
-        time.Sleep(time.Duration(5)*time.Second) // Pause for 5 seconds
-        hash := generate_hash(formData[0])
-        hashInProgress = false;
         //fmt.Printf("returning hash %s\n", hash)
-        write200Msg(w, []byte(hash))
-        elapsed := time.Since(start) // caculate how much time has passed
-        summedHashResponseTimes = addSummedResponseTime(elapsed, summedHashResponseTimes) // add elapsed time to summedHashResponseTimes slice
+        if treeOf(r).version == Version2 {
+          writeJSON(w, r, http.StatusOK, HashResult{Hash: hash})
+          return
+        }
+        write200Msg(w, []byte(hash)) // bare text, as v1 callers expect
+      case "OPTIONS":
+        writeOptions(w, hashMethods)
       default:
-        writeErrorMsg(w, r.Method + " is not supported", http.StatusNotFound)
+        writeMethodNotAllowed(w, r, hashMethods)
+  }
+}
+
+type StatsHandler struct {
+  Service *Service // DefaultService() when nil
+  AllowReset bool // DELETE starts the stats over. only set on the admin listener
+}
+// needs a ServeHTTP method from HandlerFunc Interface
+func (h *StatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  s := serviceOrDefault(h.Service)
+  methods := statsMethods
+  if h.AllowReset {
+    methods = statsResetMethods
+  }
+  switch {
+    case r.Method == "GET" || r.Method == "HEAD": // net/http drops the body for HEAD requests
+      writeJSON(w, r, http.StatusOK, s.Snapshot())
+    case r.Method == "DELETE" && h.AllowReset:
+      s.ResetStats()
+      s.auditRequest(r, audit.EventStatsReset, "")
+      w.WriteHeader(http.StatusNoContent)
+    case r.Method == "OPTIONS":
+      writeOptions(w, methods)
+    default:
+      writeMethodNotAllowed(w, r, methods)
   }
 }
 
-type StatsHandler struct {}
+type VerifyHandler struct {
+  Service *Service // normalizes the password with the same policy as /hash; DefaultService() when nil
+}
 // needs a ServeHTTP method from HandlerFunc Interface
-func (s *StatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+func (v *VerifyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  s := serviceOrDefault(v.Service)
   switch r.Method {
-    case "GET":
-      m := Stats{Total: len(summedHashResponseTimes), Average: calcAverageResponseTime(summedHashResponseTimes)}
-      jsonMessage, err := json.Marshal(m) // create json message with password hash
-      if err != nil {
-        writeErrorMsg(w, "Issue fetching data", http.StatusInternalServerError)
-      } else {
-        write200Msg(w, jsonMessage)
+    case "POST":
+      if !parseForm(w, r, s.Policy) {
+        return
+      }
+      passwords := r.Form["password"]
+      if len(passwords) != 1 {
+        writeError(w, r, NewError(CodeMissingPassword, "Exactly one password is required"))
+        return
+      }
+      hashes := r.Form["hash"]
+      if len(hashes) != 1 {
+        writeError(w, r, NewError(CodeMissingHash, "Exactly one hash is required"))
+        return
       }
+      match, apiErr := s.Verify(r.Context(), passwords[0], hashes[0])
+      if apiErr != nil {
+        writeHashError(w, r, apiErr)
+        return
+      }
+      writeJSON(w, r, http.StatusOK, Verification{Match: match})
+    case "OPTIONS":
+      writeOptions(w, verifyMethods)
     default:
-      writeErrorMsg(w, r.Method + " is not supported", http.StatusNotFound)
+      writeMethodNotAllowed(w, r, verifyMethods)
   }
 }
 
+// ShutdownHandler shows the shutdown's status on GET, schedules one on POST (after= a delay, deadline= how long the
+// drain waits for the hashing in progress) and calls a scheduled one off on DELETE. GET used to shut down straight away,
+// under /v1 too; the README records the break
 type ShutdownHandler struct {
-  Srv *http.Server // takes an httpServer
+  Srv *http.Server // takes an httpServer; shut down once the drain is done. only the hashing is drained when nil
+  Service *Service // whose hashing work to wait for; DefaultService() when nil
 }
 // needs a ServeHTTP method from HandlerFunc Interface
-func (s *ShutdownHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+func (h *ShutdownHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  s := serviceOrDefault(h.Service)
   switch r.Method {
-    case "GET":
-        for true { // continue looping until hash is not in progress.
-          if !hashInProgress {
-            fmt.Printf("Received shutdown request... shutting down\n")
-            if err := s.Srv.Shutdown(context.Background()); err != nil && err != http.ErrServerClosed {
-                log.Fatal(err)
-            }
-          }
-        }
+    case "GET", "HEAD":
+      writeJSON(w, r, http.StatusOK, s.ShutdownStatus())
+    case "POST":
+      if !parseForm(w, r, s.Policy) {
+        return
+      }
+      after, apiErr := durationParam(r, "after")
+      if apiErr != nil {
+        writeError(w, r, apiErr)
+        return
+      }
+      deadline, apiErr := durationParam(r, "deadline")
+      if apiErr != nil {
+        writeError(w, r, apiErr)
+        return
+      }
+      status, apiErr := s.ScheduleShutdown(after, deadline, h.stop)
+      if apiErr != nil {
+        writeError(w, r, apiErr)
+        return
+      }
+      s.auditRequest(r, audit.EventShutdown, fmt.Sprintf("after %v, deadline %v", after, deadline))
+      writeJSON(w, r, http.StatusAccepted, status)
+    case "DELETE":
+      status, apiErr := s.CancelShutdown()
+      if apiErr != nil {
+        writeError(w, r, apiErr)
+        return
+      }
+      s.auditRequest(r, audit.EventShutdownCancelled, "")
+      writeJSON(w, r, http.StatusOK, status)
+    case "OPTIONS":
+      writeOptions(w, shutdownMethods)
     default:
-      writeErrorMsg(w, r.Method + " is not supported", http.StatusNotFound)
+      writeMethodNotAllowed(w, r, shutdownMethods)
+  }
+}
+
+// shuts Srv down once the drain is done. ctx is done once the drain deadline passes, which cuts off the requests still in flight
+func (h *ShutdownHandler) stop(ctx context.Context) {
+  if h.Srv == nil {
+    return
+  }
+  if err := h.Srv.Shutdown(ctx); err != nil && err != http.ErrServerClosed && err != ctx.Err() {
+    log.Fatal(err)
   }
 }
 
@@ -117,16 +280,75 @@ func write200Msg(w http.ResponseWriter, message []byte) {
 }
 
 func writeErrorMsg(w http.ResponseWriter, message string, statusCode int) {
-  m := ErrorMessage{message}
+  m := ErrorMessage{Error: message}
   jsonMessage, err := json.Marshal(m)
   if err != nil {
-    jsonMessage = []byte("\"Error\": \"\"}")
+    jsonMessage = []byte("{\"Error\": \"\"}")
   }
+  setErrorReason(w, message) // show up in the /stats error breakdown
   w.Header().Set("Content-Type", "application/json")
   w.WriteHeader(statusCode)
   w.Write(jsonMessage)
 }
 
+// answers an OPTIONS request with the methods the resource supports
+func writeOptions(w http.ResponseWriter, allowed []string) {
+  w.Header().Set("Allow", strings.Join(allowed, ", "))
+  w.WriteHeader(http.StatusNoContent)
+}
+
+// 405 with an accurate Allow header so clients and gateways know what to retry with
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed []string) {
+  w.Header().Set("Allow", strings.Join(allowed, ", "))
+  writeError(w, r, NewError(CodeMethodNotAllowed, r.Method + " is not supported"))
+}
+
+// parses the urlencoded form, refusing bodies bigger than the policy allows. writes the error and returns false on failure
+func parseForm(w http.ResponseWriter, r *http.Request, policy *PasswordPolicy) bool {
+  if policy.MaxBodyBytes > 0 {
+    r.Body = http.MaxBytesReader(w, r.Body, policy.MaxBodyBytes)
+  }
+  if err := r.ParseForm(); err != nil {
+    var tooLarge *http.MaxBytesError
+    if errors.As(err, &tooLarge) {
+      writeError(w, r, NewError(CodeBodyTooLarge, fmt.Sprintf("Request body is larger than %d bytes", tooLarge.Limit)))
+    } else {
+      writeError(w, r, NewError(CodeInvalidForm, "Could not parse form data"))
+    }
+    return false
+  }
+  return true
+}
+
+// the duration form parameter name, zero when it's missing. negative or malformed durations are refused
+func durationParam(r *http.Request, name string) (time.Duration, *APIError) {
+  v := r.Form.Get(name)
+  if v == "" {
+    return 0, nil
+  }
+  d, err := time.ParseDuration(v)
+  if err != nil || d < 0 {
+    return 0, NewError(CodeInvalidParameter, name + " must be a duration such as 30s or 5m")
+  }
+  return d, nil
+}
+
+// like strconv.ParseBool but an empty value is false
+func parseBool(s string) (bool, error) {
+  if s == "" {
+    return false, nil
+  }
+  return strconv.ParseBool(s)
+}
+
+// writes an APIError from hashing. a full queue tells the caller when to retry
+func writeHashError(w http.ResponseWriter, r *http.Request, apiErr *APIError) {
+  if apiErr.Code == CodeQueueFull {
+    w.Header().Set("Retry-After", "1")
+  }
+  writeError(w, r, apiErr)
+}
+
 func generate_hash(s string) string {
     sha_512 := sha512.New()
     sha_512.Write([]byte(s))
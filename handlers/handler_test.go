@@ -4,37 +4,63 @@ import (
   "testing"
   "net/http"
   "net/http/httptest"
+  "net/url"
   "io/ioutil"
   "strings"
   "bytes"
   "encoding/json"
   "math"
   "fmt"
+  "sync"
   "time"
+  "github.com/rdibari84/GoHTTP/handlers/openapi/openapitest"
 )
 
-/**
- FIXME: The Stats Unit Tests need to run first.
- The calls that the other tests make cause these tests to fail
-*/
+// every test gets its own Service (see newTestService) so they can run in parallel
 //////////////////////////////////////////////
 ////////// Stats Unit Tests //////////////////
 //////////////////////////////////////////////
 func TestPostStatsEndpointFails(t *testing.T) {
-  ts := runStatsEndpoint()
+  t.Parallel()
+  ts := runStatsEndpoint(t, newTestService(t))
   defer ts.Close()
   // Build the request
 	resp, err :=  http.Post(ts.URL + "/stats", "application/x-www-form-urlencoded", strings.NewReader("somestring"))
   if err != nil {
 		t.Errorf("Expected no error. Error: %s", err)
 	}
-  if resp.StatusCode != 404 {
-    t.Errorf("Expected 404 error code. Got %d", resp.StatusCode)
+  if resp.StatusCode != 405 {
+    t.Errorf("Expected 405 error code. Got %d", resp.StatusCode)
+  }
+  if resp.Header.Get("Allow") != "GET, HEAD, OPTIONS" {
+    t.Errorf("Expected Allow: GET, HEAD, OPTIONS. Got %s", resp.Header.Get("Allow"))
+  }
+}
+
+func TestHeadStatsEndpointSucceeds(t *testing.T) {
+  t.Parallel()
+  ts := runStatsEndpoint(t, newTestService(t))
+  defer ts.Close()
+	resp, err :=  http.Head(ts.URL + "/stats")
+  if err != nil {
+		t.Errorf("Expected no error. Error: %s", err)
+	}
+  if resp.StatusCode != 200 {
+    t.Errorf("Expected 200 code. Got %d", resp.StatusCode)
+  }
+  if resp.Header.Get("Content-Type") != "application/json" {
+    t.Errorf("Expected application/json content type. Got %s", resp.Header.Get("Content-Type"))
+  }
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  if len(body) != 0 {
+    t.Errorf("Expected an empty body for HEAD. Got %s", body)
   }
 }
 
 func TestGetStatsEndpointSucceeds(t *testing.T) {
-  ts := runStatsEndpoint()
+  t.Parallel()
+  ts := runStatsEndpoint(t, newTestService(t))
   defer ts.Close()
 
   ch := make(chan []byte)
@@ -59,8 +85,10 @@ func TestGetStatsEndpointSucceeds(t *testing.T) {
   }
 }
 func TestGetStatsEndpointSucceedsOneHashCall(t *testing.T) {
+  t.Parallel()
+  service := newTestService(t)
   // start Hash Endpoint
-  ts := runHashEndpoint()
+  ts := runHashEndpoint(t, service)
   defer ts.Close()
 
   // make hash call
@@ -72,7 +100,7 @@ func TestGetStatsEndpointSucceedsOneHashCall(t *testing.T) {
   for true {
     if (<-ch != nil) {
       // start stats endpoint
-      tsStats := runStatsEndpoint()
+      tsStats := runStatsEndpoint(t, service)
       defer tsStats.Close()
 
       // Make stats request
@@ -107,7 +135,8 @@ func TestGetStatsEndpointSucceedsOneHashCall(t *testing.T) {
 //////////////////////////////////////////////
 
 func TestPostHashEndpointSucceeds(t *testing.T) {
-  ts := runHashEndpoint()
+  t.Parallel()
+  ts := runHashEndpoint(t, newTestService(t))
   defer ts.Close()
 
   ch := make(chan []byte)
@@ -120,7 +149,8 @@ func TestPostHashEndpointSucceeds(t *testing.T) {
 }
 
 func TestPostHashEndpointNoFormFails(t *testing.T) {
-  ts := runHashEndpoint()
+  t.Parallel()
+  ts := runHashEndpoint(t, newTestService(t))
   defer ts.Close()
 
   resp, err1 :=  http.Post(ts.URL + "/hash", "application/x-www-form-urlencoded", strings.NewReader(""))
@@ -133,7 +163,8 @@ func TestPostHashEndpointNoFormFails(t *testing.T) {
 }
 
 func TestPostHashEndpointBadFormFails(t *testing.T) {
-  ts := runHashEndpoint()
+  t.Parallel()
+  ts := runHashEndpoint(t, newTestService(t))
   defer ts.Close()
 
   resp, err1 :=  http.Post(ts.URL + "/hash", "application/x-www-form-urlencoded", strings.NewReader("badform"))
@@ -146,7 +177,8 @@ func TestPostHashEndpointBadFormFails(t *testing.T) {
 }
 
 func TestMultiplePostsHashEndpointSucceeds(t *testing.T) {
-  ts := runHashEndpoint()
+  t.Parallel()
+  ts := runHashEndpoint(t, newTestService(t))
   defer ts.Close()
 
   // Make 10 requests
@@ -164,16 +196,85 @@ func TestMultiplePostsHashEndpointSucceeds(t *testing.T) {
   }
 }
 
-func TestGetHashEndpointFails(t *testing.T) {
-  ts := runHashEndpoint()
+func TestPutHashEndpointFails(t *testing.T) {
+  t.Parallel()
+  ts := runHashEndpoint(t, newTestService(t))
   defer ts.Close()
   // Build the request
-	resp, err :=  http.Get(ts.URL + "/hash")
+  req, _ := http.NewRequest("PUT", ts.URL + "/hash", nil)
+	resp, err :=  http.DefaultClient.Do(req)
   if err != nil {
 		t.Errorf("Expected no error. Error: %s", err)
 	}
-  if resp.StatusCode != 404 {
-    t.Errorf("Expected 200 error code. Got %d", resp.StatusCode)
+  if resp.StatusCode != 405 {
+    t.Errorf("Expected 405 error code. Got %d", resp.StatusCode)
+  }
+  if resp.Header.Get("Allow") != "GET, HEAD, POST, OPTIONS" {
+    t.Errorf("Expected Allow: GET, HEAD, POST, OPTIONS. Got %s", resp.Header.Get("Allow"))
+  }
+}
+
+func TestOptionsHashEndpointListsMethods(t *testing.T) {
+  t.Parallel()
+  ts := runHashEndpoint(t, newTestService(t))
+  defer ts.Close()
+  req, _ := http.NewRequest("OPTIONS", ts.URL + "/hash", nil)
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+		t.Errorf("Expected no error. Error: %s", err)
+	}
+  if resp.StatusCode != 204 {
+    t.Errorf("Expected 204 code. Got %d", resp.StatusCode)
+  }
+  if resp.Header.Get("Allow") != "GET, HEAD, POST, OPTIONS" {
+    t.Errorf("Expected Allow: GET, HEAD, POST, OPTIONS. Got %s", resp.Header.Get("Allow"))
+  }
+}
+
+//////////////////////////////////////////////
+/////// Verify Endpoint Unit Tests ///////////
+//////////////////////////////////////////////
+
+func TestPostVerifyEndpointMatches(t *testing.T) {
+  t.Parallel()
+  ts := httptest.NewServer(openapitest.Check(t, &VerifyHandler{Service: newTestService(t)}))
+  defer ts.Close()
+
+  form := url.Values{"password": {"angryMonkey"}, "hash": {"ZEHhWB65gUlzdVwtDQArEyx+KVLzp/aTaRaPlBzYRIFj6vjFdqEb0Q5B8zVKCZ0vKbZPZklJz0Fd7su2A+gf7Q=="}}
+  if !makeVerifyRequest(t, ts, form) {
+    t.Errorf("Expected angryMonkey to match its hash")
+  }
+  form.Set("password", "happyMonkey")
+  if makeVerifyRequest(t, ts, form) {
+    t.Errorf("Expected happyMonkey not to match")
+  }
+}
+
+func TestPostVerifyEndpointMissingHashFails(t *testing.T) {
+  t.Parallel()
+  ts := httptest.NewServer(openapitest.Check(t, &VerifyHandler{Service: newTestService(t)}))
+  defer ts.Close()
+
+  resp, err := http.Post(ts.URL + "/verify", "application/x-www-form-urlencoded", strings.NewReader("password=angryMonkey"))
+  if err != nil {
+		t.Errorf("Expected no error. Error: %s", err)
+	}
+  if resp.StatusCode != 400 {
+    t.Errorf("Expected 400 error code. Got %d", resp.StatusCode)
+  }
+}
+
+func TestGetVerifyEndpointFails(t *testing.T) {
+  t.Parallel()
+  ts := httptest.NewServer(openapitest.Check(t, &VerifyHandler{Service: newTestService(t)}))
+  defer ts.Close()
+
+  resp, err := http.Get(ts.URL + "/verify")
+  if err != nil {
+		t.Errorf("Expected no error. Error: %s", err)
+	}
+  if resp.StatusCode != 405 {
+    t.Errorf("Expected 405 error code. Got %d", resp.StatusCode)
   }
 }
 
@@ -181,27 +282,76 @@ func TestGetHashEndpointFails(t *testing.T) {
 ////////// Hash Shutdown Unit Tests /////////////
 //////////////////////////////////////////////
 
-func TestPostShutdownEndpointFails(t *testing.T) {
-  ts := runShutdownEndpoint()
+func TestPutShutdownEndpointFails(t *testing.T) {
+  t.Parallel()
+  ts := runShutdownEndpoint(t, newTestService(t))
   defer ts.Close()
   // Build the request
-	resp, err :=  http.Post(ts.URL + "/shutdown", "application/x-www-form-urlencoded", strings.NewReader("somestring"))
+  req, _ := http.NewRequest("PUT", ts.URL + "/shutdown", strings.NewReader("somestring"))
+	resp, err :=  http.DefaultClient.Do(req)
   if err != nil {
-		t.Errorf("Expected no error. Error: %s", err)
+		t.Fatalf("Expected no error. Error: %s", err)
 	}
-  if resp.StatusCode != 404 {
-    t.Errorf("Expected 404 error code. Got %d", resp.StatusCode)
+  resp.Body.Close()
+  if resp.StatusCode != 405 {
+    t.Errorf("Expected 405 error code. Got %d", resp.StatusCode)
+  }
+  if resp.Header.Get("Allow") != "GET, HEAD, POST, DELETE, OPTIONS" {
+    t.Errorf("Expected Allow: GET, HEAD, POST, DELETE, OPTIONS. Got %s", resp.Header.Get("Allow"))
+  }
+}
+
+func TestGetShutdownEndpointShowsStatus(t *testing.T) {
+  t.Parallel()
+  ts := runShutdownEndpoint(t, newTestService(t))
+  defer ts.Close()
+  // GET and HEAD must not trigger a shutdown
+  for _, method := range []string{"HEAD", "GET"} {
+    req, _ := http.NewRequest(method, ts.URL + "/shutdown", nil)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+      t.Fatalf("Expected no error. Error: %s", err)
+    }
+    body, _ := ioutil.ReadAll(resp.Body)
+    resp.Body.Close()
+    if resp.StatusCode != 200 {
+      t.Errorf("Expected 200 code for %s. Got %d", method, resp.StatusCode)
+    }
+    status := ShutdownStatus{}
+    if method == "GET" && (json.Unmarshal(body, &status) != nil || status.State != ShutdownRunning || !status.At.IsZero()) {
+      t.Errorf("Expected the server to be running. Got %s", body)
+    }
   }
 }
 
-func TestGetShutdownEndpointSucceeds(t *testing.T) {
-  ts := runShutdownEndpoint()
+func TestPostShutdownEndpointSucceeds(t *testing.T) {
+  t.Parallel()
+  ts := runShutdownEndpoint(t, newTestService(t))
+  defer ts.Close()
 
-  // expecting an error
   MakeShutdownRequest(t, ts)
 
-  // call it again. expecting an error
-  MakeShutdownRequest(t, ts)
+  // call it again. expecting an error since the server is gone
+  if _, err := http.PostForm(ts.URL + "/shutdown", nil); err == nil {
+    t.Errorf("Expected an error since the server has shut down.")
+  }
+}
+
+func TestShutdownEndpointRefusesBadDurations(t *testing.T) {
+  t.Parallel()
+  ts := runShutdownEndpoint(t, newTestService(t))
+  defer ts.Close()
+
+  for _, form := range []url.Values{{"after": {"soon"}}, {"deadline": {"-1m"}}} {
+    resp, err := http.PostForm(ts.URL + "/shutdown", form)
+    if err != nil {
+      t.Fatalf("Expected no error. Error: %s", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 400 {
+      t.Errorf("Expected 400 code for %v. Got %d", form, resp.StatusCode)
+    }
+  }
 }
 
 //////////////////////////////////////////////
@@ -217,7 +367,7 @@ func TestGenHash(t *testing.T) {
 
 func TestAddSummedResponseTimeAppends1ElementCorrectly(t *testing.T) {
   // add repsonseTime
-  summedHashResponseTimes = generateSummedHashResponseTimes([]string{"1000ns"})
+  summedHashResponseTimes := generateSummedHashResponseTimes([]string{"1000ns"})
 
   // assert responseTimes slice is size 1
   if len(summedHashResponseTimes) != 1 {
@@ -231,7 +381,7 @@ func TestAddSummedResponseTimeAppends1ElementCorrectly(t *testing.T) {
 
 func TestAddSummedResponseTimeAppends2ElementCorrectly(t *testing.T) {
   // add 2 repsonseTimes
-  summedHashResponseTimes = generateSummedHashResponseTimes([]string{"1000ns", "1000ns"})
+  summedHashResponseTimes := generateSummedHashResponseTimes([]string{"1000ns", "1000ns"})
 
   // assert responseTimes slice is size 2
   if len(summedHashResponseTimes) != 2 {
@@ -257,7 +407,7 @@ func TestCalcAverageResponseTimeNoTimes(t *testing.T) {
 
 func TestCalcAverageResponseTimeIsCorrectSize1(t *testing.T) {
   // initalize variables
-  summedHashResponseTimes = generateSummedHashResponseTimes([]string{"1000ns"})
+  summedHashResponseTimes := generateSummedHashResponseTimes([]string{"1000ns"})
 
   // now test
   avg := calcAverageResponseTime(summedHashResponseTimes)
@@ -267,7 +417,7 @@ func TestCalcAverageResponseTimeIsCorrectSize1(t *testing.T) {
 }
 
 func TestCalcAverageResponseTimeIsCorrectSize2(t *testing.T) {
-  summedHashResponseTimes = generateSummedHashResponseTimes([]string{"1000ns", "2000ns"})
+  summedHashResponseTimes := generateSummedHashResponseTimes([]string{"1000ns", "2000ns"})
 
   // now test
   avg := calcAverageResponseTime(summedHashResponseTimes)
@@ -277,7 +427,7 @@ func TestCalcAverageResponseTimeIsCorrectSize2(t *testing.T) {
 }
 
 func TestCalcAverageResponseTimeIsCorrectSizeMulti(t *testing.T) {
-  summedHashResponseTimes = generateSummedHashResponseTimes([]string{"1000ns", "2000ns", "3000ns", "4000ns"})
+  summedHashResponseTimes := generateSummedHashResponseTimes([]string{"1000ns", "2000ns", "3000ns", "4000ns"})
 
   if summedHashResponseTimes[3] != 10 {
     t.Errorf("Expected last summedHashResponseTimes value to be 10. got %d", summedHashResponseTimes[3])
@@ -355,21 +505,52 @@ func generateSummedHashResponseTimes(timeDurrations []string) []time.Duration{
 /////////////// Helper Methods ///////////////
 //////////////////////////////////////////////
 
-func runHashEndpoint() *httptest.Server {
-  handler := &HashHandler{}
-  ts := httptest.NewServer(handler)
+// a Service of its own with the usual 5 second delay, on a clock where waiting takes no real time
+func newTestService(t *testing.T) *Service {
+  pool := NewHashPool(2, 64)
+  t.Cleanup(pool.Close)
+  return NewService(Config{Pool: pool, Clock: &instantClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}})
+}
+
+// a Clock where After fires straight away and moves the clock forward, so a 5 second delay takes exactly 5 seconds of clock time
+type instantClock struct {
+  mu sync.Mutex
+  now time.Time
+}
+
+func (c *instantClock) Now() time.Time {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  return c.now
+}
+
+func (c *instantClock) After(d time.Duration) <-chan time.Time {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  c.now = c.now.Add(d)
+  ch := make(chan time.Time, 1)
+  ch <- c.now
+  return ch
+}
+
+// the endpoints check every response against the OpenAPI document
+func runHashEndpoint(t *testing.T, s *Service) *httptest.Server {
+  handler := &HashHandler{Service: s}
+  ts := httptest.NewServer(openapitest.Check(t, handler))
   return ts
 }
 
-func runStatsEndpoint() *httptest.Server {
-  statshandler := &StatsHandler{}
-  ts := httptest.NewServer(statshandler)
+func runStatsEndpoint(t *testing.T, s *Service) *httptest.Server {
+  statshandler := &StatsHandler{Service: s}
+  ts := httptest.NewServer(openapitest.Check(t, statshandler))
   return ts
 }
 
-func runShutdownEndpoint() *httptest.Server {
-  shutdownhandlder := &ShutdownHandler{}
-  ts := httptest.NewServer(shutdownhandlder)
+func runShutdownEndpoint(t *testing.T, s *Service) *httptest.Server {
+  ts := httptest.NewUnstartedServer(nil)
+  shutdownhandlder := &ShutdownHandler{Srv: ts.Config, Service: s}
+  ts.Config.Handler = openapitest.Check(t, shutdownhandlder)
+  ts.Start()
   return ts
 }
 
@@ -416,10 +597,39 @@ func MakeStatsRequest(t *testing.T, ts *httptest.Server, ch chan<-[]byte) {
   ch <- greeting
 }
 
+func makeVerifyRequest(t *testing.T, ts *httptest.Server, form url.Values) bool {
+  resp, err := http.PostForm(ts.URL + "/verify", form)
+  if err != nil {
+		t.Fatalf("Expected no error. Error: %s", err)
+	}
+  if resp.StatusCode != 200 {
+    t.Errorf("Expected 200 code. Got %d", resp.StatusCode)
+  }
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  v := Verification{}
+  json.Unmarshal(body, &v)
+  return v.Match
+}
+
 func MakeShutdownRequest(t *testing.T, ts *httptest.Server) {
   // Build the request
-	_, err :=  http.Get(ts.URL + "/shutdown")
-  if err == nil {
-		t.Errorf("Expected an error since this endpoint shuts down the server.")
+	resp, err :=  http.PostForm(ts.URL + "/shutdown", nil)
+  if err != nil {
+		t.Fatalf("Expected no error. Error: %s", err)
 	}
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  status := ShutdownStatus{}
+  if resp.StatusCode != 202 || json.Unmarshal(body, &status) != nil || status.State != ShutdownDraining {
+    t.Errorf("Expected 202 and a draining server. Got %d %s", resp.StatusCode, body)
+  }
+  // the server shuts down once the drain is done
+  for i := 0; i < 100; i++ {
+    if _, err := http.Get(ts.URL + "/shutdown"); err != nil {
+      return
+    }
+    time.Sleep(10 * time.Millisecond)
+  }
+  t.Errorf("Expected an error since this endpoint shuts down the server.")
 }
@@ -0,0 +1,141 @@
+// Package handlerstest starts isolated gohttp servers for tests. Each server has its own
+// Service, hashing pool and fake clock, so tests don't share stats and can run in parallel
+package handlerstest
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "testing"
+    "time"
+    "github.com/rdibari84/GoHTTP/handlers"
+    "github.com/rdibari84/GoHTTP/handlers/openapi/openapitest"
+)
+
+//////////////////////////////////////////////
+//////////////// Test Servers ////////////////
+//////////////////////////////////////////////
+
+// Server is a running test server and the Service behind it
+type Server struct {
+  *httptest.Server
+  Admin *httptest.Server // the admin routes, for servers started with NewSplitServer
+  Service *handlers.Service
+  Clock *FakeClock // nil when the test passed its own Clock
+}
+
+// NewServer serves every endpoint except /shutdown the same way the rest server does.
+// unless cfg says otherwise there is no hashing delay, time comes from a FakeClock and hashing gets its own small pool.
+// every response is checked against the OpenAPI document and the server is closed when the test finishes
+func NewServer(t testing.TB, cfg handlers.Config) *Server {
+  s := newServer(t, cfg)
+  s.Server = start(t, s.Service.Routes(nil))
+  return s
+}
+
+// NewSplitServer serves the routes the way the rest server does with -admin-addr: hashing and verification on URL,
+// and stats, config and key management on Admin.URL
+func NewSplitServer(t testing.TB, cfg handlers.Config) *Server {
+  s := newServer(t, cfg)
+  s.Server = start(t, s.Service.PublicRoutes())
+  s.Admin = start(t, s.Service.AdminRoutes(nil))
+  return s
+}
+
+//////////////////////////////////////////////
+////////////////// Fake Time /////////////////
+//////////////////////////////////////////////
+
+// FakeClock is a handlers.Clock that only moves when Advance is called
+type FakeClock struct {
+  mu sync.Mutex
+  now time.Time
+  sleepers []sleeper
+}
+
+// someone waiting on After
+type sleeper struct {
+  until time.Time
+  ch chan time.Time
+}
+
+// NewFakeClock starts at midnight, January 1st 2020 UTC
+func NewFakeClock() *FakeClock {
+  return &FakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *FakeClock) Now() time.Time {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  ch := make(chan time.Time, 1)
+  if d <= 0 {
+    ch <- c.now
+    return ch
+  }
+  c.sleepers = append(c.sleepers, sleeper{until: c.now.Add(d), ch: ch})
+  return ch
+}
+
+// Advance moves the clock forward, waking anything sleeping until then
+func (c *FakeClock) Advance(d time.Duration) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  c.now = c.now.Add(d)
+  waiting := c.sleepers[:0]
+  for _, s := range c.sleepers {
+    if s.until.After(c.now) {
+      waiting = append(waiting, s)
+    } else {
+      s.ch <- c.now
+    }
+  }
+  c.sleepers = waiting
+}
+
+// WaitForSleepers blocks until at least n callers are waiting on After, so a following Advance wakes them
+func (c *FakeClock) WaitForSleepers(n int) {
+  for {
+    c.mu.Lock()
+    count := len(c.sleepers)
+    c.mu.Unlock()
+    if count >= n {
+      return
+    }
+    time.Sleep(time.Millisecond)
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// a Service with the test defaults filled in
+func newServer(t testing.TB, cfg handlers.Config) *Server {
+  s := &Server{}
+  if cfg.Clock == nil {
+    s.Clock = NewFakeClock()
+    cfg.Clock = s.Clock
+  }
+  if cfg.Delay == nil {
+    cfg.Delay = handlers.NoDelay{}
+  }
+  if cfg.Pool == nil {
+    cfg.Pool = handlers.NewHashPool(2, 64)
+    t.Cleanup(cfg.Pool.Close)
+  }
+  s.Service = handlers.NewService(cfg)
+  return s
+}
+
+// serves routes, checking every response against the OpenAPI document, until the test finishes
+func start(t testing.TB, routes http.Handler) *httptest.Server {
+  ts := httptest.NewServer(openapitest.Check(t, handlers.RequestID(routes)))
+  t.Cleanup(ts.Close)
+  return ts
+}
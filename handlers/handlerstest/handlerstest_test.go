@@ -0,0 +1,41 @@
+package handlerstest
+
+import (
+  "testing"
+  "net/http"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers"
+)
+
+func TestFakeClockOnlyMovesOnAdvance(t *testing.T) {
+  c := NewFakeClock()
+  start := c.Now()
+  ch := c.After(time.Second)
+
+  c.Advance(999 * time.Millisecond)
+  select {
+    case <-ch:
+      t.Fatalf("Expected After to wait the whole second")
+    default:
+  }
+  c.Advance(time.Millisecond)
+  if fired := <-ch; !fired.Equal(start.Add(time.Second)) {
+    t.Errorf("Expected After to fire at %v. got %v", start.Add(time.Second), fired)
+  }
+  if !c.Now().Equal(start.Add(time.Second)) {
+    t.Errorf("Expected the clock to have moved 1s. got %v", c.Now().Sub(start))
+  }
+}
+
+func TestNewServerLeavesOutShutdown(t *testing.T) {
+  t.Parallel()
+  srv := NewServer(t, handlers.Config{})
+  resp, err := http.Get(srv.URL + "/shutdown")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != 404 {
+    t.Errorf("Expected 404 since test servers can't be shut down. got %d", resp.StatusCode)
+  }
+}
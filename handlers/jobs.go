@@ -0,0 +1,308 @@
+package handlers
+
+import (
+    "context"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+//////////////////////////////////////////////
+/////////////// Async Hash Jobs //////////////
+//////////////////////////////////////////////
+
+// job states
+const (
+  JobPending = "pending"
+  JobDone = "done"
+  JobFailed = "failed"
+  JobCancelled = "cancelled"
+)
+
+// how long finished jobs can be fetched from GET /hash/{id}
+var JobRetention = time.Hour
+
+// how many jobs GET /hash lists unless the caller asks for a limit, and the most it will list at once
+var (
+  DefaultJobListLimit = 100
+  MaxJobListLimit = 1000
+)
+
+// Job is an asynchronous hash request. returned by POST /hash with async=true and GET /hash/{id}.
+// the password is never kept in it
+type Job struct {
+  ID string
+  Status string // pending, done, failed or cancelled
+  Algorithm string // the hash the job computes, e.g. sha512
+  Hash string `json:",omitempty"` // set once Status is done
+  Error string `json:",omitempty"` // set once Status is failed
+  Created time.Time
+  Started time.Time `json:",omitzero"` // when the delay was over and hashing began
+  Finished time.Time `json:",omitzero"` // when it was done, failed or cancelled
+  Duration float64 `json:",omitempty"` // from Created to Finished, in microseconds
+  Callback *Callback `json:",omitempty"` // delivery of the finished job to the callback_url, if one was given
+
+  owner string // id of the API key that started it, so only that key can see it
+}
+
+// JobList is a page of jobs from GET /hash, newest first
+type JobList struct {
+  Jobs []Job
+  Next string `json:",omitempty"` // pass as cursor to get the next page; empty on the last page
+}
+
+// returned by JobStore.Cancel
+var (
+  ErrJobNotFound = errors.New("no job with this id")
+  ErrJobFinished = errors.New("the job has already finished")
+)
+
+// JobStore keeps async jobs in memory until they've been finished for JobRetention
+type JobStore struct {
+  mu sync.Mutex
+  clock Clock
+  jobs map[string]*Job
+  done map[string]chan struct{} // closed when the pending job with that id finishes
+  cancels map[string]context.CancelFunc // stops the pending job with that id
+}
+
+// NewJobStore timestamps jobs with clock; RealClock when nil
+func NewJobStore(clock Clock) *JobStore {
+  if clock == nil {
+    clock = RealClock
+  }
+  return &JobStore{clock: clock, jobs: make(map[string]*Job), done: make(map[string]chan struct{}), cancels: make(map[string]context.CancelFunc)}
+}
+
+// Create adds a new pending job and returns a copy of it
+func (s *JobStore) Create() Job {
+  return s.CreateWithCallback("")
+}
+
+// CreateWithCallback adds a new pending job whose result will be delivered to callbackURL, if it is set
+func (s *JobStore) CreateWithCallback(callbackURL string) Job {
+  return s.CreateFor("", callbackURL, nil)
+}
+
+// CreateFor adds a new pending job started with the API key owner ("" without keys), whose result will be delivered to
+// callbackURL if it is set. cancel is called should the job be cancelled while it's pending
+func (s *JobStore) CreateFor(owner string, callbackURL string, cancel context.CancelFunc) Job {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  s.prune()
+  job := &Job{ID: newRequestID(), Status: JobPending, Algorithm: algorithmSHA512, Created: s.clock.Now(), owner: owner}
+  if cancel != nil {
+    s.cancels[job.ID] = cancel
+  }
+  if callbackURL != "" {
+    job.Callback = &Callback{URL: callbackURL, Status: CallbackPending}
+  }
+  s.jobs[job.ID] = job
+  s.done[job.ID] = make(chan struct{})
+  return copyJob(job)
+}
+
+// Start records that the pending job's hashing has begun
+func (s *JobStore) Start(id string) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  if job, ok := s.jobs[id]; ok && job.Status == JobPending && job.Started.IsZero() {
+    job.Started = s.clock.Now()
+  }
+}
+
+// Finish records the outcome of a job. err is nil on success. a job that was cancelled stays cancelled
+func (s *JobStore) Finish(id string, hash string, err error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  job, ok := s.jobs[id]
+  if !ok || job.Status != JobPending {
+    return
+  }
+  if done := s.finish(job); done != nil {
+    defer close(done)
+  }
+  if err != nil {
+    job.Status = JobFailed
+    job.Error = err.Error()
+  } else {
+    job.Status = JobDone
+    job.Hash = hash
+  }
+}
+
+// Cancel stops a pending job and returns a copy of it. ErrJobNotFound if there's no such job, ErrJobFinished if it's
+// no longer pending
+func (s *JobStore) Cancel(id string) (Job, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  job, ok := s.jobs[id]
+  if !ok {
+    return Job{}, ErrJobNotFound
+  }
+  if job.Status != JobPending {
+    return copyJob(job), ErrJobFinished
+  }
+  if cancel, ok := s.cancels[id]; ok {
+    cancel()
+  }
+  if done := s.finish(job); done != nil {
+    defer close(done)
+  }
+  job.Status = JobCancelled
+  return copyJob(job), nil
+}
+
+// List returns up to limit of owner's jobs, newest first, starting after cursor (a JobList.Next) when it is set.
+// status picks pending, done, failed or cancelled jobs; every job when it's empty
+func (s *JobStore) List(owner string, status string, cursor string, limit int) (JobList, error) {
+  after, afterID, err := parseJobCursor(cursor)
+  if err != nil {
+    return JobList{}, err
+  }
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  s.prune()
+  var matches []*Job
+  for _, job := range s.jobs {
+    if job.owner == owner && (status == "" || job.Status == status) {
+      matches = append(matches, job)
+    }
+  }
+  sort.Slice(matches, func(i, j int) bool { return newerJob(matches[i], matches[j]) })
+
+  list := JobList{Jobs: []Job{}}
+  for _, job := range matches {
+    if cursor != "" && !newerJob(&Job{Created: after, ID: afterID}, job) {
+      continue
+    }
+    if len(list.Jobs) == limit {
+      last := list.Jobs[len(list.Jobs) - 1]
+      list.Next = formatJobCursor(last.Created, last.ID)
+      break
+    }
+    list.Jobs = append(list.Jobs, copyJob(job))
+  }
+  return list, nil
+}
+
+// Get returns a copy of the job with the given id
+func (s *JobStore) Get(id string) (Job, bool) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  job, ok := s.jobs[id]
+  if !ok {
+    return Job{}, false
+  }
+  return copyJob(job), true
+}
+
+// Pending counts the jobs that haven't finished
+func (s *JobStore) Pending() int {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  return len(s.done)
+}
+
+// RecordCallback adds a delivery attempt to the job's callback and sets its status
+func (s *JobStore) RecordCallback(id string, attempt CallbackAttempt, status string) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  job, ok := s.jobs[id]
+  if !ok || job.Callback == nil {
+    return
+  }
+  job.Callback.Attempts = append(job.Callback.Attempts, attempt)
+  job.Callback.Status = status
+}
+
+// Done returns a channel that is closed once the job has finished. it is already closed for finished or unknown jobs
+func (s *JobStore) Done(id string) <-chan struct{} {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  if done, ok := s.done[id]; ok {
+    return done
+  }
+  closed := make(chan struct{})
+  close(closed)
+  return closed
+}
+
+// marks the job as no longer pending. returns the channel to close once the job is updated, so anyone waiting
+// on it sees the result. caller holds the lock
+func (s *JobStore) finish(job *Job) chan struct{} {
+  done := s.done[job.ID]
+  delete(s.done, job.ID)
+  delete(s.cancels, job.ID)
+  job.Finished = s.clock.Now()
+  job.Duration = microseconds(job.Finished.Sub(job.Created))
+  return done
+}
+
+// drop finished jobs older than JobRetention. caller holds the lock
+func (s *JobStore) prune() {
+  cutoff := s.clock.Now().Add(-JobRetention)
+  for id, job := range s.jobs {
+    if job.Status != JobPending && job.Finished.Before(cutoff) {
+      delete(s.jobs, id)
+    }
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// a copy of job that shares nothing with the store. caller holds the lock
+func copyJob(job *Job) Job {
+  c := *job
+  if job.Callback != nil {
+    callback := *job.Callback
+    callback.Attempts = append([]CallbackAttempt(nil), job.Callback.Attempts...)
+    c.Callback = &callback
+  }
+  return c
+}
+
+// the order jobs are listed in: newest first, ties broken by id so pages never overlap
+func newerJob(a *Job, b *Job) bool {
+  if !a.Created.Equal(b.Created) {
+    return a.Created.After(b.Created)
+  }
+  return a.ID > b.ID
+}
+
+// the position after the job created at created with id id, as an opaque string
+func formatJobCursor(created time.Time, id string) string {
+  return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(created.UnixNano(), 10) + "." + id))
+}
+
+func parseJobCursor(cursor string) (time.Time, string, error) {
+  if cursor == "" {
+    return time.Time{}, "", nil
+  }
+  decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+  if err != nil {
+    return time.Time{}, "", fmt.Errorf("cursor %q isn't one GET /hash handed out", cursor)
+  }
+  nanos, id, ok := strings.Cut(string(decoded), ".")
+  created, err := strconv.ParseInt(nanos, 10, 64)
+  if !ok || err != nil || id == "" {
+    return time.Time{}, "", fmt.Errorf("cursor %q isn't one GET /hash handed out", cursor)
+  }
+  return time.Unix(0, created), id, nil
+}
+
+// answers with the job as json. async POST /hash points Location at where to fetch it, under the same version
+func writeJob(w http.ResponseWriter, r *http.Request, status int, job Job) {
+  if status == http.StatusAccepted {
+    w.Header().Set("Location", treeOf(r).base + "/hash/" + job.ID)
+  }
+  writeJSON(w, r, status, job)
+}
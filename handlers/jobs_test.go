@@ -0,0 +1,176 @@
+package handlers
+
+import (
+  "testing"
+  "net/http"
+  "net/http/httptest"
+  "io/ioutil"
+  "strings"
+  "encoding/json"
+  "errors"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers/openapi/openapitest"
+)
+
+//////////////////////////////////////////////
+///////////// Hash Job Unit Tests ////////////
+//////////////////////////////////////////////
+
+func TestJobStoreLifecycle(t *testing.T) {
+  s := NewJobStore(nil)
+  job := s.Create()
+  if job.ID == "" || job.Status != JobPending {
+    t.Fatalf("Expected a pending job with an id. got %+v", job)
+  }
+
+  s.Finish(job.ID, "somehash", nil)
+  done, ok := s.Get(job.ID)
+  if !ok || done.Status != JobDone || done.Hash != "somehash" || done.Finished.IsZero() {
+    t.Errorf("Expected a done job. got %+v", done)
+  }
+
+  failed := s.Create()
+  s.Finish(failed.ID, "", errors.New("boom"))
+  if job, _ := s.Get(failed.ID); job.Status != JobFailed || job.Error != "boom" {
+    t.Errorf("Expected a failed job. got %+v", job)
+  }
+}
+
+func TestJobStorePrunesOldJobs(t *testing.T) {
+  s := NewJobStore(nil)
+  old := s.Create()
+  s.Finish(old.ID, "somehash", nil)
+  pending := s.Create()
+  s.jobs[old.ID].Finished = time.Now().Add(-2 * JobRetention)
+
+  s.Create() // prunes
+  if _, ok := s.Get(old.ID); ok {
+    t.Errorf("Expected expired job to be pruned")
+  }
+  if _, ok := s.Get(pending.ID); !ok {
+    t.Errorf("Expected pending job to be kept")
+  }
+}
+
+func TestAsyncHashReturnsJob(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, &HashHandler{Service: newTestService(t)}))
+  defer ts.Close()
+
+  resp, err := http.Post(ts.URL + "/hash", "application/x-www-form-urlencoded", strings.NewReader("password=angryMonkey&async=true"))
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  if resp.StatusCode != 202 {
+    t.Errorf("Expected 202 code. Got %d", resp.StatusCode)
+  }
+  job := Job{}
+  json.Unmarshal(body, &job)
+  if job.Status != JobPending || resp.Header.Get("Location") != "/hash/" + job.ID {
+    t.Errorf("Expected a pending job and its Location. got %+v %s", job, resp.Header.Get("Location"))
+  }
+
+  resp, err = http.Get(ts.URL + "/hash/" + job.ID)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != 200 {
+    t.Errorf("Expected 200 code. Got %d", resp.StatusCode)
+  }
+}
+
+func TestAsyncHashBadFlag(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, &HashHandler{Service: newTestService(t)}))
+  defer ts.Close()
+
+  resp, err := http.Post(ts.URL + "/hash", "application/x-www-form-urlencoded", strings.NewReader("password=angryMonkey&async=maybe"))
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  if m := readErrorMessage(t, resp); resp.StatusCode != 400 || m.Code != CodeInvalidForm {
+    t.Errorf("Expected 400 invalid_form. got %d %+v", resp.StatusCode, m)
+  }
+}
+
+func TestUnknownHashJob(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, &HashHandler{Service: newTestService(t)}))
+  defer ts.Close()
+
+  resp, err := http.Get(ts.URL + "/hash/doesnotexist")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  if m := readErrorMessage(t, resp); resp.StatusCode != 404 || m.Code != CodeJobNotFound {
+    t.Errorf("Expected 404 job_not_found. got %d %+v", resp.StatusCode, m)
+  }
+
+  resp, err = http.Post(ts.URL + "/hash/doesnotexist", "application/x-www-form-urlencoded", strings.NewReader(""))
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != 405 || resp.Header.Get("Allow") != "GET, HEAD, DELETE, OPTIONS" {
+    t.Errorf("Expected 405 with GET, HEAD, DELETE, OPTIONS. got %d %s", resp.StatusCode, resp.Header.Get("Allow"))
+  }
+}
+
+func TestJobStoreCancel(t *testing.T) {
+  s := NewJobStore(nil)
+  cancelled := false
+  job := s.CreateFor("", "", func() { cancelled = true })
+
+  job, err := s.Cancel(job.ID)
+  if err != nil || job.Status != JobCancelled || job.Finished.IsZero() || !cancelled {
+    t.Errorf("Expected a cancelled job. got %+v %v", job, err)
+  }
+  s.Finish(job.ID, "somehash", nil) // the hash giving up afterwards doesn't change anything
+  if job, _ := s.Get(job.ID); job.Status != JobCancelled || job.Hash != "" {
+    t.Errorf("Expected the job to stay cancelled. got %+v", job)
+  }
+  if _, err := s.Cancel(job.ID); err != ErrJobFinished {
+    t.Errorf("Expected ErrJobFinished. got %v", err)
+  }
+  if _, err := s.Cancel("doesnotexist"); err != ErrJobNotFound {
+    t.Errorf("Expected ErrJobNotFound. got %v", err)
+  }
+}
+
+func TestJobStoreListPages(t *testing.T) {
+  clock := &instantClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+  s := NewJobStore(clock)
+  var ids []string
+  for i := 0; i < 5; i++ {
+    ids = append(ids, s.CreateFor("alice", "", nil).ID)
+    clock.After(time.Second)
+  }
+  s.CreateFor("bob", "", nil)
+  s.Finish(ids[4], "somehash", nil)
+
+  var listed []string
+  cursor := ""
+  for page := 0; page < 5; page++ {
+    list, err := s.List("alice", "", cursor, 2)
+    if err != nil {
+      t.Fatalf("Expected no error. got %v", err)
+    }
+    for _, job := range list.Jobs {
+      listed = append(listed, job.ID)
+    }
+    if cursor = list.Next; cursor == "" {
+      break
+    }
+  }
+  if strings.Join(listed, ",") != strings.Join([]string{ids[4], ids[3], ids[2], ids[1], ids[0]}, ",") {
+    t.Errorf("Expected alice's five jobs newest first. got %v", listed)
+  }
+
+  list, _ := s.List("alice", JobDone, "", 10)
+  if len(list.Jobs) != 1 || list.Jobs[0].ID != ids[4] || list.Next != "" {
+    t.Errorf("Expected the one done job. got %+v", list)
+  }
+  if _, err := s.List("alice", "", "not a cursor", 10); err == nil {
+    t.Errorf("Expected a bad cursor to be refused")
+  }
+}
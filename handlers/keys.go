@@ -0,0 +1,466 @@
+package handlers
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+    "github.com/rdibari84/GoHTTP/audit"
+)
+
+//////////////////////////////////////////////
+////////////////// API Keys //////////////////
+//////////////////////////////////////////////
+
+// APIKey lets a caller use the hashing endpoints and attributes the load to them.
+// only a SHA-256 of the key itself is ever stored
+type APIKey struct {
+  ID string
+  Name string // who the key was issued to
+  DailyQuota int `json:",omitempty"` // requests allowed per UTC day; unlimited when 0
+  Created time.Time
+  Revoked time.Time `json:",omitzero"` // left out until the key is revoked
+}
+
+// NewAPIKey is answered once, when a key is created. Key can't be shown again
+type NewAPIKey struct {
+  APIKey
+  Key string // send as Authorization: Bearer <key> or X-API-Key: <key>
+}
+
+// KeyStats is the /stats section for one API key. times are in microseconds
+type KeyStats struct {
+  ID string
+  Name string
+  Requests int // since the server started
+  Today int // counted against the daily quota
+  DailyQuota int `json:",omitempty"`
+  Rejected int // refused for being over the quota
+  Average float64
+  Max float64
+}
+
+// KeyStore holds the API keys and how much each has been used. keys are saved to a json file; usage is kept in memory
+type KeyStore struct {
+  mu sync.Mutex
+  path string // "" keeps the keys in memory only
+  keys map[string]*storedKey
+  usage map[string]*keyUsage
+}
+
+// LoadKeyStore reads the keys saved at path, which is created on the first change if it doesn't exist.
+// an empty path keeps keys in memory only
+func LoadKeyStore(path string) (*KeyStore, error) {
+  k := &KeyStore{path: path, keys: make(map[string]*storedKey), usage: make(map[string]*keyUsage)}
+  if path == "" {
+    return k, nil
+  }
+  contents, err := os.ReadFile(path)
+  if os.IsNotExist(err) {
+    return k, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+  var stored []*storedKey
+  if err := json.Unmarshal(contents, &stored); err != nil {
+    return nil, fmt.Errorf("%s: %s", path, err)
+  }
+  for _, key := range stored {
+    k.keys[key.ID] = key
+  }
+  return k, nil
+}
+
+// Create issues a new key. the returned NewAPIKey is the only place the key itself appears
+func (k *KeyStore) Create(name string, dailyQuota int, now time.Time) (NewAPIKey, error) {
+  secret := make([]byte, 32)
+  if _, err := rand.Read(secret); err != nil {
+    return NewAPIKey{}, err
+  }
+  id := newRequestID()[:16]
+  key := "gh_" + id + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+  k.mu.Lock()
+  defer k.mu.Unlock()
+  stored := &storedKey{APIKey: APIKey{ID: id, Name: name, DailyQuota: dailyQuota, Created: now}, Hash: hashKey(key)}
+  k.keys[id] = stored
+  if err := k.save(); err != nil {
+    delete(k.keys, id)
+    return NewAPIKey{}, err
+  }
+  return NewAPIKey{APIKey: stored.APIKey, Key: key}, nil
+}
+
+// Revoke stops a key from working. revoking it again changes nothing
+func (k *KeyStore) Revoke(id string, now time.Time) (APIKey, bool, error) {
+  k.mu.Lock()
+  defer k.mu.Unlock()
+  stored, ok := k.keys[id]
+  if !ok {
+    return APIKey{}, false, nil
+  }
+  if stored.Revoked.IsZero() {
+    stored.Revoked = now
+    if err := k.save(); err != nil {
+      stored.Revoked = time.Time{}
+      return APIKey{}, true, err
+    }
+  }
+  return stored.APIKey, true, nil
+}
+
+// Get returns the key with this id
+func (k *KeyStore) Get(id string) (APIKey, bool) {
+  k.mu.Lock()
+  defer k.mu.Unlock()
+  stored, ok := k.keys[id]
+  if !ok {
+    return APIKey{}, false
+  }
+  return stored.APIKey, true
+}
+
+// List returns every key, revoked ones included, oldest first
+func (k *KeyStore) List() []APIKey {
+  k.mu.Lock()
+  defer k.mu.Unlock()
+  keys := make([]APIKey, 0, len(k.keys))
+  for _, stored := range k.keys {
+    keys = append(keys, stored.APIKey)
+  }
+  sort.Slice(keys, func(i, j int) bool {
+    if keys[i].Created.Equal(keys[j].Created) {
+      return keys[i].ID < keys[j].ID
+    }
+    return keys[i].Created.Before(keys[j].Created)
+  })
+  return keys
+}
+
+// Authenticate finds the key a caller sent. revoked and unknown keys aren't found
+func (k *KeyStore) Authenticate(key string) (APIKey, bool) {
+  parts := strings.SplitN(key, "_", 3)
+  if len(parts) != 3 || parts[0] != "gh" {
+    return APIKey{}, false
+  }
+  k.mu.Lock()
+  defer k.mu.Unlock()
+  stored, ok := k.keys[parts[1]]
+  if !ok || !stored.Revoked.IsZero() {
+    return APIKey{}, false
+  }
+  // constant time so the comparison doesn't leak how much of the key matched
+  if subtle.ConstantTimeCompare([]byte(hashKey(key)), []byte(stored.Hash)) != 1 {
+    return APIKey{}, false
+  }
+  return stored.APIKey, true
+}
+
+// Take counts one request against the key's quota for now's UTC day. when the quota is used up it returns false
+// and how long until the quota resets
+func (k *KeyStore) Take(key APIKey, now time.Time) (bool, time.Duration) {
+  k.mu.Lock()
+  defer k.mu.Unlock()
+  u := k.usageFor(key.ID)
+  day := now.UTC().Format("2006-01-02")
+  if u.day != day {
+    u.day, u.today = day, 0
+  }
+  if key.DailyQuota > 0 && u.today >= key.DailyQuota {
+    u.rejected++
+    midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+    return false, midnight.Sub(now)
+  }
+  u.today++
+  return true, 0
+}
+
+// Record adds a served request's response time to the key's stats
+func (k *KeyStore) Record(id string, elapsed time.Duration) {
+  k.mu.Lock()
+  defer k.mu.Unlock()
+  u := k.usageFor(id)
+  u.requests++
+  u.sum += elapsed
+  if elapsed > u.max {
+    u.max = elapsed
+  }
+}
+
+// Stats returns the usage of every key, oldest first
+func (k *KeyStore) Stats() []KeyStats {
+  keys := k.List()
+  k.mu.Lock()
+  defer k.mu.Unlock()
+  stats := make([]KeyStats, 0, len(keys))
+  for _, key := range keys {
+    s := KeyStats{ID: key.ID, Name: key.Name, DailyQuota: key.DailyQuota}
+    if u, ok := k.usage[key.ID]; ok {
+      s.Requests, s.Today, s.Rejected = u.requests, u.today, u.rejected
+      s.Max = microseconds(u.max)
+      if u.requests > 0 {
+        s.Average = microseconds(u.sum) / float64(u.requests)
+      }
+    }
+    stats = append(stats, s)
+  }
+  return stats
+}
+
+// Authorize checks an API key and counts the request against its quota
+func (s *Service) Authorize(key string) (APIKey, *APIError) {
+  if key == "" {
+    return APIKey{}, NewError(CodeMissingAPIKey, "Send an API key as Authorization: Bearer <key> or X-API-Key")
+  }
+  apiKey, ok := s.Keys.Authenticate(key)
+  if !ok {
+    return APIKey{}, NewError(CodeInvalidAPIKey, "The API key is not valid")
+  }
+  if ok, wait := s.Keys.Take(apiKey, s.Clock.Now()); !ok {
+    apiErr := NewError(CodeQuotaExceeded, fmt.Sprintf("The API key has used its quota of %d requests for today", apiKey.DailyQuota))
+    apiErr.RetryAfter = wait
+    return APIKey{}, apiErr
+  }
+  return apiKey, nil
+}
+
+// RequireAPIKey refuses requests without a valid API key within its quota and records each one against the key.
+// CORS preflights pass through since browsers don't send credentials on them
+func (s *Service) RequireAPIKey(next http.Handler) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if r.Method == "OPTIONS" {
+      next.ServeHTTP(w, r)
+      return
+    }
+    key, apiErr := s.Authorize(apiKeyFrom(r))
+    if apiErr != nil {
+      if apiErr.Status == http.StatusUnauthorized {
+        s.auditRequest(r, audit.EventAuthFailed, string(apiErr.Code) + " " + r.URL.Path)
+        w.Header().Set("WWW-Authenticate", `Bearer realm="gohttp"`)
+      }
+      writeError(w, r, apiErr)
+      return
+    }
+    start := s.Clock.Now()
+    next.ServeHTTP(w, r.WithContext(WithAPIKey(r.Context(), key.ID)))
+    s.Keys.Record(key.ID, s.Clock.Now().Sub(start))
+  })
+}
+
+const apiKeyIDKey contextKey = "api-key-id"
+
+// WithAPIKey records which API key a request was made with, so the jobs it starts are listed for that key only
+func WithAPIKey(ctx context.Context, id string) context.Context {
+  return context.WithValue(ctx, apiKeyIDKey, id)
+}
+
+// APIKeyFrom returns the id of the API key set by WithAPIKey, or "" when the request didn't need one
+func APIKeyFrom(ctx context.Context) string {
+  id, _ := ctx.Value(apiKeyIDKey).(string)
+  return id
+}
+
+// methods the key admin endpoints answer; sent back in the Allow header
+var keysMethods = []string{"GET", "POST", "OPTIONS"}
+var keyMethods = []string{"GET", "DELETE", "OPTIONS"}
+
+// KeysHandler is the admin api for API keys: POST /admin/keys creates one, GET lists them,
+// GET /admin/keys/{id} shows one and DELETE revokes it. wrap it in RequireAdmin
+type KeysHandler struct {
+  Service *Service // whose KeyStore to manage; DefaultService() when nil
+}
+// needs a ServeHTTP method from HandlerFunc Interface
+func (h *KeysHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  s := serviceOrDefault(h.Service)
+  if strings.HasPrefix(r.URL.Path, "/admin/keys/") {
+    id := strings.TrimPrefix(r.URL.Path, "/admin/keys/")
+    switch r.Method {
+      case "GET":
+        key, ok := s.Keys.Get(id)
+        if !ok {
+          writeError(w, r, NewError(CodeKeyNotFound, "No API key with this id"))
+          return
+        }
+        writeJSON(w, r, http.StatusOK, key)
+      case "DELETE":
+        key, ok, err := s.Keys.Revoke(id, s.Clock.Now())
+        if !ok {
+          writeError(w, r, NewError(CodeKeyNotFound, "No API key with this id"))
+          return
+        }
+        if err != nil {
+          writeError(w, r, NewError(CodeInternal, "Could not save the API keys"))
+          return
+        }
+        s.auditRequest(r, audit.EventKeyRevoked, key.ID)
+        writeJSON(w, r, http.StatusOK, key)
+      case "OPTIONS":
+        writeOptions(w, keyMethods)
+      default:
+        writeMethodNotAllowed(w, r, keyMethods)
+    }
+    return
+  }
+  switch r.Method {
+    case "GET":
+      writeJSON(w, r, http.StatusOK, s.Keys.List())
+    case "POST":
+      if !parseForm(w, r, s.Policy) {
+        return
+      }
+      name := strings.TrimSpace(r.Form.Get("name"))
+      if name == "" {
+        writeError(w, r, NewError(CodeInvalidParameter, "name is required"))
+        return
+      }
+      quota := 0
+      if v := r.Form.Get("daily_quota"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 {
+          writeError(w, r, NewError(CodeInvalidParameter, "daily_quota must be a whole number of requests, 0 for no limit"))
+          return
+        }
+        quota = n
+      }
+      key, err := s.Keys.Create(name, quota, s.Clock.Now())
+      if err != nil {
+        writeError(w, r, NewError(CodeInternal, "Could not save the API keys"))
+        return
+      }
+      s.auditRequest(r, audit.EventKeyCreated, key.ID + " " + key.Name)
+      w.Header().Set("Location", treeOf(r).base + "/admin/keys/" + key.ID)
+      writeJSON(w, r, http.StatusCreated, key)
+    case "OPTIONS":
+      writeOptions(w, keysMethods)
+    default:
+      writeMethodNotAllowed(w, r, keysMethods)
+  }
+}
+
+// RequireAdmin refuses requests that don't carry the admin token as Authorization: Bearer <token>
+func (s *Service) RequireAdmin(next http.Handler) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if r.Method == "OPTIONS" {
+      next.ServeHTTP(w, r)
+      return
+    }
+    token := bearerToken(r)
+    // constant time so the comparison doesn't leak how much of the token matched
+    if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) != 1 {
+      s.auditRequest(r, audit.EventAuthFailed, string(CodeAdminRequired) + " " + r.URL.Path)
+      w.Header().Set("WWW-Authenticate", `Bearer realm="gohttp-admin"`)
+      writeError(w, r, NewError(CodeAdminRequired, "Send the admin token as Authorization: Bearer <token>"))
+      return
+    }
+    next.ServeHTTP(w, r)
+  })
+}
+
+// the key admin api for a route set, or nil when it isn't served. next to the public routes it needs the AdminToken;
+// on an admin listener of its own it is open unless there is one
+func (s *Service) keysRoutes(set routeSet) http.Handler {
+  if s.Keys == nil {
+    return nil
+  }
+  keys := &KeysHandler{Service: s}
+  if s.AdminToken != "" {
+    return s.RequireAdmin(keys)
+  }
+  if set == adminRoutes {
+    return keys
+  }
+  return nil
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// a key as saved in the key file
+type storedKey struct {
+  APIKey
+  Hash string // hex SHA-256 of the key
+}
+
+// how much one key has been used since the server started
+type keyUsage struct {
+  day string // UTC date today counts towards
+  today int
+  requests int
+  rejected int
+  sum time.Duration
+  max time.Duration
+}
+
+func (k *KeyStore) usageFor(id string) *keyUsage {
+  u, ok := k.usage[id]
+  if !ok {
+    u = &keyUsage{}
+    k.usage[id] = u
+  }
+  return u
+}
+
+// writes the keys to a temporary file next to path and renames it over path, so a crash never leaves half a file.
+// callers hold k.mu
+func (k *KeyStore) save() error {
+  if k.path == "" {
+    return nil
+  }
+  stored := make([]*storedKey, 0, len(k.keys))
+  for _, key := range k.keys {
+    stored = append(stored, key)
+  }
+  sort.Slice(stored, func(i, j int) bool { return stored[i].ID < stored[j].ID })
+  contents, err := json.MarshalIndent(stored, "", "  ")
+  if err != nil {
+    return err
+  }
+  tmp, err := os.CreateTemp(filepath.Dir(k.path), filepath.Base(k.path) + ".tmp")
+  if err != nil {
+    return err
+  }
+  defer os.Remove(tmp.Name())
+  if _, err := tmp.Write(contents); err != nil {
+    tmp.Close()
+    return err
+  }
+  if err := tmp.Close(); err != nil {
+    return err
+  }
+  return os.Rename(tmp.Name(), k.path) // CreateTemp files are only readable by us
+}
+
+func hashKey(key string) string {
+  sum := sha256.Sum256([]byte(key))
+  return hex.EncodeToString(sum[:])
+}
+
+// the API key from Authorization: Bearer, or X-API-Key
+func apiKeyFrom(r *http.Request) string {
+  if token := bearerToken(r); token != "" {
+    return token
+  }
+  return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+func bearerToken(r *http.Request) string {
+  auth := r.Header.Get("Authorization")
+  if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+    return strings.TrimSpace(auth[7:])
+  }
+  return ""
+}
@@ -0,0 +1,19 @@
+// Package openapi holds the OpenAPI 3 documents describing each version of the gohttp api and a page that renders them.
+// they are served by the handlers package; openapitest checks responses against them
+package openapi
+
+import (
+    _ "embed"
+)
+
+// Spec is the OpenAPI 3 document for version 1, served at /v1/openapi.json and /openapi.json
+//go:embed openapi.json
+var Spec []byte
+
+// SpecV2 is the OpenAPI 3 document for version 2, served at /v2/openapi.json
+//go:embed openapi-v2.json
+var SpecV2 []byte
+
+// Docs is a self contained html page that fetches openapi.json from next to it and renders it. served at /docs under every version
+//go:embed docs.html
+var Docs []byte
@@ -0,0 +1,208 @@
+// Package openapitest checks that handlers answer the way the gohttp OpenAPI document says they do
+package openapitest
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "mime"
+    "net"
+    "net/http"
+    "strings"
+    "sync"
+    "testing"
+    "github.com/getkin/kin-openapi/openapi3"
+    "github.com/getkin/kin-openapi/openapi3filter"
+    "github.com/getkin/kin-openapi/routers"
+    "github.com/getkin/kin-openapi/routers/gorillamux"
+    "github.com/rdibari84/GoHTTP/handlers/openapi"
+)
+
+//////////////////////////////////////////////
+///////////// Contract Checking //////////////
+//////////////////////////////////////////////
+
+// Check serves requests with next and fails t for every response that doesn't match the OpenAPI document for its version:
+// an undocumented status, a missing header, a content type or body that doesn't fit the schema.
+// requests to paths or methods the document doesn't describe (404s, 405s, OPTIONS, HEAD) must still answer
+// with an error message or no body at all. websocket connections are not checked once upgraded
+func Check(t testing.TB, next http.Handler) http.Handler {
+  for _, version := range []string{"v1", "v2"} {
+    if _, err := Document(version); err != nil {
+      t.Fatalf("Expected a valid %s OpenAPI document. Error: %s", version, err)
+    }
+  }
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    rw := &recorder{ResponseWriter: w, header: w.Header(), head: r.Method == "HEAD"}
+    next.ServeHTTP(rw, r)
+    if rw.hijacked {
+      return
+    }
+    if err := Validate(r, rw.status(), rw.header, rw.body.Bytes()); err != nil {
+      t.Errorf("%s %s does not match the OpenAPI document: %s", r.Method, r.URL.Path, err)
+    }
+  })
+}
+
+// Validate checks one response against the document for the version in r's path: /v2 or else v1
+func Validate(r *http.Request, status int, header http.Header, body []byte) error {
+  version := "v1"
+  if strings.HasPrefix(r.URL.Path, "/v2/") {
+    version = "v2"
+  }
+  c, err := load(version)
+  if err != nil {
+    return err
+  }
+  doc := c.doc
+  route, params, err := c.router.FindRoute(r)
+  if err != nil {
+    return checkUndocumented(doc, version, status, header, body)
+  }
+
+  if version == "v1" && route.Operation.OperationID == "hash" && status == http.StatusOK {
+    // the v1 hash has always been sent as bare text labelled application/json. the document says what it really is
+    header = header.Clone()
+    header.Set("Content-Type", "text/plain")
+  }
+  input := &openapi3filter.ResponseValidationInput{
+    RequestValidationInput: &openapi3filter.RequestValidationInput{
+      Request: r,
+      PathParams: params,
+      Route: route,
+    },
+    Status: status,
+    Header: header,
+    Body: io.NopCloser(bytes.NewReader(body)),
+    Options: &openapi3filter.Options{IncludeResponseStatus: true, MultiError: true},
+  }
+  return openapi3filter.ValidateResponse(context.Background(), input)
+}
+
+// Document is the parsed OpenAPI document for version v1 or v2
+func Document(version string) (*openapi3.T, error) {
+  c, err := load(version)
+  if err != nil {
+    return nil, err
+  }
+  return c.doc, nil
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// one version's document and a router finding its operations
+type contract struct {
+  doc *openapi3.T
+  router routers.Router
+}
+
+var loadMu sync.Mutex
+var contracts = map[string]*contract{}
+
+// parses and validates a version's document once for every test
+func load(version string) (*contract, error) {
+  loadMu.Lock()
+  defer loadMu.Unlock()
+  if c, ok := contracts[version]; ok {
+    return c, nil
+  }
+  specs := map[string][]byte{"v1": openapi.Spec, "v2": openapi.SpecV2}
+  spec, ok := specs[version]
+  if !ok {
+    return nil, fmt.Errorf("no OpenAPI document for %q", version)
+  }
+  doc, err := openapi3.NewLoader().LoadFromData(spec)
+  if err != nil {
+    return nil, err
+  }
+  if err := doc.Validate(context.Background()); err != nil {
+    return nil, err
+  }
+  router, err := gorillamux.NewRouter(doc)
+  if err != nil {
+    return nil, err
+  }
+  contracts[version] = &contract{doc: doc, router: router}
+  return contracts[version], nil
+}
+
+// responses the document has no operation for are errors or empty
+func checkUndocumented(doc *openapi3.T, version string, status int, header http.Header, body []byte) error {
+  if len(body) == 0 {
+    return nil
+  }
+  if status < 400 {
+    return fmt.Errorf("undocumented %d response with a body", status)
+  }
+  schema := "ErrorMessage"
+  if version == "v2" {
+    schema = "ErrorEnvelope"
+  }
+  mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+  switch mediaType {
+    case "application/json":
+    case "application/problem+json":
+      schema = "ProblemDetails"
+    default:
+      return fmt.Errorf("error sent as %q", mediaType)
+  }
+  var value interface{}
+  if err := json.Unmarshal(body, &value); err != nil {
+    return fmt.Errorf("error body is not json: %s", err)
+  }
+  return doc.Components.Schemas[schema].Value.VisitJSON(value)
+}
+
+// records what a handler wrote on its way to the real ResponseWriter
+type recorder struct {
+  http.ResponseWriter
+  header http.Header
+  code int
+  body bytes.Buffer
+  hijacked bool
+  head bool // net/http drops the body of a HEAD response, so there's nothing to check
+}
+
+func (rw *recorder) WriteHeader(code int) {
+  if rw.code == 0 {
+    rw.code = code
+  }
+  rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recorder) Write(b []byte) (int, error) {
+  if rw.code == 0 {
+    rw.code = http.StatusOK
+  }
+  if !rw.head {
+    rw.body.Write(b)
+  }
+  return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recorder) status() int {
+  if rw.code == 0 {
+    return http.StatusOK
+  }
+  return rw.code
+}
+
+// streams flush through to the client as they would without the check
+func (rw *recorder) Flush() {
+  http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+func (rw *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+  rw.hijacked = true
+  return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
+// lets http.ResponseController reach the real ResponseWriter
+func (rw *recorder) Unwrap() http.ResponseWriter {
+  return rw.ResponseWriter
+}
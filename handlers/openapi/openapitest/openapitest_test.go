@@ -0,0 +1,44 @@
+package openapitest
+
+import (
+  "testing"
+  "net/http"
+  "net/http/httptest"
+)
+
+func TestValidateCatchesMismatches(t *testing.T) {
+  json := http.Header{"Content-Type": {"application/json"}}
+
+  tests := []struct {
+    name string
+    method string
+    path string
+    status int
+    header http.Header
+    body string
+    valid bool
+  }{
+    {"stats", "GET", "/stats", 200, json, `{"Total": 1, "Average": 2.5, "Endpoints": []}`, true},
+    {"stats missing fields", "GET", "/stats", 200, json, `{"Total": 1}`, false},
+    {"undocumented status", "GET", "/stats", 418, json, `{"Error": "teapot"}`, false},
+    {"wrong content type", "GET", "/stats", 200, http.Header{"Content-Type": {"text/plain"}}, `hello`, false},
+    {"unknown error code", "GET", "/hash/abc", 404, json, `{"Error": "gone", "Code": "gone"}`, false},
+    {"accepted job without Location", "POST", "/hash", 202, json, `{"ID": "a", "Status": "pending", "Created": "2020-01-01T00:00:00Z", "Finished": "0001-01-01T00:00:00Z"}`, false},
+    {"bare hash", "POST", "/hash", 200, json, `ZEHhWB65gUlzdVwtDQArEyx+KVLzp/aTaRaPlBzYRIFj6vjFdqEb0Q5B8zVKCZ0vKbZPZklJz0Fd7su2A+gf7Q==`, true},
+    {"undocumented path error", "GET", "/nope", 404, json, `{"Error": "Resource not found", "Code": "not_found"}`, true},
+    {"undocumented path success", "GET", "/nope", 200, json, `{}`, false},
+    {"options", "OPTIONS", "/hash", 204, http.Header{}, ``, true},
+    {"v1 prefix", "GET", "/v1/stats", 200, json, `{"Total": 1, "Average": 2.5, "Endpoints": []}`, true},
+    {"v2 unwrapped", "GET", "/v2/stats", 200, json, `{"Total": 1, "Average": 2.5, "Endpoints": []}`, false},
+    {"v2 envelope", "GET", "/v2/stats", 200, json, `{"Data": {"Total": 1, "Average": 2.5, "Endpoints": []}, "RequestID": "a"}`, true},
+    {"v2 bare hash", "POST", "/v2/hash", 200, json, `ZEHhWB65gUlzdVwtDQArEyx+KVLzp/aTaRaPlBzYRIFj6vjFdqEb0Q5B8zVKCZ0vKbZPZklJz0Fd7su2A+gf7Q==`, false},
+    {"v2 undocumented path error", "GET", "/v2/nope", 404, json, `{"Error": {"Code": "not_found", "Message": "Resource not found"}}`, true},
+  }
+  for _, test := range tests {
+    r := httptest.NewRequest(test.method, test.path, nil)
+    err := Validate(r, test.status, test.header, []byte(test.body))
+    if (err == nil) != test.valid {
+      t.Errorf("Expected %s valid to be %v. got %v", test.name, test.valid, err)
+    }
+  }
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+    "bufio"
+    "crypto/sha1"
+    "encoding/hex"
+    "fmt"
+    "os"
+    "strings"
+    "unicode/utf8"
+    "golang.org/x/text/unicode/norm"
+)
+
+//////////////////////////////////////////////
+////////////// Password Policy ///////////////
+//////////////////////////////////////////////
+
+// PasswordPolicy decides which passwords /hash accepts and how they are normalized before hashing
+type PasswordPolicy struct {
+  MinLength int // in characters (runes), after normalization
+  MaxLength int // in characters (runes), after normalization. 0 means no limit
+  MaxBodyBytes int64 // largest request body /hash will read. 0 means no limit
+  Normalization string // "", "NFC" or "NFKC". equivalent unicode strings hash identically when set
+  Breached BreachedPasswords // passwords that are refused outright. nil skips the check
+}
+
+// used by a HashHandler that doesn't set its own Policy
+var DefaultPasswordPolicy = PasswordPolicy{
+  MinLength: 1,
+  MaxLength: 1024,
+  MaxBodyBytes: 64 << 10,
+}
+
+// BreachedPasswords is a set of SHA-1 digests (upper case hex) of known breached passwords
+type BreachedPasswords map[string]struct{}
+
+// Check makes sure the policy itself makes sense; used when loading configuration
+func (p *PasswordPolicy) Check() error {
+  if p.MinLength < 0 || p.MaxLength < 0 || p.MaxBodyBytes < 0 {
+    return fmt.Errorf("password policy limits can't be negative")
+  }
+  if p.MaxLength > 0 && p.MinLength > p.MaxLength {
+    return fmt.Errorf("min password length %d is bigger than max %d", p.MinLength, p.MaxLength)
+  }
+  switch strings.ToUpper(p.Normalization) {
+    case "", "NFC", "NFKC":
+    default:
+      return fmt.Errorf("unknown normalization form %q; use NFC or NFKC", p.Normalization)
+  }
+  return nil
+}
+
+// Validate returns the normalized password to hash, or an APIError describing why it was refused
+func (p *PasswordPolicy) Validate(password string) (string, *APIError) {
+  password, apiErr := p.Normalize(password)
+  if apiErr != nil {
+    return "", apiErr
+  }
+  length := utf8.RuneCountInString(password)
+  if length < p.MinLength {
+    return "", NewError(CodePasswordTooShort, fmt.Sprintf("Password must be at least %d characters", p.MinLength))
+  }
+  if p.MaxLength > 0 && length > p.MaxLength {
+    return "", NewError(CodePasswordTooLong, fmt.Sprintf("Password must be at most %d characters", p.MaxLength))
+  }
+  if p.Breached.Contains(password) {
+    return "", NewError(CodeBreachedPassword, "Password appears in a list of breached passwords")
+  }
+  return password, nil
+}
+
+// Normalize checks the password is UTF-8 and applies the normalization form, without any of the other checks.
+// used when verifying, so a hash made under the policy can always be checked
+func (p *PasswordPolicy) Normalize(password string) (string, *APIError) {
+  if !utf8.ValidString(password) {
+    return "", NewError(CodeInvalidUTF8, "Password is not valid UTF-8")
+  }
+  switch strings.ToUpper(p.Normalization) {
+    case "NFC":
+      password = norm.NFC.String(password)
+    case "NFKC":
+      password = norm.NFKC.String(password)
+  }
+  return password, nil
+}
+
+// Contains reports whether password is in the breached list
+func (b BreachedPasswords) Contains(password string) bool {
+  if len(b) == 0 {
+    return false
+  }
+  _, found := b[sha1Hex(password)]
+  return found
+}
+
+// LoadBreachedPasswords reads a breached password list, one entry per line.
+// lines are either plaintext passwords or SHA-1 hex digests, optionally followed by ":count" (the HIBP download format).
+// only digests are kept in memory
+func LoadBreachedPasswords(path string) (BreachedPasswords, error) {
+  f, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+
+  breached := BreachedPasswords{}
+  scanner := bufio.NewScanner(f)
+  for scanner.Scan() {
+    line := strings.TrimRight(scanner.Text(), "\r")
+    if line == "" {
+      continue
+    }
+    if digest := strings.SplitN(line, ":", 2)[0]; isSHA1Hex(digest) {
+      breached[strings.ToUpper(digest)] = struct{}{}
+    } else {
+      breached[sha1Hex(line)] = struct{}{}
+    }
+  }
+  if err := scanner.Err(); err != nil {
+    return nil, err
+  }
+  return breached, nil
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+func sha1Hex(s string) string {
+  sum := sha1.Sum([]byte(s))
+  return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSHA1Hex(s string) bool {
+  if len(s) != 40 {
+    return false
+  }
+  _, err := hex.DecodeString(s)
+  return err == nil
+}
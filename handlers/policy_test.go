@@ -0,0 +1,139 @@
+package handlers
+
+import (
+  "testing"
+  "net/http"
+  "net/http/httptest"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strings"
+  "github.com/rdibari84/GoHTTP/handlers/openapi/openapitest"
+)
+
+//////////////////////////////////////////////
+////////// Password Policy Unit Tests ////////
+//////////////////////////////////////////////
+
+func TestPolicyLengthLimits(t *testing.T) {
+  p := PasswordPolicy{MinLength: 2, MaxLength: 4}
+  if _, apiErr := p.Validate("a"); apiErr == nil || apiErr.Code != CodePasswordTooShort {
+    t.Errorf("Expected password_too_short. got %v", apiErr)
+  }
+  if _, apiErr := p.Validate("abcde"); apiErr == nil || apiErr.Code != CodePasswordTooLong {
+    t.Errorf("Expected password_too_long. got %v", apiErr)
+  }
+  // lengths are counted in characters, not bytes
+  if _, apiErr := p.Validate("ééé"); apiErr != nil {
+    t.Errorf("Expected 3 characters to be accepted. got %v", apiErr)
+  }
+}
+
+func TestPolicyRejectsInvalidUTF8(t *testing.T) {
+  p := DefaultPasswordPolicy
+  if _, apiErr := p.Validate("bad\xffbytes"); apiErr == nil || apiErr.Code != CodeInvalidUTF8 {
+    t.Errorf("Expected invalid_utf8. got %v", apiErr)
+  }
+}
+
+func TestPolicyNormalization(t *testing.T) {
+  composed := "café"
+  decomposed := "café"
+
+  p := PasswordPolicy{Normalization: "NFC"}
+  a, _ := p.Validate(composed)
+  b, _ := p.Validate(decomposed)
+  if a != b {
+    t.Errorf("Expected NFC to make %q and %q identical", composed, decomposed)
+  }
+
+  p = PasswordPolicy{Normalization: "NFKC"}
+  if n, _ := p.Validate("ﬁ"); n != "fi" {
+    t.Errorf("Expected NFKC to expand the fi ligature. got %q", n)
+  }
+
+  p = PasswordPolicy{}
+  if n, _ := p.Validate(decomposed); n != decomposed {
+    t.Errorf("Expected no normalization by default. got %q", n)
+  }
+}
+
+func TestPolicyCheck(t *testing.T) {
+  if err := (&PasswordPolicy{MinLength: 5, MaxLength: 2}).Check(); err == nil {
+    t.Errorf("Expected min > max to be refused")
+  }
+  if err := (&PasswordPolicy{Normalization: "NFD"}).Check(); err == nil {
+    t.Errorf("Expected unknown normalization to be refused")
+  }
+  if err := (&PasswordPolicy{Normalization: "nfkc"}).Check(); err != nil {
+    t.Errorf("Expected nfkc to be accepted. got %v", err)
+  }
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "breached.txt")
+  // a plaintext entry, a SHA-1 of "password" in HIBP format and a blank line
+  contents := "angryMonkey\n5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3730471\n\n"
+  if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+    t.Fatal(err)
+  }
+  breached, err := LoadBreachedPasswords(path)
+  if err != nil {
+    t.Fatalf("Expected no error. got %v", err)
+  }
+  if len(breached) != 2 {
+    t.Errorf("Expected 2 entries. got %d", len(breached))
+  }
+  if !breached.Contains("angryMonkey") || !breached.Contains("password") {
+    t.Errorf("Expected both plaintext and SHA-1 entries to match")
+  }
+  if breached.Contains("happyMonkey") {
+    t.Errorf("Expected happyMonkey not to be breached")
+  }
+
+  p := PasswordPolicy{Breached: breached}
+  if _, apiErr := p.Validate("password"); apiErr == nil || apiErr.Code != CodeBreachedPassword {
+    t.Errorf("Expected breached_password. got %v", apiErr)
+  }
+}
+
+func TestHashHandlerEnforcesPolicy(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, &HashHandler{Service: NewService(Config{Policy: &PasswordPolicy{MinLength: 1, MaxBodyBytes: 32}})}))
+  defer ts.Close()
+
+  cases := []struct {
+    body string
+    status int
+    code ErrorCode
+  }{
+    {"password=", 400, CodePasswordTooShort},
+    {"password=" + strings.Repeat("a", 64), 413, CodeBodyTooLarge},
+    {"password=%ff", 400, CodeInvalidUTF8},
+    {"password=%zz", 400, CodeInvalidForm},
+  }
+  for _, c := range cases {
+    resp, err := http.Post(ts.URL + "/hash", "application/x-www-form-urlencoded", strings.NewReader(c.body))
+    if err != nil {
+      t.Fatalf("Expected no error. Error: %s", err)
+    }
+    m := readErrorMessage(t, resp)
+    if resp.StatusCode != c.status || m.Code != c.code {
+      t.Errorf("%s: expected %d %s. got %d %s", c.body, c.status, c.code, resp.StatusCode, m.Code)
+    }
+  }
+}
+
+func TestHashHandlerDefaultPolicyRejectsEmpty(t *testing.T) {
+  ts := httptest.NewServer(openapitest.Check(t, &HashHandler{}))
+  defer ts.Close()
+
+  resp, err := http.Post(ts.URL + "/hash", "application/x-www-form-urlencoded", strings.NewReader("password="))
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  if resp.StatusCode != 400 || !strings.Contains(string(body), string(CodePasswordTooShort)) {
+    t.Errorf("Expected empty password to be refused. got %d %s", resp.StatusCode, body)
+  }
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "runtime"
+    "sync"
+    "time"
+)
+
+//////////////////////////////////////////////
+///////////// Hashing Worker Pool ////////////
+//////////////////////////////////////////////
+
+// Lane picks which queue hashing work waits in. workers always drain the sync lane first
+type Lane int
+
+const (
+  LaneSync Lane = iota // a caller is waiting on the response
+  LaneBatch // async jobs; only run when no sync work is waiting
+)
+
+// returned when the lane's queue is already full
+var ErrQueueFull = errors.New("hash queue is full")
+
+// HashPool runs hashing on a fixed number of workers fed from bounded queues,
+// so CPU heavy hashing can't take over every goroutine the server has
+type HashPool struct {
+  workers int
+  lanes [2]chan *hashTask
+  quit chan struct{}
+  closeOnce sync.Once
+
+  mu sync.Mutex
+  busy int
+  laneStats [2]laneTotals
+}
+
+// a single hash waiting in a lane
+type hashTask struct {
+  ctx context.Context
+  password string
+  queued time.Time
+  result chan string
+}
+
+// running totals for one lane
+type laneTotals struct {
+  completed int
+  rejected int
+  cancelled int
+  waitSum time.Duration
+  waitMax time.Duration
+}
+
+// pool section of the /stats message
+type PoolStats struct {
+  Workers int
+  Busy int
+  QueueCapacity int // per lane
+  Lanes []LaneStats
+}
+
+// per lane section of PoolStats
+type LaneStats struct {
+  Lane string
+  Queued int // waiting right now
+  Completed int
+  Rejected int // refused because the queue was full
+  Cancelled int // caller went away before a worker picked it up
+  WaitAverage float64 // time spent queued, in microseconds
+  WaitMax float64
+}
+
+var defaultPoolOnce sync.Once
+var defaultPool *HashPool
+
+// DefaultHashPool is used by handlers that don't set their own Pool. GOMAXPROCS workers, 1024 queued hashes per lane
+func DefaultHashPool() *HashPool {
+  defaultPoolOnce.Do(func() {
+    defaultPool = NewHashPool(0, 1024)
+  })
+  return defaultPool
+}
+
+// NewHashPool starts workers goroutines (GOMAXPROCS when workers < 1), each lane holding at most queueSize hashes
+func NewHashPool(workers int, queueSize int) *HashPool {
+  if workers < 1 {
+    workers = runtime.GOMAXPROCS(0)
+  }
+  if queueSize < 0 {
+    queueSize = 0
+  }
+  p := &HashPool{workers: workers, quit: make(chan struct{})}
+  for i := range p.lanes {
+    p.lanes[i] = make(chan *hashTask, queueSize)
+  }
+  for i := 0; i < workers; i++ {
+    go p.work()
+  }
+  return p
+}
+
+// Hash queues password in lane and waits for the result.
+// returns ErrQueueFull straight away if the lane is full, or ctx.Err() if ctx is done first
+func (p *HashPool) Hash(ctx context.Context, lane Lane, password string) (string, error) {
+  task := &hashTask{ctx: ctx, password: password, queued: time.Now(), result: make(chan string, 1)}
+  select {
+    case p.lanes[lane] <- task:
+    default:
+      p.mu.Lock()
+      p.laneStats[lane].rejected++
+      p.mu.Unlock()
+      return "", ErrQueueFull
+  }
+  select {
+    case hash := <-task.result:
+      return hash, nil
+    case <-ctx.Done():
+      return "", ctx.Err()
+  }
+}
+
+// Full reports whether lane has no room for more work right now
+func (p *HashPool) Full(lane Lane) bool {
+  return len(p.lanes[lane]) == cap(p.lanes[lane])
+}
+
+// Stats returns the pool's current state and totals
+func (p *HashPool) Stats() PoolStats {
+  p.mu.Lock()
+  defer p.mu.Unlock()
+  stats := PoolStats{Workers: p.workers, Busy: p.busy, QueueCapacity: cap(p.lanes[LaneSync])}
+  for i, name := range []string{"sync", "batch"} {
+    totals := p.laneStats[i]
+    lane := LaneStats{
+      Lane: name,
+      Queued: len(p.lanes[i]),
+      Completed: totals.completed,
+      Rejected: totals.rejected,
+      Cancelled: totals.cancelled,
+      WaitMax: microseconds(totals.waitMax),
+    }
+    if totals.completed > 0 {
+      lane.WaitAverage = microseconds(totals.waitSum) / float64(totals.completed)
+    }
+    stats.Lanes = append(stats.Lanes, lane)
+  }
+  return stats
+}
+
+// Close stops the workers once they finish what they're doing. queued work is dropped
+func (p *HashPool) Close() {
+  p.closeOnce.Do(func() { close(p.quit) })
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+func (p *HashPool) work() {
+  for {
+    task, lane, ok := p.next()
+    if !ok {
+      return
+    }
+    p.run(lane, task)
+  }
+}
+
+// waits for the next task, sync lane first. false once the pool is closed
+func (p *HashPool) next() (*hashTask, Lane, bool) {
+  select {
+    case task := <-p.lanes[LaneSync]:
+      return task, LaneSync, true
+    default:
+  }
+  select {
+    case task := <-p.lanes[LaneSync]:
+      return task, LaneSync, true
+    case task := <-p.lanes[LaneBatch]:
+      return task, LaneBatch, true
+    case <-p.quit:
+      return nil, 0, false
+  }
+}
+
+func (p *HashPool) run(lane Lane, task *hashTask) {
+  wait := time.Since(task.queued)
+  p.mu.Lock()
+  if task.ctx.Err() != nil { // nobody is waiting for this one any more
+    p.laneStats[lane].cancelled++
+    p.mu.Unlock()
+    return
+  }
+  p.busy++
+  p.mu.Unlock()
+
+  hash := generate_hash(task.password)
+  task.result <- hash
+
+  p.mu.Lock()
+  p.busy--
+  totals := &p.laneStats[lane]
+  totals.completed++
+  totals.waitSum += wait
+  if wait > totals.waitMax {
+    totals.waitMax = wait
+  }
+  p.mu.Unlock()
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+  "testing"
+  "context"
+  "encoding/json"
+  "net/http"
+  "net/http/httptest"
+  "strings"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers/openapi/openapitest"
+)
+
+//////////////////////////////////////////////
+///////////// Worker Pool Unit Tests /////////
+//////////////////////////////////////////////
+
+func TestHashPoolHashes(t *testing.T) {
+  p := NewHashPool(2, 4)
+  defer p.Close()
+
+  hash, err := p.Hash(context.Background(), LaneSync, "angryMonkey")
+  if err != nil || hash != generate_hash("angryMonkey") {
+    t.Fatalf("Expected the angryMonkey hash. got %s %v", hash, err)
+  }
+  stats := p.Stats()
+  if stats.Workers != 2 || stats.QueueCapacity != 4 || len(stats.Lanes) != 2 {
+    t.Errorf("Expected 2 workers and 2 lanes of 4. got %+v", stats)
+  }
+  if stats.Lanes[0].Lane != "sync" || stats.Lanes[0].Completed != 1 || stats.Lanes[1].Completed != 0 {
+    t.Errorf("Expected one completed sync hash. got %+v", stats.Lanes)
+  }
+}
+
+func TestHashPoolDefaultsToGOMAXPROCS(t *testing.T) {
+  p := NewHashPool(0, 1)
+  defer p.Close()
+  if p.Stats().Workers < 1 {
+    t.Errorf("Expected at least one worker. got %d", p.Stats().Workers)
+  }
+}
+
+func TestHashPoolQueueFull(t *testing.T) {
+  p := newIdlePool(1)
+  ctx, cancel := context.WithCancel(context.Background())
+  cancel()
+
+  // queued, then given up on straight away since ctx is already done
+  if _, err := p.Hash(ctx, LaneSync, "first"); err != context.Canceled {
+    t.Fatalf("Expected context.Canceled. got %v", err)
+  }
+  if _, err := p.Hash(context.Background(), LaneSync, "second"); err != ErrQueueFull {
+    t.Fatalf("Expected ErrQueueFull. got %v", err)
+  }
+  if !p.Full(LaneSync) || p.Full(LaneBatch) {
+    t.Errorf("Expected only the sync lane to be full")
+  }
+
+  task, lane, _ := p.next()
+  p.run(lane, task) // skipped since nobody is waiting
+  stats := p.Stats().Lanes[LaneSync]
+  if stats.Rejected != 1 || stats.Cancelled != 1 || stats.Completed != 0 || stats.Queued != 0 {
+    t.Errorf("Expected 1 rejected and 1 cancelled. got %+v", stats)
+  }
+}
+
+func TestHashPoolSyncLaneFirst(t *testing.T) {
+  p := newIdlePool(2)
+  p.lanes[LaneBatch] <- &hashTask{password: "batch"}
+  p.lanes[LaneSync] <- &hashTask{password: "sync"}
+  p.lanes[LaneBatch] <- &hashTask{password: "batch"}
+
+  var order []string
+  for i := 0; i < 3; i++ {
+    task, _, ok := p.next()
+    if !ok {
+      t.Fatalf("Expected a task")
+    }
+    order = append(order, task.password)
+  }
+  if strings.Join(order, ",") != "sync,batch,batch" {
+    t.Errorf("Expected the sync task first. got %v", order)
+  }
+
+  p.Close()
+  if _, _, ok := p.next(); ok {
+    t.Errorf("Expected no task once closed")
+  }
+}
+
+func TestHashPoolRecordsWaitTime(t *testing.T) {
+  p := newIdlePool(1)
+  task := &hashTask{ctx: context.Background(), password: "angryMonkey", queued: time.Now().Add(-time.Second), result: make(chan string, 1)}
+  p.run(LaneBatch, task)
+  if <-task.result != generate_hash("angryMonkey") {
+    t.Errorf("Expected the angryMonkey hash")
+  }
+  stats := p.Stats().Lanes[LaneBatch]
+  if stats.Completed != 1 || stats.WaitMax < 1e6 || stats.WaitAverage < 1e6 {
+    t.Errorf("Expected a wait of at least 1s. got %+v", stats)
+  }
+}
+
+func TestHashHandlerQueueFull(t *testing.T) {
+  p := newIdlePool(0)
+  ts := httptest.NewServer(openapitest.Check(t, &HashHandler{Service: NewService(Config{Pool: p})}))
+  defer ts.Close()
+
+  resp, err := http.Post(ts.URL + "/hash", "application/x-www-form-urlencoded", strings.NewReader("password=angryMonkey&async=true"))
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  defer resp.Body.Close()
+  if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "1" {
+    t.Errorf("Expected 503 with Retry-After. got %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+  }
+  if m := readErrorMessage(t, resp); m.Code != CodeQueueFull {
+    t.Errorf("Expected code %s. got %s", CodeQueueFull, m.Code)
+  }
+}
+
+func TestStatsIncludePool(t *testing.T) {
+  p := NewHashPool(3, 8)
+  defer p.Close()
+  ts := httptest.NewServer(openapitest.Check(t, &StatsHandler{Service: NewService(Config{Pool: p})}))
+  defer ts.Close()
+
+  resp, err := http.Get(ts.URL + "/stats")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  defer resp.Body.Close()
+  var stats Stats
+  if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+    t.Fatalf("Expected json stats. Error: %s", err)
+  }
+  if stats.Pool == nil || stats.Pool.Workers != 3 || stats.Pool.QueueCapacity != 8 {
+    t.Errorf("Expected pool stats for 3 workers. got %+v", stats.Pool)
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// a pool with no running workers so tests control when tasks are taken off the queue
+func newIdlePool(queueSize int) *HashPool {
+  p := &HashPool{workers: 1, quit: make(chan struct{})}
+  for i := range p.lanes {
+    p.lanes[i] = make(chan *hashTask, queueSize)
+  }
+  return p
+}
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+    "context"
+    "crypto/subtle"
+    "fmt"
+    "net/http"
+    "sync"
+    "sync/atomic"
+    "time"
+    "github.com/rdibari84/GoHTTP/audit"
+)
+
+//////////////////////////////////////////////
+/////////////////// Service //////////////////
+//////////////////////////////////////////////
+
+// Clock is the time source for the delay, request stats and jobs. tests swap in a fake one so they don't really sleep
+type Clock interface {
+  Now() time.Time
+  After(d time.Duration) <-chan time.Time
+}
+
+// the wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RealClock is used by services that don't set their own Clock
+var RealClock Clock = realClock{}
+
+// Config is what a Service depends on. zero fields get the defaults
+type Config struct {
+  Policy *PasswordPolicy // which passwords are accepted; DefaultPasswordPolicy when nil
+  Pool *HashPool // runs the hashing; DefaultHashPool() when nil
+  Delay DelayPolicy // how long to wait before hashing; DefaultDelayPolicy when nil
+  Clock Clock // RealClock when nil
+  StatsInterval time.Duration // how often /stats/stream pushes stats; DefaultStatsInterval when zero
+  Webhooks *WebhookConfig // lets async jobs name a callback_url; refused when nil
+  Deprecations map[string]Deprecation // announced on every response from a route tree, by version: unversioned, v1 or v2
+  Keys *KeyStore // API keys the hashing endpoints require; open to anyone when nil
+  Cache *HashCache // answers repeated passwords without hashing them again; off when nil
+  AdminToken string // lets the admin api manage Keys; the admin api is off when empty
+  Debug bool // serves /debug/pprof and /debug/vars, behind the AdminToken when there is one
+  AuditLog *audit.Log // records shutdowns, stats resets, restarts, key changes and auth failures; off when nil
+  Restart func() (int, error) // hands the listening sockets to a new process and drains this one, returning the new pid. POST /restart on the admin listener when set
+}
+
+// how often /stats/stream pushes stats unless the Config or the caller says otherwise
+var DefaultStatsInterval = time.Second
+
+// Service is the state one server's handlers share: their dependencies, request stats, async jobs and hashing counters.
+// handlers built on different Services don't see each other's requests
+type Service struct {
+  Config
+  Stats *StatsRecorder // every request passed through RecordStats
+  Jobs *JobStore // async hash jobs
+
+  hashesInProgress atomic.Int32 // used in shutdown to ensure all hashing work has completed
+  shuttingDown atomic.Bool // set once a shutdown has been requested; new hashing work is refused from then on
+  stopping chan struct{} // closed along with shuttingDown so streams can finish up and close
+  stopOnce sync.Once
+  requestsInFlight atomic.Int32 // requests RecordStats is serving right now
+
+  // the shutdown POST /shutdown scheduled, see ScheduleShutdown
+  shutdownMu sync.Mutex
+  shutdownCancel chan struct{} // closed to call the scheduled shutdown off; nil when none is waiting to start
+  shutdownAt time.Time // when the scheduled shutdown starts draining, or started
+  shutdownDrain time.Duration // how long the drain waits for the hashing in progress; as long as it takes when zero
+
+  // identical hashes in progress, shared by everyone asking for them. see cachedHash
+  digestKey []byte
+  flightMu sync.Mutex
+  flights map[flightKey]*flight
+  coalesced int
+
+  // running total of the time /hash takes to return, see addSummedResponseTime. used in the /stats endpoint
+  mu sync.Mutex
+  summedHashResponseTimes []time.Duration
+}
+
+// NewService fills in the defaults for anything cfg leaves empty
+func NewService(cfg Config) *Service {
+  if cfg.Policy == nil {
+    cfg.Policy = &DefaultPasswordPolicy
+  }
+  if cfg.Pool == nil {
+    cfg.Pool = DefaultHashPool()
+  }
+  if cfg.Delay == nil {
+    cfg.Delay = DefaultDelayPolicy
+  }
+  if cfg.Clock == nil {
+    cfg.Clock = RealClock
+  }
+  if cfg.StatsInterval <= 0 {
+    cfg.StatsInterval = DefaultStatsInterval
+  }
+  if cfg.Webhooks != nil {
+    cfg.Webhooks = cfg.Webhooks.withDefaults()
+  }
+  return &Service{
+    Config: cfg,
+    Stats: NewStatsRecorder(),
+    Jobs: NewJobStore(cfg.Clock),
+    stopping: make(chan struct{}),
+    digestKey: newDigestKey(),
+    flights: make(map[flightKey]*flight),
+  }
+}
+
+var defaultServiceOnce sync.Once
+var defaultService *Service
+
+// DefaultService is shared by handlers that don't set their own Service
+func DefaultService() *Service {
+  defaultServiceOnce.Do(func() {
+    defaultService = NewService(Config{})
+  })
+  return defaultService
+}
+
+// Routes serves every endpoint under /v1 and /v2, each recorded for the /stats endpoint.
+// paths without a version behave exactly like /v1. /shutdown is only served when srv is set since it shuts srv down.
+// use PublicRoutes and AdminRoutes instead to keep /shutdown, /stats and key management off the public listener
+func (s *Service) Routes(srv *http.Server) *http.ServeMux {
+  mux := s.routes(srv, allRoutes)
+  s.debugRoutes(mux, allRoutes)
+  return mux
+}
+
+// PublicRoutes serves hashing, verification and the api docs, without anything that manages the server
+func (s *Service) PublicRoutes() *http.ServeMux {
+  return s.routes(nil, publicRoutes)
+}
+
+// AdminRoutes serves what PublicRoutes leaves out: /shutdown (when srv is set), /stats and /stats/stream,
+// DELETE /stats to start the stats over, /config, /admin/keys, and with Debug /debug/pprof and /debug/vars. serve it
+// on a listener only operators can reach; /admin/keys and the debug routes still need the AdminToken when there is one
+func (s *Service) AdminRoutes(srv *http.Server) *http.ServeMux {
+  mux := s.routes(srv, adminRoutes)
+  s.debugRoutes(mux, adminRoutes)
+  return mux
+}
+
+// RecordStats wraps a handler so every request it serves is counted in the /stats endpoint under route
+func (s *Service) RecordStats(route string, next http.Handler) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    start := s.Clock.Now()
+    sw := &statusWriter{ResponseWriter: w}
+    s.requestsInFlight.Add(1)
+    next.ServeHTTP(sw, r)
+    s.requestsInFlight.Add(-1)
+    s.Stats.CountProtocol(r.Proto)
+    s.Stats.Record(route, r.Method, sw.status(), s.Clock.Now().Sub(start), sw.reason)
+  })
+}
+
+//////////////////////////////////////////////
+/////////////// Core Operations //////////////
+//////////////////////////////////////////////
+// shared by the http handlers and the gRPC server
+
+// Hash checks password against the policy and hashes it after the delay. stops early if ctx is done
+func (s *Service) Hash(ctx context.Context, password string) (string, *APIError) {
+  start := s.Clock.Now() // capture starting time
+  if apiErr := s.beginHash(); apiErr != nil {
+    return "", apiErr
+  }
+  password, apiErr := s.Policy.Validate(password)
+  if apiErr != nil {
+    s.hashesInProgress.Add(-1)
+    return "", apiErr
+  }
+  hash, err := s.cachedHash(ctx, LaneSync, password, nil)
+  s.hashesInProgress.Add(-1)
+  if err != nil {
+    return "", hashError(err)
+  }
+  s.addHashResponseTime(s.Clock.Now().Sub(start))
+  return hash, nil
+}
+
+// HashAsync checks password against the policy and starts hashing it in the background on the pool's batch lane.
+// the finished job is POSTed to callbackURL when it is set. ctx only says which API key the job belongs to;
+// the hashing carries on after the caller goes away, until the job is cancelled
+func (s *Service) HashAsync(ctx context.Context, password string, callbackURL string) (Job, *APIError) {
+  if apiErr := s.beginHash(); apiErr != nil { // counted now so a shutdown can't slip in before the goroutine starts
+    return Job{}, apiErr
+  }
+  refuse := func(apiErr *APIError) (Job, *APIError) {
+    s.hashesInProgress.Add(-1)
+    return Job{}, apiErr
+  }
+  password, apiErr := s.Policy.Validate(password)
+  if apiErr != nil {
+    return refuse(apiErr)
+  }
+  if callbackURL != "" {
+    if apiErr := s.checkCallback(callbackURL); apiErr != nil {
+      return refuse(apiErr)
+    }
+  }
+  if s.Pool.Full(LaneBatch) { // refuse now rather than fail the job later
+    return refuse(hashError(ErrQueueFull))
+  }
+  jobCtx, cancel := context.WithCancel(context.Background())
+  job := s.Jobs.CreateFor(APIKeyFrom(ctx), callbackURL, cancel)
+  go func() {
+    defer s.hashesInProgress.Add(-1)
+    defer cancel()
+    hash, err := s.cachedHash(jobCtx, LaneBatch, password, func() { s.Jobs.Start(job.ID) })
+    s.Jobs.Finish(job.ID, hash, err)
+    if callbackURL != "" { // still counted as in progress so a shutdown waits for the delivery under way
+      s.deliverCallback(job.ID)
+    }
+  }()
+  return job, nil
+}
+
+// Result returns the async job with the given id, as long as it was started with ctx's API key. another key's job
+// is not found, same as one that doesn't exist
+func (s *Service) Result(ctx context.Context, id string) (Job, *APIError) {
+  job, ok := s.Jobs.Get(id)
+  if !ok || job.owner != APIKeyFrom(ctx) {
+    return Job{}, NewError(CodeJobNotFound, "No hash job with id " + id)
+  }
+  return job, nil
+}
+
+// CancelJob stops the pending async job with the given id, as long as it was started with ctx's API key. its
+// callback, if it has one, is told it was cancelled
+func (s *Service) CancelJob(ctx context.Context, id string) (Job, *APIError) {
+  if _, apiErr := s.Result(ctx, id); apiErr != nil {
+    return Job{}, apiErr
+  }
+  job, err := s.Jobs.Cancel(id)
+  switch err {
+    case ErrJobNotFound:
+      return Job{}, NewError(CodeJobNotFound, "No hash job with id " + id)
+    case ErrJobFinished:
+      return Job{}, NewError(CodeJobFinished, "Hash job " + id + " is already " + job.Status)
+  }
+  return job, nil
+}
+
+// ListJobs returns a page of the async jobs started with ctx's API key, newest first. see JobStore.List
+func (s *Service) ListJobs(ctx context.Context, status string, cursor string, limit int) (JobList, *APIError) {
+  switch status {
+    case "", JobPending, JobDone, JobFailed, JobCancelled:
+    default:
+      return JobList{}, NewError(CodeInvalidParameter, "status must be pending, done, failed or cancelled")
+  }
+  if limit == 0 {
+    limit = DefaultJobListLimit
+  }
+  if limit < 1 || limit > MaxJobListLimit {
+    return JobList{}, NewError(CodeInvalidParameter, fmt.Sprintf("limit must be between 1 and %d", MaxJobListLimit))
+  }
+  list, err := s.Jobs.List(APIKeyFrom(ctx), status, cursor, limit)
+  if err != nil {
+    return JobList{}, NewError(CodeInvalidParameter, err.Error())
+  }
+  return list, nil
+}
+
+// Verify reports whether password hashes to hash, normalizing the password the same way Hash does
+func (s *Service) Verify(ctx context.Context, password string, hash string) (bool, *APIError) {
+  password, apiErr := s.Policy.Normalize(password)
+  if apiErr != nil {
+    return false, apiErr
+  }
+  computed, err := s.Pool.Hash(ctx, LaneSync, password)
+  if err != nil {
+    return false, hashError(err)
+  }
+  // constant time so the comparison doesn't leak how much of the hash matched
+  return subtle.ConstantTimeCompare([]byte(computed), []byte(hash)) == 1, nil
+}
+
+// Snapshot returns the numbers served by the /stats endpoint
+func (s *Service) Snapshot() Stats {
+  total, average := s.hashResponseTimes()
+  poolStats := s.Pool.Stats()
+  stats := Stats{Total: total, Average: average, Endpoints: s.Stats.Snapshot(), Protocols: s.Stats.Protocols(), Pool: &poolStats, Cache: s.cacheStats()}
+  if s.Keys != nil {
+    stats.Keys = s.Keys.Stats()
+  }
+  return stats
+}
+
+// ResetStats starts the /stats numbers over. API key quotas and the hashing pool's counters are left alone
+func (s *Service) ResetStats() {
+  s.Stats.Reset()
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  s.summedHashResponseTimes = nil
+}
+
+// Drain refuses new hashing work, tells the streams to close and waits until the hashing in progress has finished, or ctx is done
+func (s *Service) Drain(ctx context.Context) error {
+  s.startDraining()
+  ticker := time.NewTicker(10 * time.Millisecond)
+  defer ticker.Stop()
+  for s.hashesInProgress.Load() != 0 { // continue looping until hash is not in progress.
+    select {
+      case <-ctx.Done():
+        return ctx.Err()
+      case <-ticker.C:
+    }
+  }
+  return nil
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// refuses new hashing work and tells the streams to close
+func (s *Service) startDraining() {
+  s.shuttingDown.Store(true)
+  s.stopOnce.Do(func() { close(s.stopping) })
+}
+
+// counts a hash in progress, then refuses it if a shutdown has started. counting first means a drain that starts
+// in between either sees the hash and waits for it, or the hash sees the drain and backs out
+func (s *Service) beginHash() *APIError {
+  s.hashesInProgress.Add(1)
+  if s.shuttingDown.Load() {
+    s.hashesInProgress.Add(-1)
+    return NewError(CodeShuttingDown, "Server is shutting down")
+  }
+  return nil
+}
+
+// the handler's Service, or the default one
+func serviceOrDefault(s *Service) *Service {
+  if s == nil {
+    return DefaultService()
+  }
+  return s
+}
+
+// hashes on the pool after the artificial delay. callers track hashesInProgress.
+// the delay happens on the caller's goroutine so it doesn't hold up a worker
+func (s *Service) delayedHash(ctx context.Context, lane Lane, password string, started func()) (string, error) {
+  d := s.Delay.Next()
+  fmt.Printf("Waiting %v before returning hash\n", d)
+  if err := sleep(ctx, s.Clock, d); err != nil {
+    return "", err
+  }
+  started()
+  return s.Pool.Hash(ctx, lane, password)
+}
+
+// turns an error from HashPool.Hash into an APIError
+func hashError(err error) *APIError {
+  if err == ErrQueueFull {
+    return NewError(CodeQueueFull, "Too much hashing work queued")
+  }
+  // usually the caller went away, in which case nobody reads this
+  return NewError(CodeInternal, err.Error())
+}
+
+// adds a /hash response time to the legacy Total and Average
+func (s *Service) addHashResponseTime(elapsed time.Duration) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  s.summedHashResponseTimes = addSummedResponseTime(elapsed, s.summedHashResponseTimes)
+}
+
+// the legacy Total and Average for the /stats endpoint
+func (s *Service) hashResponseTimes() (int, float64) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  return len(s.summedHashResponseTimes), calcAverageResponseTime(s.summedHashResponseTimes)
+}
@@ -0,0 +1,207 @@
+package handlers_test
+
+import (
+  "testing"
+  "context"
+  "encoding/json"
+  "io/ioutil"
+  "net/http"
+  "strings"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers"
+  "github.com/rdibari84/GoHTTP/handlers/handlerstest"
+)
+
+const angryMonkeyHash = "ZEHhWB65gUlzdVwtDQArEyx+KVLzp/aTaRaPlBzYRIFj6vjFdqEb0Q5B8zVKCZ0vKbZPZklJz0Fd7su2A+gf7Q=="
+
+//////////////////////////////////////////////
+////////////// Service Unit Tests ////////////
+//////////////////////////////////////////////
+
+func TestServicesAreIsolated(t *testing.T) {
+  t.Parallel()
+  one := handlerstest.NewServer(t, handlers.Config{})
+  two := handlerstest.NewServer(t, handlers.Config{})
+
+  if hash := postHash(t, one.URL, "angryMonkey"); hash != angryMonkeyHash {
+    t.Errorf("Expected %s. got %s", angryMonkeyHash, hash)
+  }
+  if stats := getStats(t, one.URL); stats.Total != 1 {
+    t.Errorf("Expected the first server to count its hash. got %d", stats.Total)
+  }
+  if stats := getStats(t, two.URL); stats.Total != 0 || len(stats.Endpoints) != 0 {
+    t.Errorf("Expected the second server to have no stats. got %+v", stats)
+  }
+}
+
+func TestHashWaitsOnClock(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{Delay: handlers.FixedDelay{Delay: time.Minute}})
+
+  done := make(chan string)
+  go func() {
+    done <- postHash(t, srv.URL, "angryMonkey")
+  }()
+
+  srv.Clock.WaitForSleepers(1)
+  srv.Clock.Advance(59 * time.Second)
+  select {
+    case <-done:
+      t.Fatalf("Expected the hash to wait for the whole delay")
+    case <-time.After(10 * time.Millisecond):
+  }
+  srv.Clock.Advance(time.Second)
+  if hash := <-done; hash != angryMonkeyHash {
+    t.Errorf("Expected the angryMonkey hash once the delay passed. got %s", hash)
+  }
+
+  // the response time comes from the clock too
+  stats := getStats(t, srv.URL)
+  if stats.Total != 1 || stats.Average != 60e6 {
+    t.Errorf("Expected one hash taking exactly 60s. got %d %v", stats.Total, stats.Average)
+  }
+}
+
+func TestHashStopsWhenCallerGoesAway(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{Delay: handlers.FixedDelay{Delay: time.Hour}})
+
+  ctx, cancel := context.WithCancel(context.Background())
+  req, _ := http.NewRequestWithContext(ctx, "POST", srv.URL + "/hash", strings.NewReader("password=angryMonkey"))
+  req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+  errs := make(chan error)
+  go func() {
+    _, err := http.DefaultClient.Do(req)
+    errs <- err
+  }()
+
+  srv.Clock.WaitForSleepers(1)
+  cancel()
+  if err := <-errs; err == nil {
+    t.Errorf("Expected the request to be cancelled")
+  }
+  // the handler gives up without the clock ever moving; the request is recorded once it returns
+  deadline := time.Now().Add(5 * time.Second)
+  for len(srv.Service.Stats.Snapshot()) == 0 && time.Now().Before(deadline) {
+    time.Sleep(time.Millisecond)
+  }
+  if len(srv.Service.Stats.Snapshot()) == 0 {
+    t.Errorf("Expected the abandoned hash to stop early")
+  }
+  if stats := getStats(t, srv.URL); stats.Total != 0 {
+    t.Errorf("Expected no hash to be counted. got %d", stats.Total)
+  }
+}
+
+func TestAsyncJobUsesClock(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{Delay: handlers.FixedDelay{Delay: 5 * time.Second}})
+
+  resp, err := http.Post(srv.URL + "/hash", "application/x-www-form-urlencoded", strings.NewReader("password=angryMonkey&async=true"))
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  job := handlers.Job{}
+  json.NewDecoder(resp.Body).Decode(&job)
+  resp.Body.Close()
+  if !job.Created.Equal(srv.Clock.Now()) {
+    t.Errorf("Expected the job to be created at %v. got %v", srv.Clock.Now(), job.Created)
+  }
+
+  srv.Clock.WaitForSleepers(1)
+  srv.Clock.Advance(5 * time.Second)
+  deadline := time.Now().Add(5 * time.Second)
+  for job.Status == handlers.JobPending && time.Now().Before(deadline) {
+    job, _ = srv.Service.Jobs.Get(job.ID)
+    time.Sleep(time.Millisecond)
+  }
+  if job.Status != handlers.JobDone || job.Hash != angryMonkeyHash || job.Finished.Sub(job.Created) != 5 * time.Second {
+    t.Errorf("Expected a job finished 5s after it was created. got %+v", job)
+  }
+}
+
+func TestCancelPendingJob(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{Delay: handlers.FixedDelay{Delay: time.Minute}})
+
+  resp, err := http.Post(srv.URL + "/hash", "application/x-www-form-urlencoded", strings.NewReader("password=angryMonkey&async=true"))
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  job := handlers.Job{}
+  json.NewDecoder(resp.Body).Decode(&job)
+  resp.Body.Close()
+  srv.Clock.WaitForSleepers(1)
+  srv.Clock.Advance(time.Second)
+
+  resp = jobRequest(t, "DELETE", srv.URL + "/hash/" + job.ID)
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  json.Unmarshal(body, &job)
+  if resp.StatusCode != 200 || job.Status != handlers.JobCancelled || job.Duration != 1e6 || !job.Started.IsZero() {
+    t.Errorf("Expected 200 with a job cancelled a second in, before hashing began. got %d %+v", resp.StatusCode, job)
+  }
+  if strings.Contains(string(body), "angryMonkey") || job.Algorithm != "sha512" {
+    t.Errorf("Expected the algorithm and never the password. got %s", body)
+  }
+
+  resp = jobRequest(t, "DELETE", srv.URL + "/hash/" + job.ID)
+  m := handlers.ErrorMessage{}
+  json.NewDecoder(resp.Body).Decode(&m)
+  resp.Body.Close()
+  if resp.StatusCode != 409 || m.Code != handlers.CodeJobFinished {
+    t.Errorf("Expected 409 job_finished for a job that's already cancelled. got %d %+v", resp.StatusCode, m)
+  }
+
+  resp = jobRequest(t, "GET", srv.URL + "/hash?status=cancelled")
+  list := handlers.JobList{}
+  json.NewDecoder(resp.Body).Decode(&list)
+  resp.Body.Close()
+  if len(list.Jobs) != 1 || list.Jobs[0].ID != job.ID {
+    t.Errorf("Expected the cancelled job to be listed. got %+v", list)
+  }
+  for _, query := range []string{"status=stuck", "limit=0", "limit=1001", "limit=ten", "cursor=nope"} {
+    resp = jobRequest(t, "GET", srv.URL + "/hash?" + query)
+    resp.Body.Close()
+    if resp.StatusCode != 400 {
+      t.Errorf("Expected 400 for %s. got %d", query, resp.StatusCode)
+    }
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+func postHash(t *testing.T, url string, password string) string {
+  resp, err := http.Post(url + "/hash", "application/x-www-form-urlencoded", strings.NewReader("password=" + password))
+  if err != nil {
+    t.Errorf("Expected no error. Error: %s", err)
+    return ""
+  }
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  return string(body)
+}
+
+func getStats(t *testing.T, url string) handlers.Stats {
+  resp, err := http.Get(url + "/stats")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  defer resp.Body.Close()
+  stats := handlers.Stats{}
+  if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+    t.Fatalf("Expected stats json. Error: %s", err)
+  }
+  return stats
+}
+
+func jobRequest(t *testing.T, method string, url string) *http.Response {
+  req, _ := http.NewRequest(method, url, nil)
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  return resp
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "time"
+)
+
+//////////////////////////////////////////////
+///////////////// Shutdown ///////////////////
+//////////////////////////////////////////////
+
+// the states a ShutdownStatus reports
+const (
+  ShutdownRunning = "running"
+  ShutdownScheduled = "scheduled"
+  ShutdownDraining = "draining"
+)
+
+// ShutdownStatus is what /shutdown answers with
+type ShutdownStatus struct {
+  State string // running, scheduled or draining
+  At time.Time `json:",omitzero"` // when the scheduled shutdown starts draining, or started
+  Deadline time.Time `json:",omitzero"` // when the drain gives up on the hashing in progress; it waits as long as it takes when missing
+  HashesInProgress int // sync and async hashes the drain waits on
+  PendingJobs int // async jobs that haven't finished
+  RequestsInFlight int // requests being served right now, this one included
+}
+
+// ScheduleShutdown starts draining after the delay, then calls stop once the hashing in progress has finished. with a
+// drain timeout the drain gives up after that long and stop's context is done from then on. scheduling again replaces
+// the shutdown waiting to start; without a delay new hashing work is refused straight away
+func (s *Service) ScheduleShutdown(after time.Duration, drain time.Duration, stop func(context.Context)) (ShutdownStatus, *APIError) {
+  s.shutdownMu.Lock()
+  if s.shuttingDown.Load() {
+    s.shutdownMu.Unlock()
+    return ShutdownStatus{}, NewError(CodeShuttingDown, "Server is already shutting down")
+  }
+  if s.shutdownCancel != nil {
+    close(s.shutdownCancel)
+    s.shutdownCancel = nil
+  }
+  s.shutdownAt, s.shutdownDrain = s.Clock.Now().Add(after), drain
+  cancel := make(chan struct{})
+  if after > 0 {
+    s.shutdownCancel = cancel
+  } else {
+    s.startDraining()
+  }
+  s.shutdownMu.Unlock()
+
+  go func() {
+    if after > 0 {
+      select {
+        case <-cancel:
+          return
+        case <-s.Clock.After(after):
+      }
+      if !s.startScheduledDrain(cancel) {
+        return
+      }
+    }
+    s.finishShutdown(drain, stop)
+  }()
+  return s.ShutdownStatus(), nil
+}
+
+// CancelShutdown calls off the scheduled shutdown, as long as it hasn't started draining
+func (s *Service) CancelShutdown() (ShutdownStatus, *APIError) {
+  s.shutdownMu.Lock()
+  if s.shuttingDown.Load() {
+    s.shutdownMu.Unlock()
+    return ShutdownStatus{}, NewError(CodeShuttingDown, "Server is already shutting down")
+  }
+  if s.shutdownCancel == nil {
+    s.shutdownMu.Unlock()
+    return ShutdownStatus{}, NewError(CodeShutdownNotScheduled, "No shutdown is scheduled")
+  }
+  close(s.shutdownCancel)
+  s.shutdownCancel, s.shutdownAt, s.shutdownDrain = nil, time.Time{}, 0
+  s.shutdownMu.Unlock()
+  return s.ShutdownStatus(), nil
+}
+
+// ShutdownStatus reports whether a shutdown is scheduled or draining, and the work it waits on
+func (s *Service) ShutdownStatus() ShutdownStatus {
+  s.shutdownMu.Lock()
+  defer s.shutdownMu.Unlock()
+  status := ShutdownStatus{
+    State: ShutdownRunning,
+    HashesInProgress: int(s.hashesInProgress.Load()),
+    PendingJobs: s.Jobs.Pending(),
+    RequestsInFlight: int(s.requestsInFlight.Load()),
+  }
+  switch {
+    case s.shuttingDown.Load():
+      status.State = ShutdownDraining
+    case s.shutdownCancel != nil:
+      status.State = ShutdownScheduled
+    default:
+      return status
+  }
+  // a drain started by a restart or the gRPC Shutdown has neither
+  status.At = s.shutdownAt
+  if s.shutdownDrain > 0 && !s.shutdownAt.IsZero() {
+    status.Deadline = s.shutdownAt.Add(s.shutdownDrain)
+  }
+  return status
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// starts draining for the shutdown whose cancel channel this is, unless it was called off or replaced meanwhile,
+// or something else has started the drain already
+func (s *Service) startScheduledDrain(cancel chan struct{}) bool {
+  s.shutdownMu.Lock()
+  defer s.shutdownMu.Unlock()
+  if s.shutdownCancel != cancel || s.shuttingDown.Load() {
+    return false
+  }
+  s.shutdownCancel, s.shutdownAt = nil, s.Clock.Now()
+  s.startDraining()
+  return true
+}
+
+// waits for the hashing in progress, for no longer than drain when it's set, then stops
+func (s *Service) finishShutdown(drain time.Duration, stop func(context.Context)) {
+  ctx, cancel := context.WithCancel(context.Background())
+  defer cancel()
+  if drain > 0 {
+    deadline := s.Clock.After(drain)
+    go func() {
+      select {
+        case <-deadline:
+          cancel()
+        case <-ctx.Done():
+      }
+    }()
+  }
+  if err := s.Drain(ctx); err != nil {
+    fmt.Printf("Gave up on %d hashes in progress after %v\n", s.hashesInProgress.Load(), drain)
+  }
+  fmt.Printf("Received shutdown request... shutting down\n")
+  stop(ctx)
+}
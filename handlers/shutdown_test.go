@@ -0,0 +1,212 @@
+package handlers_test
+
+import (
+  "testing"
+  "encoding/json"
+  "io/ioutil"
+  "net/http"
+  "net/http/httptest"
+  "net/url"
+  "strings"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers"
+  "github.com/rdibari84/GoHTTP/handlers/handlerstest"
+  "github.com/rdibari84/GoHTTP/handlers/openapi/openapitest"
+)
+
+//////////////////////////////////////////////
+///////////// Shutdown Unit Tests ////////////
+//////////////////////////////////////////////
+
+func TestScheduledShutdownWaitsForItsDelay(t *testing.T) {
+  clock := handlerstest.NewFakeClock()
+  ts, _ := newShutdownServer(t, handlers.Config{Clock: clock})
+  start := clock.Now()
+
+  code, status := shutdownRequest(t, ts, "POST", url.Values{"after": {"30s"}, "deadline": {"1m"}})
+  if code != 202 || status.State != handlers.ShutdownScheduled {
+    t.Fatalf("Expected 202 and a scheduled shutdown. got %d %+v", code, status)
+  }
+  if !status.At.Equal(start.Add(30 * time.Second)) || !status.Deadline.Equal(start.Add(90 * time.Second)) {
+    t.Errorf("Expected the drain at +30s with a deadline at +90s. got %v %v", status.At, status.Deadline)
+  }
+
+  clock.WaitForSleepers(1)
+  clock.Advance(29 * time.Second)
+  if _, status = shutdownRequest(t, ts, "GET", nil); status.State != handlers.ShutdownScheduled {
+    t.Errorf("Expected the shutdown to still be scheduled. got %+v", status)
+  }
+  clock.Advance(time.Second)
+  waitFor(t, func() bool { return serverStopped(ts) })
+}
+
+func TestShutdownStatusLeavesOutTimesWhileRunning(t *testing.T) {
+  ts, _ := newShutdownServer(t, handlers.Config{Clock: handlerstest.NewFakeClock()})
+
+  resp, err := http.Get(ts.URL + "/shutdown")
+  if err != nil {
+    t.Fatalf("Expected no error. got %v", err)
+  }
+  defer resp.Body.Close()
+  fields := map[string]json.RawMessage{}
+  json.NewDecoder(resp.Body).Decode(&fields)
+  _, at := fields["At"]
+  _, deadline := fields["Deadline"]
+  if resp.StatusCode != 200 || string(fields["State"]) != `"running"` || at || deadline {
+    t.Errorf("Expected a running server without At or Deadline. got %d %v", resp.StatusCode, fields)
+  }
+}
+
+func TestRescheduledShutdownReplacesTheFirst(t *testing.T) {
+  clock := handlerstest.NewFakeClock()
+  ts, _ := newShutdownServer(t, handlers.Config{Clock: clock})
+
+  shutdownRequest(t, ts, "POST", url.Values{"after": {"30s"}})
+  code, status := shutdownRequest(t, ts, "POST", url.Values{"after": {"1h"}})
+  if code != 202 || !status.At.Equal(clock.Now().Add(time.Hour)) {
+    t.Fatalf("Expected the shutdown to move to an hour from now. got %d %+v", code, status)
+  }
+  clock.WaitForSleepers(1)
+  clock.Advance(time.Minute)
+  time.Sleep(20 * time.Millisecond)
+  if serverStopped(ts) {
+    t.Errorf("Expected the first schedule to be replaced")
+  }
+}
+
+func TestCancelScheduledShutdown(t *testing.T) {
+  clock := handlerstest.NewFakeClock()
+  ts, _ := newShutdownServer(t, handlers.Config{Clock: clock})
+
+  if code, _ := shutdownRequest(t, ts, "DELETE", nil); code != 409 {
+    t.Errorf("Expected 409 with nothing scheduled. got %d", code)
+  }
+  shutdownRequest(t, ts, "POST", url.Values{"after": {"30s"}})
+  code, status := shutdownRequest(t, ts, "DELETE", nil)
+  if code != 200 || status.State != handlers.ShutdownRunning || !status.At.IsZero() {
+    t.Errorf("Expected 200 and a running server. got %d %+v", code, status)
+  }
+
+  clock.Advance(time.Minute)
+  time.Sleep(20 * time.Millisecond)
+  if serverStopped(ts) {
+    t.Fatalf("Expected the cancelled shutdown not to fire")
+  }
+  if hash := postHash(t, ts.URL, "angryMonkey"); hash != angryMonkeyHash {
+    t.Errorf("Expected the server to keep hashing. got %s", hash)
+  }
+}
+
+func TestShutdownDrainDeadline(t *testing.T) {
+  clock := handlerstest.NewFakeClock()
+  ts, service := newShutdownServer(t, handlers.Config{Clock: clock, Delay: handlers.FixedDelay{Delay: 5 * time.Minute}})
+
+  hashed := make(chan struct{})
+  go func() {
+    defer close(hashed)
+    resp, err := http.PostForm(ts.URL + "/hash", url.Values{"password": {"angryMonkey"}})
+    if err == nil {
+      resp.Body.Close()
+    }
+  }()
+  clock.WaitForSleepers(1)
+
+  code, status := shutdownRequest(t, ts, "POST", url.Values{"deadline": {"1m"}})
+  if code != 202 || status.State != handlers.ShutdownDraining {
+    t.Fatalf("Expected 202 and a draining server. got %d %+v", code, status)
+  }
+  if status.HashesInProgress != 1 || status.RequestsInFlight != 2 {
+    t.Errorf("Expected the hash in progress and two requests in flight. got %+v", status)
+  }
+  if code, _ := shutdownRequest(t, ts, "DELETE", nil); code != 503 {
+    t.Errorf("Expected 503 cancelling a drain under way. got %d", code)
+  }
+
+  // the drain gives up on the hash a minute in, well before its delay is up
+  clock.WaitForSleepers(2)
+  clock.Advance(time.Minute)
+  waitFor(t, func() bool { return serverStopped(ts) })
+  if service.DebugVars().HashesInProgress != 1 {
+    t.Errorf("Expected the server to stop with the hash still in progress")
+  }
+  clock.Advance(5 * time.Minute)
+  <-hashed
+}
+
+func TestShutdownNeedsTheAdminToken(t *testing.T) {
+  ts, _ := newShutdownServer(t, handlers.Config{AdminToken: "s3cret"})
+
+  for _, token := range []string{"", "nope"} {
+    for _, method := range []string{"GET", "POST", "DELETE"} {
+      if code := shutdownWithToken(t, ts, method, token); code != 401 {
+        t.Errorf("Expected 401 for %s /shutdown with token %q. got %d", method, token, code)
+      }
+    }
+  }
+  if hash := postHash(t, ts.URL, "angryMonkey"); hash != angryMonkeyHash {
+    t.Fatalf("Expected the refused shutdowns to leave the server hashing. got %s", hash)
+  }
+
+  if code := shutdownWithToken(t, ts, "POST", "s3cret"); code != 202 && code != 0 {
+    t.Errorf("Expected 202, or the connection to close, with the admin token. got %d", code)
+  }
+  waitFor(t, func() bool { return serverStopped(ts) })
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// serves every route, /shutdown included, on a server the shutdown really stops
+func newShutdownServer(t *testing.T, cfg handlers.Config) (*httptest.Server, *handlers.Service) {
+  if cfg.Pool == nil {
+    cfg.Pool = handlers.NewHashPool(2, 16)
+    t.Cleanup(cfg.Pool.Close)
+  }
+  if cfg.Delay == nil {
+    cfg.Delay = handlers.NoDelay{}
+  }
+  service := handlers.NewService(cfg)
+  ts := httptest.NewUnstartedServer(nil)
+  ts.Config.Handler = openapitest.Check(t, service.Routes(ts.Config))
+  ts.Start()
+  t.Cleanup(ts.Close)
+  return ts, service
+}
+
+func shutdownRequest(t *testing.T, ts *httptest.Server, method string, form url.Values) (int, handlers.ShutdownStatus) {
+  req, _ := http.NewRequest(method, ts.URL + "/shutdown", strings.NewReader(form.Encode()))
+  req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+    t.Fatalf("Expected no error. got %v", err)
+  }
+  defer resp.Body.Close()
+  body, _ := ioutil.ReadAll(resp.Body)
+  status := handlers.ShutdownStatus{}
+  json.Unmarshal(body, &status)
+  return resp.StatusCode, status
+}
+
+// whether the server has stopped taking connections
+func serverStopped(ts *httptest.Server) bool {
+  resp, err := http.Get(ts.URL + "/shutdown")
+  if err != nil {
+    return true
+  }
+  resp.Body.Close()
+  return false
+}
+
+func shutdownWithToken(t *testing.T, ts *httptest.Server, method string, token string) int {
+  req, _ := http.NewRequest(method, ts.URL + "/shutdown", nil)
+  if token != "" {
+    req.Header.Set("Authorization", "Bearer " + token)
+  }
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+    return 0 // the server may close the connection as it stops
+  }
+  resp.Body.Close()
+  return resp.StatusCode
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "sort"
+    "sync"
+    "time"
+)
+
+//////////////////////////////////////////////
+///////////// Request Statistics /////////////
+//////////////////////////////////////////////
+
+// per route/method/status breakdown returned as part of the /stats message
+type EndpointStats struct {
+  Route string
+  Method string
+  Status int
+  Count int
+  Average float64 // average response time in microseconds
+  Max float64 // slowest response time in microseconds
+  Errors map[string]int `json:",omitempty"` // error code (or message for legacy errors) -> number of times it was returned
+}
+
+// key used to group recorded requests
+type endpointKey struct {
+  route string
+  method string
+  status int
+}
+
+// running totals for one endpointKey
+type endpointTotals struct {
+  count int
+  sum time.Duration
+  max time.Duration
+  errors map[string]int
+}
+
+// StatsRecorder keeps request counts and latencies for every request that passes through Service.RecordStats
+type StatsRecorder struct {
+  mu sync.Mutex
+  endpoints map[endpointKey]*endpointTotals
+  protocols map[string]int // requests per protocol, e.g. HTTP/1.1 or HTTP/2.0
+}
+
+func NewStatsRecorder() *StatsRecorder {
+  return &StatsRecorder{endpoints: make(map[endpointKey]*endpointTotals), protocols: make(map[string]int)}
+}
+
+// CountProtocol adds a request served over proto
+func (s *StatsRecorder) CountProtocol(proto string) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  s.protocols[proto]++
+}
+
+// Protocols returns how many requests each protocol has served
+func (s *StatsRecorder) Protocols() map[string]int {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  protocols := make(map[string]int, len(s.protocols))
+  for proto, count := range s.protocols {
+    protocols[proto] = count
+  }
+  return protocols
+}
+
+// Record adds a single request to the recorder. reason is the error message returned to the caller, if any
+func (s *StatsRecorder) Record(route string, method string, status int, elapsed time.Duration, reason string) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  key := endpointKey{route: route, method: method, status: status}
+  totals, ok := s.endpoints[key]
+  if !ok {
+    totals = &endpointTotals{}
+    s.endpoints[key] = totals
+  }
+  totals.count++
+  totals.sum += elapsed
+  if elapsed > totals.max {
+    totals.max = elapsed
+  }
+  if reason != "" {
+    if totals.errors == nil {
+      totals.errors = make(map[string]int)
+    }
+    totals.errors[reason]++
+  }
+}
+
+// Reset forgets every request recorded so far
+func (s *StatsRecorder) Reset() {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  s.endpoints = make(map[endpointKey]*endpointTotals)
+  s.protocols = make(map[string]int)
+}
+
+// Snapshot returns the recorded stats sorted by route, method and status
+func (s *StatsRecorder) Snapshot() []EndpointStats {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+  snapshot := make([]EndpointStats, 0, len(s.endpoints))
+  for key, totals := range s.endpoints {
+    e := EndpointStats{
+      Route: key.route,
+      Method: key.method,
+      Status: key.status,
+      Count: totals.count,
+      Average: microseconds(totals.sum) / float64(totals.count),
+      Max: microseconds(totals.max),
+    }
+    if len(totals.errors) > 0 {
+      e.Errors = make(map[string]int, len(totals.errors))
+      for reason, count := range totals.errors {
+        e.Errors[reason] = count
+      }
+    }
+    snapshot = append(snapshot, e)
+  }
+  sort.Slice(snapshot, func(i, j int) bool {
+    if snapshot[i].Route != snapshot[j].Route {
+      return snapshot[i].Route < snapshot[j].Route
+    }
+    if snapshot[i].Method != snapshot[j].Method {
+      return snapshot[i].Method < snapshot[j].Method
+    }
+    return snapshot[i].Status < snapshot[j].Status
+  })
+  return snapshot
+}
+
+// LogRequests writes a line to out for every request: request id, protocol, method, path, status and how long it took
+func LogRequests(out io.Writer, next http.Handler) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    start := time.Now()
+    sw := &statusWriter{ResponseWriter: w}
+    next.ServeHTTP(sw, r)
+    fmt.Fprintf(out, "%s %s %s %s %d %v\n", RequestIDFrom(r.Context()), r.Proto, r.Method, r.URL.Path, sw.status(), time.Since(start))
+  })
+}
+
+// NotFoundHandler answers any path that no other handler is registered for
+type NotFoundHandler struct {}
+// needs a ServeHTTP method from HandlerFunc Interface
+func (n *NotFoundHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  writeError(w, r, NewError(CodeNotFound, "Resource not found"))
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// statusWriter captures the status code and error reason written by a handler
+type statusWriter struct {
+  http.ResponseWriter
+  code int
+  reason string
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+  if sw.code == 0 {
+    sw.code = code
+  }
+  sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+  if sw.code == 0 {
+    sw.code = http.StatusOK
+  }
+  return sw.ResponseWriter.Write(b)
+}
+
+// lets http.ResponseController reach the underlying writer (flushing, deadlines)
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+  return sw.ResponseWriter
+}
+
+// lets websocket upgrades take over the connection. the request is recorded as a 101
+func (sw *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+  if sw.code == 0 {
+    sw.code = http.StatusSwitchingProtocols
+  }
+  return http.NewResponseController(sw.ResponseWriter).Hijack()
+}
+
+func (sw *statusWriter) status() int {
+  if sw.code == 0 { // handler never wrote anything; net/http sends a 200
+    return http.StatusOK
+  }
+  return sw.code
+}
+
+// setErrorReason hands the error message to the statusWriter, if there is one in the writer chain
+func setErrorReason(w http.ResponseWriter, reason string) {
+  for {
+    switch v := w.(type) {
+      case *statusWriter:
+        v.reason = reason
+        return
+      case interface{ Unwrap() http.ResponseWriter }:
+        w = v.Unwrap()
+      default:
+        return
+    }
+  }
+}
+
+func microseconds(d time.Duration) float64 {
+  return float64(d) / float64(time.Microsecond)
+}
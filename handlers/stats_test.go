@@ -0,0 +1,147 @@
+package handlers
+
+import (
+  "testing"
+  "bytes"
+  "net/http"
+  "net/http/httptest"
+  "io/ioutil"
+  "strings"
+  "encoding/json"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers/openapi/openapitest"
+)
+
+//////////////////////////////////////////////
+////////// Stats Recorder Unit Tests /////////
+//////////////////////////////////////////////
+
+func TestStatsRecorderGroupsByRouteMethodStatus(t *testing.T) {
+  s := NewStatsRecorder()
+  s.Record("/hash", "POST", 200, 2*time.Microsecond, "")
+  s.Record("/hash", "POST", 200, 4*time.Microsecond, "")
+  s.Record("/hash", "POST", 400, 1*time.Microsecond, "Missing input data in request")
+  s.Record("/hash", "GET", 404, 1*time.Microsecond, "GET is not supported")
+
+  snapshot := s.Snapshot()
+  if len(snapshot) != 3 {
+    t.Fatalf("Expected 3 endpoint groups. got %d", len(snapshot))
+  }
+  // sorted by route, method then status
+  if snapshot[0].Method != "GET" || snapshot[1].Status != 200 || snapshot[2].Status != 400 {
+    t.Errorf("Expected snapshot sorted by route, method and status. got %+v", snapshot)
+  }
+  if snapshot[1].Count != 2 {
+    t.Errorf("Expected 2 successful POSTs. got %d", snapshot[1].Count)
+  }
+  if snapshot[1].Average != 3 {
+    t.Errorf("Expected average of 3 microseconds. got %f", snapshot[1].Average)
+  }
+  if snapshot[1].Max != 4 {
+    t.Errorf("Expected max of 4 microseconds. got %f", snapshot[1].Max)
+  }
+  if snapshot[2].Errors["Missing input data in request"] != 1 {
+    t.Errorf("Expected error reason to be counted. got %v", snapshot[2].Errors)
+  }
+}
+
+func TestRecordStatsCapturesErrorReasons(t *testing.T) {
+  service := newTestService(t)
+  ts := httptest.NewServer(openapitest.Check(t, service.RecordStats("/hash", &HashHandler{Service: service})))
+  defer ts.Close()
+
+  resp, err := http.Post(ts.URL + "/hash", "application/x-www-form-urlencoded", strings.NewReader(""))
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+
+  snapshot := service.Stats.Snapshot()
+  if len(snapshot) != 1 {
+    t.Fatalf("Expected 1 endpoint group. got %d", len(snapshot))
+  }
+  e := snapshot[0]
+  if e.Route != "/hash" || e.Method != "POST" || e.Status != 400 || e.Count != 1 {
+    t.Errorf("Expected one POST /hash 400. got %+v", e)
+  }
+  if e.Errors["missing_password"] != 1 {
+    t.Errorf("Expected error reason to be recorded. got %v", e.Errors)
+  }
+}
+
+func TestRecordStatsDefaultsTo200(t *testing.T) {
+  service := newTestService(t)
+  ts := httptest.NewServer(openapitest.Check(t, service.RecordStats("/empty", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))))
+  defer ts.Close()
+
+  resp, err := http.Get(ts.URL)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+
+  snapshot := service.Stats.Snapshot()
+  if len(snapshot) != 1 || snapshot[0].Status != 200 {
+    t.Errorf("Expected a single 200 to be recorded. got %+v", snapshot)
+  }
+}
+
+func TestStatsEndpointIncludesEndpointBreakdown(t *testing.T) {
+  service := newTestService(t)
+  mux := http.NewServeMux()
+  mux.Handle("/stats", service.RecordStats("/stats", &StatsHandler{Service: service}))
+  mux.Handle("/", service.RecordStats("unmatched", &NotFoundHandler{}))
+  ts := httptest.NewServer(openapitest.Check(t, mux))
+  defer ts.Close()
+
+  resp, err := http.Get(ts.URL + "/nothing-here")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != 404 {
+    t.Errorf("Expected 404 error code. Got %d", resp.StatusCode)
+  }
+
+  resp, err = http.Get(ts.URL + "/stats")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+
+  stats := Stats{}
+  if err := json.Unmarshal(body, &stats); err != nil {
+    t.Fatalf("Expected stats json. Error: %s", err)
+  }
+  if len(stats.Endpoints) != 1 {
+    t.Fatalf("Expected only the unmatched request to be recorded so far. got %+v", stats.Endpoints)
+  }
+  if stats.Endpoints[0].Route != "unmatched" || stats.Endpoints[0].Status != 404 {
+    t.Errorf("Expected unmatched 404. got %+v", stats.Endpoints[0])
+  }
+  if stats.Endpoints[0].Errors["not_found"] != 1 {
+    t.Errorf("Expected not found reason. got %v", stats.Endpoints[0].Errors)
+  }
+  if stats.Protocols["HTTP/1.1"] != 1 {
+    t.Errorf("Expected the unmatched request counted under HTTP/1.1. got %v", stats.Protocols)
+  }
+}
+
+func TestLogRequestsIncludesProtocol(t *testing.T) {
+  var out bytes.Buffer
+  ts := httptest.NewServer(openapitest.Check(t, RequestID(LogRequests(&out, &NotFoundHandler{}))))
+  defer ts.Close()
+
+  req, _ := http.NewRequest("GET", ts.URL + "/nothing-here", nil)
+  req.Header.Set("X-Request-ID", "abc123")
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+
+  if !strings.HasPrefix(out.String(), "abc123 HTTP/1.1 GET /nothing-here 404 ") {
+    t.Errorf("Expected the request id, protocol, method, path and status to be logged. got %q", out.String())
+  }
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "time"
+    "github.com/gorilla/websocket"
+)
+
+//////////////////////////////////////////////
+/////////////////// Streams //////////////////
+//////////////////////////////////////////////
+
+// the shortest interval a /stats/stream caller can ask for
+var MinStatsInterval = 100 * time.Millisecond
+
+// methods the stream handlers answer; sent back in the Allow header
+var streamMethods = []string{"GET", "OPTIONS"}
+
+// StatsStreamHandler pushes the /stats message as a server-sent event every StatsInterval, or ?interval=.
+// the stream ends with a shutdown event once the server starts draining
+type StatsStreamHandler struct {
+  Service *Service // DefaultService() when nil
+}
+// needs a ServeHTTP method from HandlerFunc Interface
+func (h *StatsStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  s := serviceOrDefault(h.Service)
+  switch r.Method {
+    case "GET":
+      interval := s.StatsInterval
+      if v := r.URL.Query().Get("interval"); v != "" {
+        d, err := time.ParseDuration(v)
+        if err != nil || d < MinStatsInterval {
+          writeError(w, r, NewError(CodeInvalidParameter, fmt.Sprintf("interval must be a duration of at least %v", MinStatsInterval)))
+          return
+        }
+        interval = d
+      }
+      rc := http.NewResponseController(w)
+      w.Header().Set("Content-Type", "text/event-stream")
+      w.Header().Set("Cache-Control", "no-cache")
+      w.WriteHeader(http.StatusOK)
+      for {
+        if err := writeEvent(w, "stats", s.Snapshot()); err != nil {
+          return
+        }
+        if err := rc.Flush(); err != nil {
+          return
+        }
+        select {
+          case <-r.Context().Done(): // caller went away
+            return
+          case <-s.stopping:
+            writeEvent(w, "shutdown", ErrorMessage{Error: "Server is shutting down", Code: CodeShuttingDown})
+            rc.Flush()
+            return
+          case <-s.Clock.After(interval):
+        }
+      }
+    case "OPTIONS":
+      writeOptions(w, streamMethods)
+    default:
+      writeMethodNotAllowed(w, r, streamMethods)
+  }
+}
+
+// JobSubmission is a message a client sends to /hash/ws to start an async hash
+type JobSubmission struct {
+  Ref string `json:",omitempty"` // echoed back on every event about this submission
+  Password string
+}
+
+// job event types
+const (
+  JobEventAccepted = "accepted" // the job was created; Job is pending
+  JobEventFinished = "finished" // Job is done or failed
+  JobEventError = "error" // the submission was refused; see Error
+)
+
+// JobEvent is a message /hash/ws sends back
+type JobEvent struct {
+  Type string
+  Ref string `json:",omitempty"`
+  Job *Job `json:",omitempty"`
+  Error *ErrorMessage `json:",omitempty"`
+}
+
+// same origin only, like a browser would enforce without CORS
+var jobUpgrader = websocket.Upgrader{}
+
+// JobSocketHandler starts an async hash for every JobSubmission received over a websocket and sends a JobEvent
+// when it is accepted and another when it finishes. once the server starts draining the socket is closed with
+// 1001 (going away) after the jobs it started have finished
+type JobSocketHandler struct {
+  Service *Service // DefaultService() when nil
+}
+// needs a ServeHTTP method from HandlerFunc Interface
+func (h *JobSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  s := serviceOrDefault(h.Service)
+  switch r.Method {
+    case "GET":
+      if !websocket.IsWebSocketUpgrade(r) {
+        w.Header().Set("Upgrade", "websocket")
+        writeError(w, r, NewError(CodeUpgradeRequired, "Connect with a websocket client"))
+        return
+      }
+      conn, err := jobUpgrader.Upgrade(w, r, nil)
+      if err != nil { // Upgrade has already answered
+        return
+      }
+      defer conn.Close()
+      s.serveJobSocket(r.Context(), conn, RequestIDFrom(r.Context()))
+    case "OPTIONS":
+      writeOptions(w, streamMethods)
+    default:
+      writeMethodNotAllowed(w, r, streamMethods)
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// writes one server-sent event with v as json data
+func writeEvent(w io.Writer, event string, v interface{}) error {
+  data, err := json.Marshal(v)
+  if err != nil {
+    return err
+  }
+  _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+  return err
+}
+
+// reads submissions on one goroutine and writes every event on this one, since a websocket has a single writer.
+// ctx is the upgraded request's, which says whose jobs they are
+func (s *Service) serveJobSocket(ctx context.Context, conn *websocket.Conn, requestID string) {
+  events := make(chan JobEvent)
+  quit := make(chan struct{}) // closed once nobody is writing events any more
+  defer close(quit)
+  send := func(e JobEvent) bool {
+    select {
+      case events <- e:
+        return true
+      case <-quit:
+        return false
+    }
+  }
+
+  readDone := make(chan struct{})
+  go func() {
+    defer close(readDone)
+    for {
+      _, message, err := conn.ReadMessage()
+      if err != nil { // closed by the client, or by us
+        return
+      }
+      sub := JobSubmission{}
+      if err := json.Unmarshal(message, &sub); err != nil {
+        if !send(JobEvent{Type: JobEventError, Error: &ErrorMessage{Error: "Messages must be a json JobSubmission", Code: CodeInvalidMessage, RequestID: requestID}}) {
+          return
+        }
+        continue
+      }
+      job, apiErr := s.HashAsync(ctx, sub.Password, "")
+      if apiErr != nil {
+        if !send(JobEvent{Type: JobEventError, Ref: sub.Ref, Error: &ErrorMessage{Error: apiErr.Message, Code: apiErr.Code, RequestID: requestID}}) {
+          return
+        }
+        continue
+      }
+      // accepted is always sent before finished, so the writer's pending count never goes negative
+      if !send(JobEvent{Type: JobEventAccepted, Ref: sub.Ref, Job: &job}) {
+        return
+      }
+      go func(ref string, id string) {
+        <-s.Jobs.Done(id)
+        finished, _ := s.Jobs.Get(id)
+        send(JobEvent{Type: JobEventFinished, Ref: ref, Job: &finished})
+      }(sub.Ref, job.ID)
+    }
+  }()
+
+  pending := 0 // jobs accepted on this socket that haven't finished
+  draining := false
+  stopping := s.stopping
+  for {
+    select {
+      case e := <-events:
+        switch e.Type {
+          case JobEventAccepted:
+            pending++
+          case JobEventFinished:
+            pending--
+        }
+        if err := conn.WriteJSON(e); err != nil {
+          return
+        }
+        if draining && pending == 0 {
+          closeGoingAway(conn)
+          return
+        }
+      case <-stopping:
+        if pending == 0 {
+          closeGoingAway(conn)
+          return
+        }
+        draining = true
+        stopping = nil // keep serving events until the pending jobs have finished
+      case <-readDone:
+        return
+    }
+  }
+}
+
+// tells a websocket client the server is going away
+func closeGoingAway(conn *websocket.Conn) {
+  message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+  conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+}
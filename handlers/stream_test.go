@@ -0,0 +1,172 @@
+package handlers_test
+
+import (
+  "testing"
+  "bufio"
+  "context"
+  "encoding/json"
+  "net/http"
+  "strings"
+  "time"
+  "github.com/gorilla/websocket"
+  "github.com/rdibari84/GoHTTP/handlers"
+  "github.com/rdibari84/GoHTTP/handlers/handlerstest"
+)
+
+//////////////////////////////////////////////
+////////////// Stream Unit Tests /////////////
+//////////////////////////////////////////////
+
+func TestStatsStreamPushesOnInterval(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{})
+
+  resp, err := http.Get(srv.URL + "/stats/stream?interval=2s")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  defer resp.Body.Close()
+  if resp.Header.Get("Content-Type") != "text/event-stream" {
+    t.Errorf("Expected text/event-stream. got %s", resp.Header.Get("Content-Type"))
+  }
+  events := bufio.NewReader(resp.Body)
+
+  // stats are sent straight away, then every interval
+  if event, stats := readEvent(t, events); event != "stats" || stats.Total != 0 {
+    t.Errorf("Expected the first stats event. got %s %+v", event, stats)
+  }
+  postHash(t, srv.URL, "angryMonkey")
+  srv.Clock.WaitForSleepers(1)
+  srv.Clock.Advance(2 * time.Second)
+  if event, stats := readEvent(t, events); event != "stats" || stats.Total != 1 {
+    t.Errorf("Expected a stats event counting the hash. got %s %+v", event, stats)
+  }
+
+  // a drain ends the stream
+  srv.Service.Drain(context.Background())
+  if event, _ := readEvent(t, events); event != "shutdown" {
+    t.Errorf("Expected a shutdown event. got %s", event)
+  }
+  if _, err := events.ReadString('\n'); err == nil {
+    t.Errorf("Expected the stream to end")
+  }
+}
+
+func TestStatsStreamRejectsShortInterval(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{})
+
+  resp, err := http.Get(srv.URL + "/stats/stream?interval=1ms")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != 400 {
+    t.Errorf("Expected 400. got %d", resp.StatusCode)
+  }
+}
+
+func TestJobSocketReportsJobs(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{})
+  conn := dialJobSocket(t, srv.URL)
+
+  conn.WriteJSON(handlers.JobSubmission{Ref: "one", Password: "angryMonkey"})
+  accepted := readJobEvent(t, conn)
+  if accepted.Type != handlers.JobEventAccepted || accepted.Ref != "one" || accepted.Job == nil {
+    t.Fatalf("Expected the job to be accepted. got %+v", accepted)
+  }
+  finished := readJobEvent(t, conn)
+  if finished.Type != handlers.JobEventFinished || finished.Job.ID != accepted.Job.ID || finished.Job.Hash != angryMonkeyHash {
+    t.Errorf("Expected the job to finish with the angryMonkey hash. got %+v", finished.Job)
+  }
+
+  conn.WriteJSON(handlers.JobSubmission{Ref: "two", Password: ""})
+  refused := readJobEvent(t, conn)
+  if refused.Type != handlers.JobEventError || refused.Ref != "two" || refused.Error.Code != handlers.CodePasswordTooShort {
+    t.Errorf("Expected password_too_short. got %+v", refused)
+  }
+
+  conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+  if invalid := readJobEvent(t, conn); invalid.Error == nil || invalid.Error.Code != handlers.CodeInvalidMessage {
+    t.Errorf("Expected invalid_message. got %+v", invalid)
+  }
+}
+
+func TestJobSocketClosesAfterDrain(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{Delay: handlers.FixedDelay{Delay: 5 * time.Second}})
+  conn := dialJobSocket(t, srv.URL)
+
+  conn.WriteJSON(handlers.JobSubmission{Password: "angryMonkey"})
+  readJobEvent(t, conn)
+  srv.Clock.WaitForSleepers(1)
+  go srv.Service.Drain(context.Background())
+
+  // the job that was already running still gets reported before the socket closes
+  srv.Clock.Advance(5 * time.Second)
+  if finished := readJobEvent(t, conn); finished.Type != handlers.JobEventFinished {
+    t.Errorf("Expected the running job to finish. got %+v", finished)
+  }
+  _, _, err := conn.ReadMessage()
+  if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+    t.Errorf("Expected a going away close. got %v", err)
+  }
+}
+
+func TestJobSocketNeedsUpgrade(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{})
+
+  resp, err := http.Get(srv.URL + "/hash/ws")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != http.StatusUpgradeRequired || resp.Header.Get("Upgrade") != "websocket" {
+    t.Errorf("Expected 426 with Upgrade: websocket. got %d %q", resp.StatusCode, resp.Header.Get("Upgrade"))
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// reads one server-sent event. stats is only filled in for stats events
+func readEvent(t *testing.T, events *bufio.Reader) (string, handlers.Stats) {
+  event := ""
+  stats := handlers.Stats{}
+  for {
+    line, err := events.ReadString('\n')
+    if err != nil {
+      t.Fatalf("Expected another event. Error: %s", err)
+    }
+    line = strings.TrimSuffix(line, "\n")
+    switch {
+      case line == "":
+        return event, stats
+      case strings.HasPrefix(line, "event: "):
+        event = strings.TrimPrefix(line, "event: ")
+      case strings.HasPrefix(line, "data: ") && event == "stats":
+        json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &stats)
+    }
+  }
+}
+
+func dialJobSocket(t *testing.T, url string) *websocket.Conn {
+  conn, _, err := websocket.DefaultDialer.Dial("ws" + strings.TrimPrefix(url, "http") + "/hash/ws", nil)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  t.Cleanup(func() { conn.Close() })
+  return conn
+}
+
+func readJobEvent(t *testing.T, conn *websocket.Conn) handlers.JobEvent {
+  conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+  event := handlers.JobEvent{}
+  if err := conn.ReadJSON(&event); err != nil {
+    t.Fatalf("Expected a job event. Error: %s", err)
+  }
+  return event
+}
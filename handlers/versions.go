@@ -0,0 +1,207 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+    "github.com/rdibari84/GoHTTP/handlers/openapi"
+)
+
+//////////////////////////////////////////////
+//////////////// API Versions ////////////////
+//////////////////////////////////////////////
+
+// route trees, as named in Config.Deprecations
+const (
+  VersionUnversioned = "unversioned" // paths without a version; they answer exactly like v1
+  Version1 = "v1" // the original contract: a bare text hash, unwrapped json
+  Version2 = "v2" // every json response wrapped in an Envelope, and /hash is async unless async=false
+)
+
+// Deprecation announces that a route tree is going away, on every response from it
+type Deprecation struct {
+  Since time.Time // sent as a Deprecation header; may be in the future. not sent when zero
+  Sunset time.Time // when the tree stops being served, sent as a Sunset header. not sent when zero
+  Successor string // the tree to move to, sent as a Link header with rel="successor-version"
+}
+
+// Envelope wraps every /v2 json response: Data on success, Error on failure
+type Envelope struct {
+  Data interface{} `json:",omitempty"`
+  Error *EnvelopeError `json:",omitempty"`
+  RequestID string `json:",omitempty"` // matches the X-Request-ID response header
+}
+
+// EnvelopeError says why a /v2 request failed
+type EnvelopeError struct {
+  Code ErrorCode // stable machine readable code; see ErrorCatalog
+  Message string
+}
+
+// HashResult is the Data of a synchronous /v2/hash response
+type HashResult struct {
+  Hash string
+}
+
+// ParseDeprecations reads deprecation and sunset dates for the route trees, e.g. from command line flags.
+// each is a comma separated list of version=date such as "unversioned=2026-12-01,v1=2027-03-01".
+// deprecated trees point at v2 as their successor
+func ParseDeprecations(deprecated string, sunset string) (map[string]Deprecation, error) {
+  deprecations := map[string]Deprecation{}
+  for _, list := range []struct{ spec string; sunset bool }{{deprecated, false}, {sunset, true}} {
+    for _, entry := range strings.Split(list.spec, ",") {
+      entry = strings.TrimSpace(entry)
+      if entry == "" {
+        continue
+      }
+      parts := strings.SplitN(entry, "=", 2)
+      if len(parts) != 2 {
+        return nil, fmt.Errorf("%q should look like v1=2026-12-01", entry)
+      }
+      version := strings.TrimSpace(parts[0])
+      if version != VersionUnversioned && version != Version1 && version != Version2 {
+        return nil, fmt.Errorf("unknown version %q; use unversioned, v1 or v2", version)
+      }
+      date, err := time.Parse("2006-01-02", strings.TrimSpace(parts[1]))
+      if err != nil {
+        return nil, fmt.Errorf("%q is not a date like 2026-12-01", parts[1])
+      }
+      d := deprecations[version]
+      if list.sunset {
+        d.Sunset = date
+      } else {
+        d.Since = date
+      }
+      if version != Version2 {
+        d.Successor = "/v2"
+      }
+      deprecations[version] = d
+    }
+  }
+  return deprecations, nil
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// the route tree a request came in on
+type routeTree struct {
+  version string
+  base string // path prefix the tree is mounted at; "" for unversioned paths
+}
+
+const routeTreeKey contextKey = "route-tree"
+
+// every endpoint of one version in set. routes are recorded in /stats under base
+func (s *Service) versionRoutes(srv *http.Server, version string, base string, set routeSet) *http.ServeMux {
+  hash := HashHandler{Service: s}
+  verify := VerifyHandler{Service: s}
+  stats := StatsHandler{Service: s, AllowReset: set == adminRoutes}
+  errors := ErrorsHandler{}
+  notFound := NotFoundHandler{}
+  spec := openapi.Spec
+  if version == Version2 {
+    spec = openapi.SpecV2
+  }
+
+  // the hashing endpoints need an API key once keys are set up
+  keyed := func(h http.Handler) http.Handler {
+    if s.Keys == nil {
+      return h
+    }
+    return s.RequireAPIKey(h)
+  }
+
+  mux := http.NewServeMux()
+  if set & publicRoutes != 0 {
+    mux.Handle("/hash", s.RecordStats(base + "/hash", keyed(&hash)))
+    mux.Handle("/hash/", s.RecordStats(base + "/hash/{id}", keyed(&hash)))
+    mux.Handle("/hash/ws", s.RecordStats(base + "/hash/ws", keyed(&JobSocketHandler{Service: s})))
+    mux.Handle("/verify", s.RecordStats(base + "/verify", keyed(&verify)))
+  }
+  if set & adminRoutes != 0 {
+    mux.Handle("/stats", s.RecordStats(base + "/stats", &stats))
+    mux.Handle("/stats/stream", s.RecordStats(base + "/stats/stream", &StatsStreamHandler{Service: s}))
+    if srv != nil {
+      var shutdown http.Handler = &ShutdownHandler{Srv: srv, Service: s}
+      if s.AdminToken != "" { // same as the gRPC Shutdown
+        shutdown = s.RequireAdmin(shutdown)
+      }
+      mux.Handle("/shutdown", s.RecordStats(base + "/shutdown", shutdown))
+    }
+    if keys := s.keysRoutes(set); keys != nil {
+      mux.Handle("/admin/keys", s.RecordStats(base + "/admin/keys", keys))
+      mux.Handle("/admin/keys/", s.RecordStats(base + "/admin/keys/{id}", keys))
+    }
+  }
+  if set == adminRoutes {
+    mux.Handle("/config", s.RecordStats(base + "/config", &ConfigHandler{Service: s}))
+    if s.Restart != nil {
+      mux.Handle("/restart", s.RecordStats(base + "/restart", &RestartHandler{Service: s}))
+    }
+  }
+  mux.Handle("/errors", s.RecordStats(base + "/errors", &errors))
+  mux.Handle("/openapi.json", s.RecordStats(base + "/openapi.json", &OpenAPIHandler{Spec: spec}))
+  mux.Handle("/docs", s.RecordStats(base + "/docs", &DocsHandler{}))
+  mux.Handle("/", s.RecordStats("unmatched", &notFound))
+  return mux
+}
+
+// every version of the routes in set: under /v1 and /v2, and paths without a version behaving like /v1
+func (s *Service) routes(srv *http.Server, set routeSet) *http.ServeMux {
+  mux := http.NewServeMux()
+  mux.Handle("/v1/", s.mountVersion(Version1, "/v1", s.versionRoutes(srv, Version1, "/v1", set)))
+  mux.Handle("/v2/", s.mountVersion(Version2, "/v2", s.versionRoutes(srv, Version2, "/v2", set)))
+  mux.Handle("/", s.mountVersion(VersionUnversioned, "", s.versionRoutes(srv, Version1, "", set)))
+  return mux
+}
+
+// serves a route tree under base, telling the handlers which version they're answering for and announcing its deprecation
+func (s *Service) mountVersion(version string, base string, tree http.Handler) http.Handler {
+  deprecation := s.Deprecations[version]
+  var next http.Handler = tree
+  if base != "" {
+    next = http.StripPrefix(base, tree)
+  }
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if !deprecation.Since.IsZero() {
+      w.Header().Set("Deprecation", fmt.Sprintf("@%d", deprecation.Since.Unix()))
+    }
+    if !deprecation.Sunset.IsZero() {
+      w.Header().Set("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+    }
+    if deprecation.Successor != "" && (!deprecation.Since.IsZero() || !deprecation.Sunset.IsZero()) {
+      w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", deprecation.Successor))
+    }
+    ctx := context.WithValue(r.Context(), routeTreeKey, routeTree{version: version, base: base})
+    next.ServeHTTP(w, r.WithContext(ctx))
+  })
+}
+
+// the route tree r came in on. requests served outside Routes answer like unversioned paths
+func treeOf(r *http.Request) routeTree {
+  tree, ok := r.Context().Value(routeTreeKey).(routeTree)
+  if !ok {
+    return routeTree{version: VersionUnversioned}
+  }
+  return tree
+}
+
+// answers with v as json; wrapped in an Envelope for /v2
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+  if treeOf(r).version == Version2 {
+    v = Envelope{Data: v, RequestID: RequestIDFrom(r.Context())}
+  }
+  jsonMessage, err := json.Marshal(v)
+  if err != nil {
+    writeError(w, r, NewError(CodeInternal, "Issue fetching data"))
+    return
+  }
+  w.Header().Set("Content-Type", "application/json")
+  w.WriteHeader(status)
+  w.Write(jsonMessage)
+}
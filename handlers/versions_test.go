@@ -0,0 +1,187 @@
+package handlers_test
+
+import (
+  "testing"
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "net/url"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers"
+  "github.com/rdibari84/GoHTTP/handlers/handlerstest"
+)
+
+//////////////////////////////////////////////
+///////////// Version Unit Tests /////////////
+//////////////////////////////////////////////
+
+func TestV1MatchesUnversioned(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{})
+
+  for _, path := range []string{"/hash", "/v1/hash"} {
+    resp, err := http.PostForm(srv.URL + path, url.Values{"password": {"angryMonkey"}})
+    if err != nil {
+      t.Fatalf("Expected no error. Error: %s", err)
+    }
+    body, _ := ioutil.ReadAll(resp.Body)
+    resp.Body.Close()
+    if resp.StatusCode != 200 || string(body) != angryMonkeyHash || resp.Header.Get("Content-Type") != "application/json" {
+      t.Errorf("Expected the bare hash from %s. got %d %s", path, resp.StatusCode, body)
+    }
+  }
+
+  // each tree is recorded under its own routes
+  stats := getStats(t, srv.URL + "/v1")
+  if stats.Total != 2 || !hasEndpoint(stats, "/hash") || !hasEndpoint(stats, "/v1/hash") {
+    t.Errorf("Expected both hashes recorded separately. got %+v", stats)
+  }
+}
+
+func TestV1JobLocationStaysInVersion(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{})
+
+  resp, err := http.PostForm(srv.URL + "/v1/hash", url.Values{"password": {"angryMonkey"}, "async": {"true"}})
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  if location := resp.Header.Get("Location"); resp.StatusCode != 202 || len(location) < 9 || location[:9] != "/v1/hash/" {
+    t.Errorf("Expected a job under /v1. got %d %q", resp.StatusCode, location)
+  }
+}
+
+func TestV2HashIsAsync(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{})
+
+  resp, err := http.PostForm(srv.URL + "/v2/hash", url.Values{"password": {"angryMonkey"}})
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  accepted := jobEnvelope{}
+  json.NewDecoder(resp.Body).Decode(&accepted)
+  resp.Body.Close()
+  location := resp.Header.Get("Location")
+  if resp.StatusCode != 202 || accepted.Data.ID == "" || location != "/v2/hash/" + accepted.Data.ID {
+    t.Fatalf("Expected a job id in an envelope. got %d %q %+v", resp.StatusCode, location, accepted)
+  }
+  if accepted.RequestID != resp.Header.Get("X-Request-ID") {
+    t.Errorf("Expected the envelope to carry the request id. got %q", accepted.RequestID)
+  }
+
+  srv.Service.Drain(t.Context()) // waits for the job
+  resp, err = http.Get(srv.URL + location)
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  finished := jobEnvelope{}
+  json.NewDecoder(resp.Body).Decode(&finished)
+  resp.Body.Close()
+  if finished.Data.Status != handlers.JobDone || finished.Data.Hash != angryMonkeyHash {
+    t.Errorf("Expected the finished job. got %+v", finished.Data)
+  }
+}
+
+func TestV2HashCanWait(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{})
+
+  resp, err := http.PostForm(srv.URL + "/v2/hash", url.Values{"password": {"angryMonkey"}, "async": {"false"}})
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  defer resp.Body.Close()
+  envelope := struct{ Data handlers.HashResult }{}
+  json.NewDecoder(resp.Body).Decode(&envelope)
+  if resp.StatusCode != 200 || envelope.Data.Hash != angryMonkeyHash {
+    t.Errorf("Expected the hash in an envelope. got %d %+v", resp.StatusCode, envelope)
+  }
+}
+
+func TestV2ErrorsAreEnveloped(t *testing.T) {
+  t.Parallel()
+  srv := handlerstest.NewServer(t, handlers.Config{})
+
+  for _, test := range []struct{ method string; path string; status int; code handlers.ErrorCode }{
+    {"POST", "/v2/hash", 400, handlers.CodeMissingPassword},
+    {"GET", "/v2/hash/nope", 404, handlers.CodeJobNotFound},
+    {"DELETE", "/v2/stats", 405, handlers.CodeMethodNotAllowed},
+    {"GET", "/v2/nope", 404, handlers.CodeNotFound},
+  } {
+    req, _ := http.NewRequest(test.method, srv.URL + test.path, nil)
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+      t.Fatalf("Expected no error. Error: %s", err)
+    }
+    envelope := handlers.Envelope{}
+    json.NewDecoder(resp.Body).Decode(&envelope)
+    resp.Body.Close()
+    if resp.StatusCode != test.status || envelope.Error == nil || envelope.Error.Code != test.code || envelope.RequestID == "" {
+      t.Errorf("Expected %s %s to answer %d %s. got %d %+v", test.method, test.path, test.status, test.code, resp.StatusCode, envelope)
+    }
+  }
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+  t.Parallel()
+  deprecations, err := handlers.ParseDeprecations("unversioned=2026-11-01", "unversioned=2027-05-01,v1=2027-05-01")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  srv := handlerstest.NewServer(t, handlers.Config{Deprecations: deprecations})
+
+  resp, err := http.Get(srv.URL + "/stats")
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  resp.Body.Close()
+  since := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC).Unix()
+  if resp.Header.Get("Deprecation") != fmt.Sprintf("@%d", since) {
+    t.Errorf("Expected a Deprecation date. got %q", resp.Header.Get("Deprecation"))
+  }
+  if resp.Header.Get("Sunset") != "Sat, 01 May 2027 00:00:00 GMT" || resp.Header.Get("Link") != `</v2>; rel="successor-version"` {
+    t.Errorf("Expected a Sunset date and a successor. got %q %q", resp.Header.Get("Sunset"), resp.Header.Get("Link"))
+  }
+
+  // v1 only has a sunset so far, and v2 isn't going anywhere
+  resp, _ = http.Get(srv.URL + "/v1/stats")
+  resp.Body.Close()
+  if resp.Header.Get("Deprecation") != "" || resp.Header.Get("Sunset") == "" {
+    t.Errorf("Expected only a Sunset on v1. got %q %q", resp.Header.Get("Deprecation"), resp.Header.Get("Sunset"))
+  }
+  resp, _ = http.Get(srv.URL + "/v2/stats")
+  resp.Body.Close()
+  if resp.Header.Get("Deprecation") != "" || resp.Header.Get("Sunset") != "" || resp.Header.Get("Link") != "" {
+    t.Errorf("Expected no deprecation on v2. got %v", resp.Header)
+  }
+}
+
+func TestParseDeprecationsRejectsBadInput(t *testing.T) {
+  for _, spec := range []string{"v3=2026-11-01", "v1", "v1=next week"} {
+    if _, err := handlers.ParseDeprecations(spec, ""); err == nil {
+      t.Errorf("Expected %q to be refused", spec)
+    }
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+type jobEnvelope struct {
+  Data handlers.Job
+  RequestID string
+}
+
+func hasEndpoint(stats handlers.Stats, route string) bool {
+  for _, e := range stats.Endpoints {
+    if e.Route == route {
+      return true
+    }
+  }
+  return false
+}
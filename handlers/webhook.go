@@ -0,0 +1,200 @@
+package handlers
+
+import (
+    "bytes"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "net/url"
+    "strconv"
+    "syscall"
+    "time"
+)
+
+//////////////////////////////////////////////
+////////////// Webhook Callbacks /////////////
+//////////////////////////////////////////////
+
+// headers sent with every callback
+const (
+  WebhookTimestampHeader = "X-GoHTTP-Timestamp" // unix seconds the callback was signed at
+  WebhookSignatureHeader = "X-GoHTTP-Signature" // sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+)
+
+// callback states
+const (
+  CallbackPending = "pending"
+  CallbackDelivered = "delivered"
+  CallbackFailed = "failed"
+)
+
+// WebhookConfig turns on callback_url for async jobs. finished jobs are POSTed to the callback as json, signed with Secret
+type WebhookConfig struct {
+  Secret []byte // signs every callback. required
+  MaxAttempts int // deliveries tried before giving up; 5 when zero
+  InitialBackoff time.Duration // wait before the first retry, doubling after each; 1s when zero
+  MaxBackoff time.Duration // longest wait between retries; 1m when zero
+  Client *http.Client // sends the callbacks; one with a 10s timeout that refuses private addresses unless AllowPrivate, and doesn't follow redirects, when nil
+  AllowPrivate bool // lets callbacks reach loopback, private and link-local addresses, e.g. a receiver on the same host
+}
+
+// why a callback to a loopback, private or link-local address is refused
+var ErrPrivateCallback = errors.New("callbacks to loopback, private and link-local addresses are not allowed")
+
+// Callback is the delivery state of a job's callback_url. shown on the job resource
+type Callback struct {
+  URL string
+  Status string // pending, delivered or failed
+  Attempts []CallbackAttempt
+}
+
+// CallbackAttempt is one try at delivering a callback
+type CallbackAttempt struct {
+  At time.Time
+  StatusCode int `json:",omitempty"` // the receiver's response, if it answered
+  Error string `json:",omitempty"` // why the attempt failed
+}
+
+// SignWebhook returns the signature header value for a callback body sent at timestamp (unix seconds).
+// receivers recompute it with the shared secret and compare with hmac.Equal
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+  mac := hmac.New(sha256.New, secret)
+  mac.Write([]byte(timestamp))
+  mac.Write([]byte("."))
+  mac.Write(body)
+  return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// checks a callback_url before a job is started for it
+func (s *Service) checkCallback(callbackURL string) *APIError {
+  if s.Webhooks == nil || len(s.Webhooks.Secret) == 0 {
+    return NewError(CodeInvalidCallback, "Callbacks are not enabled on this server")
+  }
+  u, err := url.Parse(callbackURL)
+  if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+    return NewError(CodeInvalidCallback, "callback_url must be an absolute http or https url")
+  }
+  // names are checked once they resolve, when the callback is delivered
+  if ip := net.ParseIP(u.Hostname()); ip != nil && !s.Webhooks.AllowPrivate && privateIP(ip) {
+    return NewError(CodeInvalidCallback, "callback_url can't be a loopback, private or link-local address")
+  }
+  return nil
+}
+
+// whether ip is somewhere a callback mustn't reach: this host, the local network or a cloud metadata service
+func privateIP(ip net.IP) bool {
+  return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// refuses connections to private addresses after the name has resolved, so neither DNS nor a redirect can get around checkCallback
+func refusePrivate(network string, address string, c syscall.RawConn) error {
+  host, _, err := net.SplitHostPort(address)
+  if err != nil {
+    return err
+  }
+  if ip := net.ParseIP(host); ip == nil || privateIP(ip) {
+    return ErrPrivateCallback
+  }
+  return nil
+}
+
+// POSTs the finished job to its callback until the receiver accepts it, recording each attempt on the job.
+// once the server starts draining no more retries are made
+func (s *Service) deliverCallback(id string) {
+  cfg := s.Webhooks
+  backoff := cfg.InitialBackoff
+  for attempt := 1; ; attempt++ {
+    job, ok := s.Jobs.Get(id)
+    if !ok || job.Callback == nil {
+      return
+    }
+    target := job.Callback.URL
+    job.Callback = nil // the receiver gets the result, not our delivery bookkeeping
+    body, _ := json.Marshal(job)
+    result, retry := s.postCallback(cfg, target, body)
+    switch {
+      case result.Error == "" && result.StatusCode < 300:
+        s.Jobs.RecordCallback(id, result, CallbackDelivered)
+        return
+      case !retry || attempt >= cfg.MaxAttempts:
+        s.Jobs.RecordCallback(id, result, CallbackFailed)
+        return
+    }
+    s.Jobs.RecordCallback(id, result, CallbackPending)
+
+    select {
+      case <-s.stopping:
+        s.Jobs.RecordCallback(id, CallbackAttempt{At: s.Clock.Now(), Error: "server shut down before the callback was delivered"}, CallbackFailed)
+        return
+      case <-s.Clock.After(backoff):
+    }
+    backoff *= 2
+    if backoff > cfg.MaxBackoff {
+      backoff = cfg.MaxBackoff
+    }
+  }
+}
+
+// one delivery attempt. retry is false when the receiver refused the callback for good
+func (s *Service) postCallback(cfg *WebhookConfig, target string, body []byte) (CallbackAttempt, bool) {
+  attempt := CallbackAttempt{At: s.Clock.Now()}
+  timestamp := strconv.FormatInt(attempt.At.Unix(), 10)
+  req, err := http.NewRequest("POST", target, bytes.NewReader(body))
+  if err != nil {
+    attempt.Error = err.Error()
+    return attempt, false
+  }
+  req.Header.Set("Content-Type", "application/json")
+  req.Header.Set(WebhookTimestampHeader, timestamp)
+  req.Header.Set(WebhookSignatureHeader, SignWebhook(cfg.Secret, timestamp, body))
+  resp, err := cfg.Client.Do(req)
+  if err != nil {
+    attempt.Error = err.Error()
+    return attempt, !errors.Is(err, ErrPrivateCallback)
+  }
+  resp.Body.Close()
+  attempt.StatusCode = resp.StatusCode
+  if resp.StatusCode >= 300 {
+    attempt.Error = fmt.Sprintf("receiver answered %d", resp.StatusCode)
+  }
+  // timeouts, rate limits and server errors may pass; any other refusal won't
+  retry := resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+  return attempt, retry
+}
+
+// a copy of c with the defaults filled in
+func (c WebhookConfig) withDefaults() *WebhookConfig {
+  cfg := c
+  if cfg.MaxAttempts < 1 {
+    cfg.MaxAttempts = 5
+  }
+  if cfg.InitialBackoff <= 0 {
+    cfg.InitialBackoff = time.Second
+  }
+  if cfg.MaxBackoff <= 0 {
+    cfg.MaxBackoff = time.Minute
+  }
+  if cfg.Client == nil {
+    dialer := &net.Dialer{Timeout: 10 * time.Second}
+    if !cfg.AllowPrivate {
+      dialer.Control = refusePrivate
+    }
+    // no proxy from the environment: it would be dialed instead of the receiver. redirects aren't followed since
+    // they'd turn the signed POST into a GET without the body; a 3xx fails the attempt instead
+    cfg.Client = &http.Client{
+      Timeout: 10 * time.Second,
+      Transport: &http.Transport{DialContext: dialer.DialContext},
+      CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+    }
+  }
+  return &cfg
+}
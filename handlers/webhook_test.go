@@ -0,0 +1,242 @@
+package handlers_test
+
+import (
+  "testing"
+  "context"
+  "encoding/json"
+  "io/ioutil"
+  "net/http"
+  "net/http/httptest"
+  "net/url"
+  "strings"
+  "sync"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers"
+  "github.com/rdibari84/GoHTTP/handlers/handlerstest"
+)
+
+var webhookSecret = []byte("shh")
+
+//////////////////////////////////////////////
+///////////// Webhook Unit Tests /////////////
+//////////////////////////////////////////////
+
+func TestCallbackDelivered(t *testing.T) {
+  t.Parallel()
+  receiver := newReceiver(t, http.StatusOK)
+  srv := handlerstest.NewServer(t, handlers.Config{Webhooks: &handlers.WebhookConfig{Secret: webhookSecret, AllowPrivate: true}})
+
+  // a callback implies async
+  job := postCallbackJob(t, srv.URL, receiver.URL)
+  if job.Callback == nil || job.Callback.Status != handlers.CallbackPending || job.Callback.URL != receiver.URL {
+    t.Errorf("Expected a pending callback. got %+v", job.Callback)
+  }
+  job = waitForCallback(t, srv, job.ID)
+  if job.Callback.Status != handlers.CallbackDelivered || len(job.Callback.Attempts) != 1 || job.Callback.Attempts[0].StatusCode != 200 {
+    t.Errorf("Expected one successful delivery. got %+v", job.Callback)
+  }
+
+  delivered := receiver.received()
+  if len(delivered) != 1 {
+    t.Fatalf("Expected one callback. got %d", len(delivered))
+  }
+  if !delivered[0].signed {
+    t.Errorf("Expected the callback to be signed with the shared secret")
+  }
+  if delivered[0].job.ID != job.ID || delivered[0].job.Hash != angryMonkeyHash || delivered[0].job.Callback != nil {
+    t.Errorf("Expected the finished job without delivery details. got %+v", delivered[0].job)
+  }
+}
+
+func TestCallbackRetriesWithBackoff(t *testing.T) {
+  t.Parallel()
+  receiver := newReceiver(t, 500, 503, 204)
+  srv := handlerstest.NewServer(t, handlers.Config{Webhooks: &handlers.WebhookConfig{Secret: webhookSecret, AllowPrivate: true}})
+
+  job := postCallbackJob(t, srv.URL, receiver.URL)
+  srv.Clock.WaitForSleepers(1)
+  srv.Clock.Advance(time.Second)
+  srv.Clock.WaitForSleepers(1)
+  srv.Clock.Advance(2 * time.Second) // the backoff doubles
+
+  job = waitForCallback(t, srv, job.ID)
+  attempts := job.Callback.Attempts
+  if job.Callback.Status != handlers.CallbackDelivered || len(attempts) != 3 {
+    t.Fatalf("Expected delivery on the third attempt. got %+v", job.Callback)
+  }
+  if attempts[0].StatusCode != 500 || attempts[0].Error == "" || attempts[2].StatusCode != 204 {
+    t.Errorf("Expected every attempt to be recorded. got %+v", attempts)
+  }
+  if attempts[1].At.Sub(attempts[0].At) != time.Second || attempts[2].At.Sub(attempts[1].At) != 2 * time.Second {
+    t.Errorf("Expected retries 1s then 2s apart. got %+v", attempts)
+  }
+}
+
+func TestCallbackGivesUpOnRefusal(t *testing.T) {
+  t.Parallel()
+  receiver := newReceiver(t, http.StatusBadRequest)
+  srv := handlerstest.NewServer(t, handlers.Config{Webhooks: &handlers.WebhookConfig{Secret: webhookSecret, AllowPrivate: true}})
+
+  job := waitForCallback(t, srv, postCallbackJob(t, srv.URL, receiver.URL).ID)
+  if job.Callback.Status != handlers.CallbackFailed || len(job.Callback.Attempts) != 1 {
+    t.Errorf("Expected a 400 not to be retried. got %+v", job.Callback)
+  }
+}
+
+func TestCallbackRedirectIsNotFollowed(t *testing.T) {
+  t.Parallel()
+  receiver := newReceiver(t, http.StatusOK)
+  redirect := httptest.NewServer(http.RedirectHandler(receiver.URL, http.StatusFound))
+  t.Cleanup(redirect.Close)
+  srv := handlerstest.NewServer(t, handlers.Config{Webhooks: &handlers.WebhookConfig{Secret: webhookSecret, AllowPrivate: true}})
+
+  // following it would turn the signed POST into a bare GET
+  job := waitForCallback(t, srv, postCallbackJob(t, srv.URL, redirect.URL).ID)
+  if job.Callback.Status != handlers.CallbackFailed || len(job.Callback.Attempts) != 1 || job.Callback.Attempts[0].StatusCode != 302 {
+    t.Errorf("Expected the 302 to fail the delivery. got %+v", job.Callback)
+  }
+  if len(receiver.received()) != 0 {
+    t.Errorf("Expected nothing to reach the redirect target. got %d callbacks", len(receiver.received()))
+  }
+}
+
+func TestCallbackStopsRetryingOnDrain(t *testing.T) {
+  t.Parallel()
+  receiver := newReceiver(t, http.StatusServiceUnavailable)
+  srv := handlerstest.NewServer(t, handlers.Config{Webhooks: &handlers.WebhookConfig{Secret: webhookSecret, AllowPrivate: true}})
+
+  job := postCallbackJob(t, srv.URL, receiver.URL)
+  srv.Clock.WaitForSleepers(1)
+  ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+  defer cancel()
+  if err := srv.Service.Drain(ctx); err != nil {
+    t.Fatalf("Expected the drain not to wait for retries. Error: %s", err)
+  }
+  job, _ = srv.Service.Jobs.Get(job.ID)
+  if job.Callback.Status != handlers.CallbackFailed || len(job.Callback.Attempts) != 2 {
+    t.Errorf("Expected the delivery to be given up. got %+v", job.Callback)
+  }
+}
+
+func TestCallbackRefused(t *testing.T) {
+  t.Parallel()
+  disabled := handlerstest.NewServer(t, handlers.Config{})
+  enabled := handlerstest.NewServer(t, handlers.Config{Webhooks: &handlers.WebhookConfig{Secret: webhookSecret, AllowPrivate: true}})
+
+  for _, test := range []struct{ url string; callback string }{
+    {disabled.URL, "http://example.com/done"},
+    {enabled.URL, "ftp://example.com/done"},
+    {enabled.URL, "/done"},
+  } {
+    resp, err := http.PostForm(test.url + "/hash", url.Values{"password": {"angryMonkey"}, "callback_url": {test.callback}})
+    if err != nil {
+      t.Fatalf("Expected no error. Error: %s", err)
+    }
+    message := handlers.ErrorMessage{}
+    json.NewDecoder(resp.Body).Decode(&message)
+    resp.Body.Close()
+    if resp.StatusCode != 400 || message.Code != handlers.CodeInvalidCallback {
+      t.Errorf("Expected invalid_callback for %s. got %d %+v", test.callback, resp.StatusCode, message)
+    }
+  }
+}
+
+func TestCallbackToPrivateAddressRefused(t *testing.T) {
+  t.Parallel()
+  receiver := newReceiver(t, http.StatusOK)
+  srv := handlerstest.NewServer(t, handlers.Config{Webhooks: &handlers.WebhookConfig{Secret: webhookSecret}})
+
+  for _, callback := range []string{receiver.URL, "http://169.254.169.254/latest/meta-data", "http://10.0.0.5/done", "http://[::1]/done"} {
+    resp, err := http.PostForm(srv.URL + "/hash", url.Values{"password": {"angryMonkey"}, "callback_url": {callback}})
+    if err != nil {
+      t.Fatalf("Expected no error. Error: %s", err)
+    }
+    message := handlers.ErrorMessage{}
+    json.NewDecoder(resp.Body).Decode(&message)
+    resp.Body.Close()
+    if resp.StatusCode != 400 || message.Code != handlers.CodeInvalidCallback {
+      t.Errorf("Expected invalid_callback for %s. got %d %+v", callback, resp.StatusCode, message)
+    }
+  }
+
+  // a name is only refused once it resolves to 127.0.0.1, and that isn't retried
+  byName := strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)
+  job := waitForCallback(t, srv, postCallbackJob(t, srv.URL, byName).ID)
+  if job.Callback.Status != handlers.CallbackFailed || len(job.Callback.Attempts) != 1 {
+    t.Errorf("Expected the delivery to be refused for good. got %+v", job.Callback)
+  }
+  if len(receiver.received()) != 0 {
+    t.Errorf("Expected nothing to reach the receiver. got %d callbacks", len(receiver.received()))
+  }
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+type callbackReceiver struct {
+  *httptest.Server
+  mu sync.Mutex
+  statuses []int // answered in order; the last one repeats
+  callbacks []receivedCallback
+}
+
+type receivedCallback struct {
+  job handlers.Job
+  signed bool
+}
+
+func newReceiver(t *testing.T, statuses ...int) *callbackReceiver {
+  receiver := &callbackReceiver{statuses: statuses}
+  receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    body, _ := ioutil.ReadAll(r.Body)
+    expected := handlers.SignWebhook(webhookSecret, r.Header.Get(handlers.WebhookTimestampHeader), body)
+    callback := receivedCallback{signed: r.Header.Get(handlers.WebhookSignatureHeader) == expected}
+    json.Unmarshal(body, &callback.job)
+
+    receiver.mu.Lock()
+    receiver.callbacks = append(receiver.callbacks, callback)
+    status := receiver.statuses[0]
+    if len(receiver.statuses) > 1 {
+      receiver.statuses = receiver.statuses[1:]
+    }
+    receiver.mu.Unlock()
+    w.WriteHeader(status)
+  }))
+  t.Cleanup(receiver.Close)
+  return receiver
+}
+
+func (c *callbackReceiver) received() []receivedCallback {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  return append([]receivedCallback(nil), c.callbacks...)
+}
+
+func postCallbackJob(t *testing.T, url string, callbackURL string) handlers.Job {
+  resp, err := http.Post(url + "/hash", "application/x-www-form-urlencoded", strings.NewReader("password=angryMonkey&callback_url=" + callbackURL))
+  if err != nil {
+    t.Fatalf("Expected no error. Error: %s", err)
+  }
+  defer resp.Body.Close()
+  if resp.StatusCode != http.StatusAccepted {
+    t.Fatalf("Expected 202. got %d", resp.StatusCode)
+  }
+  job := handlers.Job{}
+  json.NewDecoder(resp.Body).Decode(&job)
+  return job
+}
+
+// waits until the job's callback is no longer pending
+func waitForCallback(t *testing.T, srv *handlerstest.Server, id string) handlers.Job {
+  deadline := time.Now().Add(5 * time.Second)
+  for time.Now().Before(deadline) {
+    job, _ := srv.Service.Jobs.Get(id)
+    if job.Callback != nil && job.Callback.Status != handlers.CallbackPending {
+      return job
+    }
+    time.Sleep(time.Millisecond)
+  }
+  t.Fatalf("Expected the callback to be delivered or given up")
+  return handlers.Job{}
+}
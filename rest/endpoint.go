@@ -1,11 +1,22 @@
 package main
 
 import (
+    "flag"
     "fmt"
+    "io"
     "log"
     "net/http"
+    "os"
     "context"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "syscall"
+    "time"
+    "github.com/rdibari84/GoHTTP/audit"
+    "github.com/rdibari84/GoHTTP/grpcserver"
     "github.com/rdibari84/GoHTTP/handlers"
+    "google.golang.org/grpc"
 )
 
 //////////////////////////////////////////////
@@ -14,25 +25,108 @@ import (
 
 type App struct {
   srv http.Server
+  CORS handlers.CORSConfig // browser origins allowed to call the api; disabled when empty
+  Config handlers.Config // password policy, hashing pool, delay and clock; zero fields get the handlers defaults
+  GRPCAddr string // serves the gRPC api on its own listener too when set
+  GRPCMultiplex bool // serves the gRPC api on addr alongside http, over HTTP/2 without TLS
+  Server ServerConfig // protocols, stream and header limits and keep-alive settings
+  AccessLog io.Writer // one line per request, including the protocol that served it; off when nil
+  AdminAddr string // moves shutdown, stats, config, key management and the debug routes to a listener of their own, e.g. localhost:8081 or unix:/run/gohttp/admin.sock
+  SocketMode os.FileMode // permissions for the unix sockets the App creates; left to the umask when 0
+  RestartTimeout time.Duration // how long a restarted process gets to report ready; DefaultRestartTimeout when zero
+
+  command []string // what Restart runs; this binary with the same arguments when empty
+  mu sync.Mutex
+  running *http.Server
+  service *handlers.Service
+  sockets []servedSocket // everything Start listens on, handed to the new process on a restart
+  restarting atomic.Bool
+  drained chan struct{} // closed once a restart has finished the work in progress
 }
 
-// start http server
+// start http server on addr: one or more comma separated addresses, each host:port, unix:/path, systemd or systemd:name.
+// see listenAll
 func (a *App) Start(addr string) {
   srv := &http.Server{Addr: addr}
+  // every request is recorded for the /stats endpoint
+  cfg := a.Config
+  cfg.Restart = a.Restart
+  service := handlers.NewService(cfg)
+  a.mu.Lock()
+  a.running, a.service, a.drained = srv, service, make(chan struct{})
+  a.mu.Unlock()
+  routes := service.Routes(srv)
+  if a.AdminAddr != "" {
+    routes = service.PublicRoutes()
+    a.startAdmin(service, srv)
+  }
+  var handler http.Handler = handlers.CORS(a.CORS, routes)
+  if a.AccessLog != nil {
+    handler = handlers.LogRequests(a.AccessLog, handler)
+  }
+  handler = handlers.RequestID(handler)
 
-  // Create the handlers
-  hash := handlers.HashHandler{}
-  stats := handlers.StatsHandler{}
-  shutdown := handlers.ShutdownHandler{Srv: srv}
-  // now serve the handler functions
-  http.HandleFunc("/hash", hash.ServeHTTP)
-  http.HandleFunc("/stats", stats.ServeHTTP)
-  http.HandleFunc("/shutdown", shutdown.ServeHTTP)
+  // the gRPC Shutdown call stops the http server, which stops the gRPC server in turn.
+  // with an admin listener only /shutdown there can stop the server, and only /stats there shows the stats
+  stop := func() { srv.Shutdown(context.Background()) }
+  grpcSrv := grpcserver.NewServer(service, stop, a.AdminAddr != "")
+  srv.RegisterOnShutdown(grpcSrv.GracefulStop)
+  config := a.Server
+  if a.GRPCMultiplex {
+    handler = grpcOrHTTP(grpcSrv, handler)
+    config = config.withH2C() // gRPC clients speak h2c when there's no TLS
+  }
+  config.apply(srv)
+  srv.Handler = handler
+  if a.GRPCAddr != "" {
+    listeners, err := a.listen("grpc", a.GRPCAddr)
+    if err != nil {
+      log.Fatal(err)
+    }
+    for _, lis := range listeners {
+      fmt.Printf("Serving gRPC on %s\n", lis.Addr())
+      go grpcSrv.Serve(lis)
+    }
+  }
 
+  // named systemd sockets went to the admin and gRPC listeners above, so a plain systemd here gets the rest
+  listeners, err := a.listen("public", addr)
+  if err != nil {
+    log.Fatal(err)
+  }
   fmt.Printf("Starting server\n")
-  if err := srv.ListenAndServe(); err != nil {
+  for _, lis := range listeners[1:] {
+    fmt.Printf("Listening on %s\n", lis.Addr())
+    go srv.Serve(lis)
+  }
+  fmt.Printf("Listening on %s\n", listeners[0].Addr())
+  notifyReady()
+  err = srv.Serve(listeners[0])
+  if err == http.ErrServerClosed && a.restarting.Load() {
+    <-a.drained
+    fmt.Printf("Drained... the new process has taken over\n")
+    return
+  }
+  log.Fatal(err)
+}
+
+// serves the admin routes on AdminAddr until srv shuts down
+func (a *App) startAdmin(service *handlers.Service, srv *http.Server) {
+  var handler http.Handler = service.AdminRoutes(srv)
+  if a.AccessLog != nil {
+    handler = handlers.LogRequests(a.AccessLog, handler)
+  }
+  adminSrv := &http.Server{Handler: handlers.RequestID(handler), ReadHeaderTimeout: a.Server.ReadHeaderTimeout}
+  // runs once /shutdown has answered, since shutting down waits for that request to finish
+  srv.RegisterOnShutdown(func() { adminSrv.Shutdown(context.Background()) })
+  listeners, err := a.listen("admin", a.AdminAddr)
+  if err != nil {
     log.Fatal(err)
   }
+  for _, lis := range listeners {
+    fmt.Printf("Serving admin routes on %s\n", lis.Addr())
+    go adminSrv.Serve(lis)
+  }
 }
 
 // shutdown http server
@@ -43,11 +137,173 @@ func (a *App) Shutdown() {
   }
 }
 
+// sends gRPC requests to the gRPC server and everything else to the http handlers
+func grpcOrHTTP(grpcSrv *grpc.Server, httpHandler http.Handler) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+      grpcSrv.ServeHTTP(w, r)
+      return
+    }
+    httpHandler.ServeHTTP(w, r)
+  })
+}
+
 //////////////////////////////////////////////
 //////////////////// Main ////////////////////
 //////////////////////////////////////////////
 
 func main() {
-  a := App{}
-  a.Start(":8080") // start application server on port 8080
+  addr := flag.String("addr", ":8080", "comma separated addresses to listen on: host:port, unix:/path, systemd (socket activation) or systemd:name")
+  socketModeSpec := flag.String("socket-mode", "0660", "permissions for unix sockets, in octal")
+  corsOrigins := flag.String("cors-origins", "", "comma separated list of origins allowed to call the api, or * for any")
+  corsCredentials := flag.Bool("cors-credentials", false, "allow browsers to send credentials on cross origin requests")
+  corsMaxAge := flag.Duration("cors-max-age", 10*time.Minute, "how long browsers may cache a CORS preflight response")
+  minLength := flag.Int("min-password-length", handlers.DefaultPasswordPolicy.MinLength, "shortest password /hash accepts, in characters")
+  maxLength := flag.Int("max-password-length", handlers.DefaultPasswordPolicy.MaxLength, "longest password /hash accepts, in characters. 0 for no limit")
+  maxBody := flag.Int64("max-body-bytes", handlers.DefaultPasswordPolicy.MaxBodyBytes, "largest request body /hash reads. 0 for no limit")
+  normalization := flag.String("normalize", "", "unicode normalization applied before hashing: NFC or NFKC. off by default")
+  breachedFile := flag.String("breached-passwords", "", "file of breached passwords (plaintext or SHA-1 hex per line) that /hash refuses")
+  workers := flag.Int("hash-workers", 0, "goroutines doing the hashing. 0 uses GOMAXPROCS")
+  queueSize := flag.Int("hash-queue", 1024, "hashes each priority lane can queue before /hash answers 503")
+  cacheSize := flag.Int("cache-size", 0, "hashes to remember so repeated passwords skip the delay and the hashing. 0 turns the cache off")
+  cacheTTL := flag.Duration("cache-ttl", handlers.DefaultCacheTTL, "how long the cache remembers a hash")
+  delaySpec := flag.String("delay", "fixed:5s", "wait before each hash: none, fixed:5s, jitter:1s-5s or exponential:2s")
+  grpcAddr := flag.String("grpc-addr", "", "addresses to serve the gRPC api on, e.g. :9090, like -addr. off when empty")
+  grpcMultiplex := flag.Bool("grpc-multiplex", false, "also serve the gRPC api on -addr, over HTTP/2 without TLS")
+  protocolSpec := flag.String("protocols", "http1", "comma separated protocols to serve on -addr: http1, h2c (HTTP/2 without TLS)")
+  maxStreams := flag.Int("max-concurrent-streams", 0, "HTTP/2 streams one connection may have open. 0 uses the net/http default (250)")
+  maxHeaderBytes := flag.Int("max-header-bytes", 0, "largest request header the server reads. 0 uses the net/http default (1MB)")
+  readHeaderTimeout := flag.Duration("read-header-timeout", 0, "how long clients get to send request headers. 0 for no limit")
+  idleTimeout := flag.Duration("idle-timeout", 0, "how long idle keep-alive connections stay open. 0 for no limit")
+  keepAlives := flag.Bool("keep-alives", true, "keep connections open between requests")
+  statsInterval := flag.Duration("stats-interval", handlers.DefaultStatsInterval, "how often /stats/stream pushes stats unless the caller asks with ?interval=")
+  webhookSecretFile := flag.String("webhook-secret-file", "", "file holding the secret callbacks are signed with. callback_url is refused without one; $GOHTTP_WEBHOOK_SECRET works too")
+  webhookAttempts := flag.Int("webhook-attempts", 5, "times a callback is tried before giving up")
+  webhookAllowPrivate := flag.Bool("webhook-allow-private", false, "let callbacks reach loopback, private and link-local addresses. refused by default")
+  webhookBackoff := flag.Duration("webhook-backoff", time.Second, "wait before the first callback retry, doubling after each up to a minute")
+  deprecate := flag.String("deprecate", "", "comma separated version=date pairs announcing a deprecation, e.g. unversioned=2026-12-01,v1=2027-03-01")
+  sunset := flag.String("sunset", "", "comma separated version=date pairs announcing when a version stops being served")
+  apiKeysFile := flag.String("api-keys", "", "json file API keys are kept in, hashed. /hash and /verify need a key when set")
+  adminTokenFile := flag.String("admin-token-file", "", "file holding the token /admin/keys and /shutdown need. off without one; $GOHTTP_ADMIN_TOKEN works too")
+  adminAddr := flag.String("admin-addr", "", "serve /shutdown, /stats, /config, /admin/keys and the -debug routes on their own listener, e.g. localhost:8081 or unix:/run/gohttp/admin.sock, instead of -addr")
+  auditPath := flag.String("audit-log", "", "file to append hash-chained audit events to: shutdowns, stats resets, restarts, key changes and auth failures. off when empty")
+  auditMaxBytes := flag.Int64("audit-max-bytes", audit.DefaultMaxBytes, "size the audit log is rotated at")
+  auditKeep := flag.Int("audit-keep", audit.DefaultKeep, "rotated audit log files to keep")
+  debug := flag.Bool("debug", false, "serve /debug/pprof and /debug/vars. they need the admin token, or -admin-addr without one")
+  restartTimeout := flag.Duration("restart-timeout", DefaultRestartTimeout, "how long the new process started by SIGHUP or POST /restart gets to report ready before the restart is given up")
+  accessLog := flag.Bool("access-log", true, "print a line per request with the protocol, status and duration")
+  flag.Parse()
+
+  delay, err := handlers.ParseDelayPolicy(*delaySpec)
+  if err != nil {
+    log.Fatal(err)
+  }
+  protocols, err := parseProtocols(*protocolSpec)
+  if err != nil {
+    log.Fatal(err)
+  }
+  deprecations, err := handlers.ParseDeprecations(*deprecate, *sunset)
+  if err != nil {
+    log.Fatal(err)
+  }
+  socketMode, err := parseSocketMode(*socketModeSpec)
+  if err != nil {
+    log.Fatal(err)
+  }
+
+  policy := handlers.PasswordPolicy{
+    MinLength: *minLength,
+    MaxLength: *maxLength,
+    MaxBodyBytes: *maxBody,
+    Normalization: *normalization,
+  }
+  if *breachedFile != "" {
+    breached, err := handlers.LoadBreachedPasswords(*breachedFile)
+    if err != nil {
+      log.Fatal(err)
+    }
+    fmt.Printf("Loaded %d breached passwords\n", len(breached))
+    policy.Breached = breached
+  }
+  if err := policy.Check(); err != nil {
+    log.Fatal(err)
+  }
+
+  var webhooks *handlers.WebhookConfig
+  if secret := readSecret(*webhookSecretFile, "GOHTTP_WEBHOOK_SECRET"); secret != "" {
+    webhooks = &handlers.WebhookConfig{Secret: []byte(secret), MaxAttempts: *webhookAttempts, InitialBackoff: *webhookBackoff, AllowPrivate: *webhookAllowPrivate}
+  }
+
+  var keys *handlers.KeyStore
+  if *apiKeysFile != "" {
+    keys, err = handlers.LoadKeyStore(*apiKeysFile)
+    if err != nil {
+      log.Fatal(err)
+    }
+    fmt.Printf("Loaded %d API keys\n", len(keys.List()))
+  }
+  adminToken := readSecret(*adminTokenFile, "GOHTTP_ADMIN_TOKEN")
+
+  var auditLog *audit.Log
+  if *auditPath != "" {
+    auditLog, err = audit.Open(*auditPath, *auditMaxBytes, *auditKeep)
+    if err != nil {
+      log.Fatal(err)
+    }
+  }
+
+  a := App{
+    CORS: handlers.CORSConfig{
+      AllowedOrigins: handlers.ParseOrigins(*corsOrigins),
+      ExposedHeaders: []string{"X-Request-ID", "Location", "Deprecation", "Sunset", "Link", "Retry-After"},
+      AllowCredentials: *corsCredentials,
+      MaxAge: *corsMaxAge,
+    },
+    Config: handlers.Config{
+      Policy: &policy,
+      Pool: handlers.NewHashPool(*workers, *queueSize),
+      Cache: handlers.NewHashCache(*cacheSize, *cacheTTL),
+      Delay: delay,
+      StatsInterval: *statsInterval,
+      Webhooks: webhooks,
+      Deprecations: deprecations,
+      Keys: keys,
+      AdminToken: adminToken,
+      Debug: *debug,
+      AuditLog: auditLog,
+    },
+    GRPCAddr: *grpcAddr,
+    AdminAddr: *adminAddr,
+    SocketMode: socketMode,
+    RestartTimeout: *restartTimeout,
+    GRPCMultiplex: *grpcMultiplex,
+    Server: ServerConfig{
+      Protocols: protocols,
+      MaxConcurrentStreams: *maxStreams,
+      MaxHeaderBytes: *maxHeaderBytes,
+      ReadHeaderTimeout: *readHeaderTimeout,
+      IdleTimeout: *idleTimeout,
+      DisableKeepAlives: !*keepAlives,
+    },
+  }
+  if err := a.CORS.Check(); err != nil {
+    log.Fatal(err)
+  }
+  if *accessLog {
+    a.AccessLog = os.Stdout
+  }
+  go a.restartOnSignal(syscall.SIGHUP)
+  a.Start(*addr) // start application server on port 8080 by default
+}
+
+// a secret from a file, or from the environment variable env when no file is given
+func readSecret(path string, env string) string {
+  if path == "" {
+    return os.Getenv(env)
+  }
+  contents, err := os.ReadFile(path)
+  if err != nil {
+    log.Fatal(err)
+  }
+  return strings.TrimSpace(string(contents))
 }
@@ -2,13 +2,23 @@ package main
 
 import (
   "testing"
+  "context"
+  "encoding/json"
+  "net"
   "net/http"
+  "path/filepath"
   "strings"
+  "time"
+  "github.com/rdibari84/GoHTTP/gohttppb"
+  "github.com/rdibari84/GoHTTP/handlers"
+  "google.golang.org/grpc"
+  "google.golang.org/grpc/credentials/insecure"
 )
 
 func TestApp(t *testing.T) {
   a := App{}
   go a.Start(":8080") // start application server on port 8080
+  waitForServer(t, "localhost:8080")
   _, err := http.Get("http://localhost:8080/stats")
 	if err != nil {
 		t.Errorf("Did not expect an error but got one. err %v", err)
@@ -19,3 +29,129 @@ func TestApp(t *testing.T) {
 	}
 
 }
+
+func TestAppServesGRPC(t *testing.T) {
+  a := App{Config: handlers.Config{Delay: handlers.NoDelay{}}, GRPCAddr: "localhost:8082", GRPCMultiplex: true}
+  go a.Start("localhost:8081")
+  waitForServer(t, "localhost:8081")
+  waitForServer(t, "localhost:8082")
+
+  // the same call works on the multiplexed http port and the dedicated gRPC port
+  for _, addr := range []string{"localhost:8081", "localhost:8082"} {
+    conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+    if err != nil {
+      t.Fatalf("Did not expect an error but got one. err %v", err)
+    }
+    resp, err := gohttppb.NewHashServiceClient(conn).Hash(context.Background(), &gohttppb.HashRequest{Password: "angryMonkey"})
+    if err != nil || resp.GetHash() == "" {
+      t.Errorf("Expected a hash from %s. got %v %v", addr, resp, err)
+    }
+    conn.Close()
+  }
+  // plain http still works on the multiplexed port
+  resp, err := http.Get("http://localhost:8081/stats")
+  if err != nil || resp.StatusCode != http.StatusOK {
+    t.Errorf("Expected /stats to answer. got %v %v", resp, err)
+  }
+}
+
+func TestAppServesH2C(t *testing.T) {
+  a := App{Server: ServerConfig{Protocols: []string{"http1", "h2c"}, MaxConcurrentStreams: 10}}
+  go a.Start("localhost:8083")
+  waitForServer(t, "localhost:8083")
+
+  // prior knowledge h2c, the way in-cluster clients and proxies connect
+  transport := &http.Transport{Protocols: new(http.Protocols)}
+  transport.Protocols.SetUnencryptedHTTP2(true)
+  h2c := &http.Client{Transport: transport}
+  resp, err := h2c.Get("http://localhost:8083/stats")
+  if err != nil {
+    t.Fatalf("Did not expect an error but got one. err %v", err)
+  }
+  resp.Body.Close()
+  if resp.Proto != "HTTP/2.0" {
+    t.Errorf("Expected HTTP/2.0. got %s", resp.Proto)
+  }
+
+  // HTTP/1.1 still works, and /stats counts both. a request is counted once it has been answered
+  resp, err = http.Get("http://localhost:8083/hash")
+  if err != nil {
+    t.Fatalf("Did not expect an error but got one. err %v", err)
+  }
+  resp.Body.Close()
+  resp, err = http.Get("http://localhost:8083/stats")
+  if err != nil {
+    t.Fatalf("Did not expect an error but got one. err %v", err)
+  }
+  stats := handlers.Stats{}
+  json.NewDecoder(resp.Body).Decode(&stats)
+  resp.Body.Close()
+  if stats.Protocols["HTTP/2.0"] != 1 || stats.Protocols["HTTP/1.1"] != 1 {
+    t.Errorf("Expected one request per protocol. got %v", stats.Protocols)
+  }
+}
+
+func TestAppServesAdminSeparately(t *testing.T) {
+  socket := filepath.Join(t.TempDir(), "admin.sock")
+  a := App{Config: handlers.Config{Delay: handlers.NoDelay{}}, AdminAddr: "unix:" + socket}
+  go a.Start("localhost:8084")
+  waitForServer(t, "localhost:8084")
+  waitForServer(t, "unix:" + socket)
+
+  resp, err := http.Get("http://localhost:8084/stats")
+  if err != nil {
+    t.Fatalf("Did not expect an error but got one. err %v", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != http.StatusNotFound {
+    t.Errorf("Expected no /stats on the public listener. got %d", resp.StatusCode)
+  }
+
+  admin := &http.Client{Transport: &http.Transport{
+    DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+      return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+    },
+  }}
+  resp, err = admin.Get("http://admin/stats")
+  if err != nil {
+    t.Fatalf("Did not expect an error but got one. err %v", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != http.StatusOK {
+    t.Errorf("Expected /stats on the admin socket. got %d", resp.StatusCode)
+  }
+}
+
+func TestParseProtocols(t *testing.T) {
+  protocols, err := parseProtocols("http1, H2C")
+  if err != nil || len(protocols) != 2 || protocols[0] != "http1" || protocols[1] != "h2c" {
+    t.Errorf("Expected http1 and h2c. got %v %v", protocols, err)
+  }
+  for _, spec := range []string{"h3", "spdy"} {
+    if _, err := parseProtocols(spec); err == nil {
+      t.Errorf("Expected an error for %s", spec)
+    }
+  }
+  // multiplexed gRPC adds h2c without dropping http1
+  config := ServerConfig{}.withH2C()
+  if len(config.Protocols) != 2 {
+    t.Errorf("Expected http1 and h2c. got %v", config.Protocols)
+  }
+}
+
+// Start blocks, so give the listener a moment to come up before making requests. addr may be unix:/path
+func waitForServer(t *testing.T, addr string) {
+  network := "tcp"
+  if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+    network, addr = "unix", path
+  }
+  for i := 0; i < 50; i++ {
+    conn, err := net.Dial(network, addr)
+    if err == nil {
+      conn.Close()
+      return
+    }
+    time.Sleep(20 * time.Millisecond)
+  }
+  t.Fatalf("server never started listening on %s", addr)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "slices"
+    "strings"
+    "time"
+)
+
+//////////////////////////////////////////////
+//////////// Listener Configuration //////////
+//////////////////////////////////////////////
+
+// ServerConfig tunes the http listener. zero values keep net/http's defaults
+type ServerConfig struct {
+  Protocols []string // http1 and/or h2c (HTTP/2 without TLS, for in-cluster traffic). http1 only when empty
+  MaxConcurrentStreams int // HTTP/2 streams a single connection may have open
+  MaxHeaderBytes int // largest request header the server reads
+  ReadHeaderTimeout time.Duration // how long a client gets to send its request headers
+  IdleTimeout time.Duration // how long an idle keep-alive connection is kept open
+  DisableKeepAlives bool // close every connection after one request
+}
+
+// the protocols -protocols accepts. anything added here (h3 once there is a QUIC listener) goes through apply
+var knownProtocols = []string{"http1", "h2c"}
+
+// parseProtocols reads a comma separated protocol list such as "http1,h2c"
+func parseProtocols(spec string) ([]string, error) {
+  var protocols []string
+  for _, p := range strings.Split(spec, ",") {
+    p = strings.ToLower(strings.TrimSpace(p))
+    switch p {
+      case "":
+        continue
+      case "http1", "h2c":
+        protocols = append(protocols, p)
+      case "h3", "http3":
+        return nil, fmt.Errorf("HTTP/3 needs a QUIC listener, which this server doesn't have yet. use %s", strings.Join(knownProtocols, " or "))
+      default:
+        return nil, fmt.Errorf("unknown protocol %q. use %s", p, strings.Join(knownProtocols, " or "))
+    }
+  }
+  return protocols, nil
+}
+
+// apply sets the config on srv
+func (c ServerConfig) apply(srv *http.Server) {
+  if len(c.Protocols) > 0 {
+    srv.Protocols = new(http.Protocols)
+    for _, p := range c.Protocols {
+      switch p {
+        case "http1":
+          srv.Protocols.SetHTTP1(true)
+        case "h2c":
+          srv.Protocols.SetUnencryptedHTTP2(true)
+      }
+    }
+  }
+  if c.MaxConcurrentStreams > 0 {
+    srv.HTTP2 = &http.HTTP2Config{MaxConcurrentStreams: c.MaxConcurrentStreams}
+  }
+  srv.MaxHeaderBytes = c.MaxHeaderBytes
+  srv.ReadHeaderTimeout = c.ReadHeaderTimeout
+  srv.IdleTimeout = c.IdleTimeout
+  srv.SetKeepAlivesEnabled(!c.DisableKeepAlives)
+}
+
+// c with h2c turned on as well as whatever it already serves
+func (c ServerConfig) withH2C() ServerConfig {
+  if len(c.Protocols) == 0 {
+    c.Protocols = []string{"http1"}
+  }
+  if !slices.Contains(c.Protocols, "h2c") {
+    c.Protocols = append(slices.Clip(c.Protocols), "h2c")
+  }
+  return c
+}
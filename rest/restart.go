@@ -0,0 +1,207 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net"
+    "os"
+    "os/exec"
+    "os/signal"
+    "strconv"
+    "strings"
+    "time"
+    "github.com/rdibari84/GoHTTP/audit"
+)
+
+//////////////////////////////////////////////
+//////////////// Restarts ////////////////////
+//////////////////////////////////////////////
+
+// how long a restarted process gets to report ready unless App.RestartTimeout says otherwise
+var DefaultRestartTimeout = 30 * time.Second
+
+// the descriptor a restarted process reports ready on, by writing a byte and closing it
+const readyFDEnv = "GOHTTP_READY_FD"
+
+// a socket the App is serving on, and which listener it belongs to: public, admin or grpc
+type servedSocket struct {
+  role string
+  lis net.Listener
+}
+
+// Restart starts this binary again with the same arguments and the listening sockets passed in, and once it reports
+// ready drains this process the way /shutdown does: new connections go to the new process while the hashing in
+// progress finishes here. Start returns once the drain is done. returns the new process id
+func (a *App) Restart() (int, error) {
+  a.mu.Lock()
+  srv := a.running
+  a.mu.Unlock()
+  if srv == nil {
+    return 0, errors.New("the server hasn't started yet")
+  }
+  if !a.restarting.CompareAndSwap(false, true) {
+    return 0, errors.New("a restart is already under way")
+  }
+  // the new process carries on the audit chain, so this one stops writing to it first
+  auditLog := a.Config.AuditLog
+  if auditLog != nil {
+    auditLog.Close()
+  }
+  pid, err := a.startSuccessor()
+  if err != nil {
+    if auditLog != nil {
+      if err := auditLog.Reopen(); err != nil {
+        fmt.Printf("Could not reopen the audit log: %v\n", err)
+      }
+    }
+    a.restarting.Store(false)
+    return 0, err
+  }
+  fmt.Printf("Handed the sockets to process %d... draining\n", pid)
+  go a.drain()
+  return pid, nil
+}
+
+// restarts whenever the process gets one of sigs, e.g. SIGHUP from kill -HUP
+func (a *App) restartOnSignal(sigs ...os.Signal) {
+  received := make(chan os.Signal, 1)
+  signal.Notify(received, sigs...)
+  for sig := range received {
+    a.mu.Lock()
+    service := a.service
+    a.mu.Unlock()
+    if service != nil {
+      service.Audit(audit.Event{Event: audit.EventRestart, Actor: "signal", Detail: sig.String()})
+    }
+    if _, err := a.Restart(); err != nil {
+      fmt.Printf("Restart failed: %v\n", err)
+    }
+  }
+}
+
+// opens the sockets for role on spec, or takes them over from a restarting parent, and remembers them for the next restart
+func (a *App) listen(role string, spec string) ([]net.Listener, error) {
+  listeners, err := handedOver.claim(role)
+  if err != nil {
+    listeners, err = listenAll(spec, a.SocketMode)
+    if err != nil {
+      return nil, err
+    }
+  }
+  a.mu.Lock()
+  defer a.mu.Unlock()
+  for _, lis := range listeners {
+    a.sockets = append(a.sockets, servedSocket{role: role, lis: lis})
+  }
+  return listeners, nil
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// execs the new process with a copy of every socket and waits for it to report ready
+func (a *App) startSuccessor() (int, error) {
+  a.mu.Lock()
+  sockets := append([]servedSocket(nil), a.sockets...)
+  a.mu.Unlock()
+
+  var files []*os.File
+  var roles []string
+  defer func() {
+    for _, f := range files {
+      f.Close() // the new process has its own copies
+    }
+  }()
+  for _, socket := range sockets {
+    filer, ok := socket.lis.(interface{ File() (*os.File, error) })
+    if !ok {
+      return 0, fmt.Errorf("can't hand over %s: a %T has no file descriptor", socket.lis.Addr(), socket.lis)
+    }
+    f, err := filer.File()
+    if err != nil {
+      return 0, err
+    }
+    files = append(files, f)
+    roles = append(roles, socket.role)
+  }
+  ready, notify, err := os.Pipe()
+  if err != nil {
+    return 0, err
+  }
+  defer ready.Close()
+
+  command := a.command
+  if len(command) == 0 {
+    exe, err := os.Executable()
+    if err != nil {
+      notify.Close()
+      return 0, err
+    }
+    command = append([]string{exe}, os.Args[1:]...)
+  }
+  cmd := exec.Command(command[0], command[1:]...)
+  cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+  // ExtraFiles start at descriptor 3: the sockets, then the ready pipe
+  cmd.ExtraFiles = append(append([]*os.File(nil), files...), notify)
+  cmd.Env = append(os.Environ(),
+    handoffFDsEnv + "=" + strconv.Itoa(len(files)),
+    handoffNamesEnv + "=" + strings.Join(roles, ":"),
+    readyFDEnv + "=" + strconv.Itoa(3 + len(files)),
+  )
+  err = cmd.Start()
+  notify.Close() // so the read below ends if the new process exits without reporting ready
+  if err != nil {
+    return 0, err
+  }
+  go cmd.Wait() // reaps it should it exit while this process is still around
+
+  timeout := a.RestartTimeout
+  if timeout <= 0 {
+    timeout = DefaultRestartTimeout
+  }
+  ready.SetReadDeadline(time.Now().Add(timeout))
+  if _, err := ready.Read(make([]byte, 1)); err != nil {
+    cmd.Process.Kill()
+    if errors.Is(err, os.ErrDeadlineExceeded) {
+      return 0, fmt.Errorf("process %d did not report ready within %s", cmd.Process.Pid, timeout)
+    }
+    return 0, fmt.Errorf("process %d exited before it was ready", cmd.Process.Pid)
+  }
+  return cmd.Process.Pid, nil
+}
+
+// run by a restarted process once it is serving; tells the parent it can start draining
+func notifyReady() {
+  fd, err := strconv.Atoi(os.Getenv(readyFDEnv))
+  os.Unsetenv(readyFDEnv)
+  if err != nil {
+    return // not started by a restart
+  }
+  ready := os.NewFile(uintptr(fd), "ready")
+  ready.Write([]byte{1})
+  ready.Close()
+}
+
+// stops taking connections, leaving the sockets to the new process, and finishes the work in progress
+func (a *App) drain() {
+  a.mu.Lock()
+  srv, service, sockets := a.running, a.service, a.sockets
+  a.mu.Unlock()
+  for _, socket := range sockets {
+    if lis, ok := socket.lis.(*net.UnixListener); ok {
+      lis.SetUnlinkOnClose(false) // the socket file belongs to the new process now
+    }
+  }
+  // clients get Connection: close with the response they're waiting on, and reconnect to the new process
+  srv.SetKeepAlivesEnabled(false)
+  closed := make(chan struct{})
+  go func() {
+    srv.Shutdown(context.Background()) // waits for the requests in flight; streams end once Drain starts
+    close(closed)
+  }()
+  service.Drain(context.Background())
+  <-closed
+  close(a.drained)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+  "testing"
+  "context"
+  "encoding/json"
+  "net"
+  "net/http"
+  "net/url"
+  "os"
+  "path/filepath"
+  "time"
+  "github.com/rdibari84/GoHTTP/handlers"
+)
+
+func TestAppRestartHandsOverSockets(t *testing.T) {
+  socket := filepath.Join(t.TempDir(), "admin.sock")
+  a := App{Config: handlers.Config{Delay: handlers.FixedDelay{Delay: time.Second}}, AdminAddr: "unix:" + socket}
+  a.command = []string{os.Args[0], "-test.run=^TestRestartedProcess$"}
+  t.Setenv("GOHTTP_TEST_RESTARTED", "1")
+  stopped := make(chan struct{})
+  go func() {
+    a.Start("localhost:8086")
+    close(stopped)
+  }()
+  waitForServer(t, "localhost:8086")
+  waitForServer(t, "unix:" + socket)
+
+  // a hash still in progress when the restart happens finishes in this process
+  hashed := make(chan int, 1)
+  go func() {
+    resp, err := http.PostForm("http://localhost:8086/hash", url.Values{"password": {"angryMonkey"}})
+    if err != nil {
+      hashed <- 0
+      return
+    }
+    resp.Body.Close()
+    hashed <- resp.StatusCode
+  }()
+  time.Sleep(100 * time.Millisecond)
+
+  admin := &http.Client{Transport: &http.Transport{
+    DisableKeepAlives: true,
+    DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+      return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+    },
+  }}
+  resp, err := admin.Post("http://admin/restart", "", nil)
+  if err != nil {
+    t.Fatalf("Did not expect an error but got one. err %v", err)
+  }
+  restarted := handlers.Restarted{}
+  json.NewDecoder(resp.Body).Decode(&restarted)
+  resp.Body.Close()
+  if resp.StatusCode != http.StatusAccepted || restarted.PID == 0 || restarted.PID == os.Getpid() {
+    t.Fatalf("Expected a new process. got %d %+v", resp.StatusCode, restarted)
+  }
+  if p, err := os.FindProcess(restarted.PID); err == nil {
+    defer p.Kill()
+  }
+
+  if status := <-hashed; status != http.StatusOK {
+    t.Errorf("Expected the hash in progress to finish. got %d", status)
+  }
+  select {
+    case <-stopped:
+    case <-time.After(10 * time.Second):
+      t.Fatalf("Expected Start to return once the old process drained")
+  }
+  if _, err := a.Restart(); err == nil {
+    t.Errorf("Expected no second restart from a process that handed over")
+  }
+
+  // this process has stopped serving, so the new one answers on both sockets
+  resp, err = http.PostForm("http://localhost:8086/hash", url.Values{"password": {"angryMonkey"}})
+  if err != nil {
+    t.Fatalf("Expected the new process on the public socket. err %v", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != http.StatusOK {
+    t.Errorf("Expected the new process to hash. got %d", resp.StatusCode)
+  }
+  resp, err = admin.Get("http://admin/stats")
+  if err != nil {
+    t.Fatalf("Expected the new process on the admin socket. err %v", err)
+  }
+  resp.Body.Close()
+  if resp.StatusCode != http.StatusOK {
+    t.Errorf("Expected /stats from the new process. got %d", resp.StatusCode)
+  }
+}
+
+// the process TestAppRestartHandsOverSockets starts. its addresses can't be opened, so it only serves on the sockets it's handed
+func TestRestartedProcess(t *testing.T) {
+  if os.Getenv("GOHTTP_TEST_RESTARTED") == "" {
+    t.Skip("only runs as the process a restart starts")
+  }
+  missing := filepath.Join(t.TempDir(), "missing")
+  a := App{Config: handlers.Config{Delay: handlers.NoDelay{}}, AdminAddr: "unix:" + filepath.Join(missing, "admin.sock")}
+  a.Start("unix:" + filepath.Join(missing, "public.sock"))
+}
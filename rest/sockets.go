@@ -0,0 +1,205 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "net"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+    "syscall"
+)
+
+//////////////////////////////////////////////
+////////////////// Sockets ///////////////////
+//////////////////////////////////////////////
+
+// listenAll opens every address in a comma separated list:
+//   host:port        a tcp socket
+//   unix:/path       a unix socket, given mode as its permissions unless mode is 0
+//   systemd          every socket systemd passed in (LISTEN_FDS) that a systemd:name address hasn't claimed
+//   systemd:name     the sockets systemd passed in under FileDescriptorName=name
+func listenAll(spec string, mode os.FileMode) ([]net.Listener, error) {
+  var listeners []net.Listener
+  for _, addr := range strings.Split(spec, ",") {
+    addr = strings.TrimSpace(addr)
+    if addr == "" {
+      continue
+    }
+    opened, err := listen(addr, mode)
+    if err != nil {
+      for _, lis := range listeners {
+        lis.Close()
+      }
+      return nil, err
+    }
+    listeners = append(listeners, opened...)
+  }
+  if len(listeners) == 0 {
+    return nil, fmt.Errorf("no address to listen on in %q", spec)
+  }
+  return listeners, nil
+}
+
+// listen opens one address from a listenAll list
+func listen(addr string, mode os.FileMode) ([]net.Listener, error) {
+  if addr == "systemd" {
+    return activated.claim("")
+  }
+  if name, ok := strings.CutPrefix(addr, "systemd:"); ok {
+    return activated.claim(name)
+  }
+  path, ok := strings.CutPrefix(addr, "unix:")
+  if !ok {
+    lis, err := net.Listen("tcp", addr)
+    if err != nil {
+      return nil, err
+    }
+    return []net.Listener{lis}, nil
+  }
+  if err := removeStaleSocket(path); err != nil {
+    return nil, err
+  }
+  lis, err := listenUnix(path, mode)
+  if err != nil {
+    return nil, err
+  }
+  return []net.Listener{lis}, nil
+}
+
+// parseSocketMode reads unix socket permissions in octal, e.g. 0660
+func parseSocketMode(spec string) (os.FileMode, error) {
+  mode, err := strconv.ParseUint(spec, 8, 32)
+  if err != nil || mode > 0777 {
+    return 0, fmt.Errorf("socket mode %q should be octal permissions like 0660", spec)
+  }
+  return os.FileMode(mode), nil
+}
+
+//////////////////////////////////////////////
+/////////////// Helper Methods ///////////////
+//////////////////////////////////////////////
+
+// a socket file left behind by an earlier run would make the listen fail, so it's removed once nothing answers on it.
+// a server still listening there is left alone
+func removeStaleSocket(path string) error {
+  info, err := os.Lstat(path)
+  if err != nil || info.Mode() & os.ModeSocket == 0 {
+    return nil // the listen reports anything else in the way
+  }
+  conn, err := net.Dial("unix", path)
+  if err == nil {
+    conn.Close()
+    return fmt.Errorf("%s is in use by a running server", path)
+  }
+  if !errors.Is(err, syscall.ECONNREFUSED) {
+    return nil
+  }
+  return os.Remove(path)
+}
+
+// creates the unix socket with mode's permissions from the start, so nobody else can connect before they're set.
+// the umask is the process's own, so it's only narrowed for as long as the listen takes
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+  if mode != 0 {
+    old := syscall.Umask(int(0777 &^ mode))
+    defer syscall.Umask(old)
+  }
+  return net.Listen("unix", path)
+}
+
+// the first file descriptor systemd or a restart passes in; see sd_listen_fds(3)
+var listenFDsStart = 3
+
+// the sockets systemd passed to this process, handed out once each
+var activated = systemdActivation()
+
+// the sockets a restarting parent passed to this process, named by role: public, admin or grpc. see App.Restart
+var handedOver = handoffActivation()
+
+// environment a restart passes the sockets in with; the parent's pid isn't known before the exec, so there's no LISTEN_PID check
+const (
+  handoffFDsEnv = "GOHTTP_LISTEN_FDS"
+  handoffNamesEnv = "GOHTTP_LISTEN_FDNAMES"
+)
+
+type activation struct {
+  source string // who passes the sockets in, for errors
+  pidEnv string // must name this process when set
+  fdsEnv string
+  namesEnv string
+
+  once sync.Once
+  err error
+  sockets []activatedSocket
+}
+
+func systemdActivation() *activation {
+  return &activation{source: "systemd", pidEnv: "LISTEN_PID", fdsEnv: "LISTEN_FDS", namesEnv: "LISTEN_FDNAMES"}
+}
+
+func handoffActivation() *activation {
+  return &activation{source: "the restarting process", fdsEnv: handoffFDsEnv, namesEnv: handoffNamesEnv}
+}
+
+type activatedSocket struct {
+  name string
+  lis net.Listener // nil once claimed
+}
+
+// the sockets named name, or every unclaimed socket when name is empty
+func (a *activation) claim(name string) ([]net.Listener, error) {
+  a.once.Do(a.load)
+  if a.err != nil {
+    return nil, a.err
+  }
+  var listeners []net.Listener
+  for i := range a.sockets {
+    socket := &a.sockets[i]
+    if socket.lis != nil && (name == "" || socket.name == name) {
+      listeners = append(listeners, socket.lis)
+      socket.lis = nil
+    }
+  }
+  if len(listeners) == 0 {
+    if name == "" {
+      return nil, fmt.Errorf("%s passed no sockets that are still free", a.source)
+    }
+    return nil, fmt.Errorf("%s passed no socket named %q", a.source, name)
+  }
+  return listeners, nil
+}
+
+// reads the pid, fds and names variables, e.g. LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES, then unsets them so processes we start don't pick them up
+func (a *activation) load() {
+  defer func() {
+    os.Unsetenv(a.pidEnv)
+    os.Unsetenv(a.fdsEnv)
+    os.Unsetenv(a.namesEnv)
+  }()
+  if a.pidEnv != "" && os.Getenv(a.pidEnv) != strconv.Itoa(os.Getpid()) {
+    a.err = fmt.Errorf("no sockets from %s: %s isn't this process", a.source, a.pidEnv)
+    return
+  }
+  count, err := strconv.Atoi(os.Getenv(a.fdsEnv))
+  if err != nil || count < 1 {
+    a.err = fmt.Errorf("no sockets from %s: %s is %q", a.source, a.fdsEnv, os.Getenv(a.fdsEnv))
+    return
+  }
+  names := strings.Split(os.Getenv(a.namesEnv), ":")
+  for i := 0; i < count; i++ {
+    name := ""
+    if i < len(names) {
+      name = names[i]
+    }
+    f := os.NewFile(uintptr(listenFDsStart + i), name)
+    lis, err := net.FileListener(f)
+    f.Close() // FileListener has its own copy of the descriptor
+    if err != nil {
+      a.err = fmt.Errorf("socket %d (%s) from %s isn't a listening socket: %v", i, name, a.source, err)
+      return
+    }
+    a.sockets = append(a.sockets, activatedSocket{name: name, lis: lis})
+  }
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+  "testing"
+  "context"
+  "net"
+  "net/http"
+  "os"
+  "path/filepath"
+  "strconv"
+)
+
+func TestListenAllOpensEveryAddress(t *testing.T) {
+  socket := filepath.Join(t.TempDir(), "gohttp.sock")
+  listeners, err := listenAll("127.0.0.1:0, unix:" + socket, 0600)
+  if err != nil {
+    t.Fatalf("Did not expect an error but got one. err %v", err)
+  }
+  defer closeAll(listeners)
+  if len(listeners) != 2 || listeners[0].Addr().Network() != "tcp" || listeners[1].Addr().Network() != "unix" {
+    t.Fatalf("Expected a tcp and a unix listener. got %v", listeners)
+  }
+  info, err := os.Stat(socket)
+  if err != nil || info.Mode().Perm() != 0600 {
+    t.Errorf("Expected the socket to be 0600. got %v %v", info, err)
+  }
+
+  // a socket left behind by an earlier run doesn't get in the way
+  listeners[1].(*net.UnixListener).SetUnlinkOnClose(false)
+  listeners[1].Close()
+  again, err := listenAll("unix:" + socket, 0)
+  if err != nil {
+    t.Fatalf("Expected a stale socket to be replaced. err %v", err)
+  }
+  closeAll(again)
+}
+
+func TestListenAllLeavesALiveSocketAlone(t *testing.T) {
+  socket := filepath.Join(t.TempDir(), "gohttp.sock")
+  running, err := listenAll("unix:" + socket, 0600)
+  if err != nil {
+    t.Fatalf("Did not expect an error but got one. err %v", err)
+  }
+  defer closeAll(running)
+
+  if listeners, err := listenAll("unix:" + socket, 0600); err == nil {
+    closeAll(listeners)
+    t.Fatalf("Expected a socket a server is listening on to be refused")
+  }
+  conn, err := net.Dial("unix", socket)
+  if err != nil {
+    t.Fatalf("Expected the running server to still answer. err %v", err)
+  }
+  conn.Close()
+}
+
+func TestListenAllRefusesBadAddresses(t *testing.T) {
+  for _, spec := range []string{"", " , ", "unix:" + filepath.Join(t.TempDir(), "missing", "dir.sock"), "not-a-port:http"} {
+    if listeners, err := listenAll(spec, 0); err == nil {
+      closeAll(listeners)
+      t.Errorf("Expected %q to be refused", spec)
+    }
+  }
+}
+
+func TestListenAllSystemdActivation(t *testing.T) {
+  passed, err := net.Listen("tcp", "127.0.0.1:0")
+  if err != nil {
+    t.Fatalf("Did not expect an error but got one. err %v", err)
+  }
+  defer passed.Close()
+  f, err := passed.(*net.TCPListener).File()
+  if err != nil {
+    t.Fatalf("Did not expect an error but got one. err %v", err)
+  }
+  defer f.Close()
+
+  // pretend systemd passed f in as the only socket, named admin
+  oldStart, oldActivated := listenFDsStart, activated
+  listenFDsStart, activated = int(f.Fd()), systemdActivation()
+  defer func() { listenFDsStart, activated = oldStart, oldActivated }()
+  t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+  t.Setenv("LISTEN_FDS", "1")
+  t.Setenv("LISTEN_FDNAMES", "admin")
+
+  if _, err := listenAll("systemd:public", 0); err == nil {
+    t.Errorf("Expected no socket named public")
+  }
+  listeners, err := listenAll("systemd:admin", 0)
+  if err != nil || len(listeners) != 1 {
+    t.Fatalf("Expected the admin socket. got %v %v", listeners, err)
+  }
+  defer closeAll(listeners)
+  if listeners[0].Addr().String() != passed.Addr().String() {
+    t.Errorf("Expected the socket systemd passed in. got %s", listeners[0].Addr())
+  }
+  if _, err := listenAll("systemd", 0); err == nil {
+    t.Errorf("Expected no sockets left once admin was claimed")
+  }
+  if os.Getenv("LISTEN_FDS") != "" {
+    t.Errorf("Expected LISTEN_FDS to be unset so child processes don't pick it up")
+  }
+}
+
+func TestAppServesSeveralAddresses(t *testing.T) {
+  socket := filepath.Join(t.TempDir(), "gohttp.sock")
+  a := App{SocketMode: 0600}
+  go a.Start("localhost:8085,unix:" + socket)
+  waitForServer(t, "localhost:8085")
+  waitForServer(t, "unix:" + socket)
+
+  overSocket := &http.Client{Transport: &http.Transport{
+    DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+      return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+    },
+  }}
+  for _, get := range []func() (*http.Response, error){
+    func() (*http.Response, error) { return http.Get("http://localhost:8085/stats") },
+    func() (*http.Response, error) { return overSocket.Get("http://gohttp/stats") },
+  } {
+    resp, err := get()
+    if err != nil {
+      t.Fatalf("Did not expect an error but got one. err %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+      t.Errorf("Expected /stats on every address. got %d", resp.StatusCode)
+    }
+  }
+}
+
+func TestParseSocketMode(t *testing.T) {
+  if mode, err := parseSocketMode("0660"); err != nil || mode != 0660 {
+    t.Errorf("Expected 0660. got %o %v", mode, err)
+  }
+  for _, spec := range []string{"rw-rw----", "0999", "01777"} {
+    if _, err := parseSocketMode(spec); err == nil {
+      t.Errorf("Expected %q to be refused", spec)
+    }
+  }
+}
+
+func closeAll(listeners []net.Listener) {
+  for _, lis := range listeners {
+    lis.Close()
+  }
+}